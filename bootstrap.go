@@ -0,0 +1,59 @@
+package main
+
+import (
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+// bootstrapAdmin membuat akun admin pertama dari env BOOTSTRAP_ADMIN_NAMA,
+// BOOTSTRAP_ADMIN_EMAIL dan BOOTSTRAP_ADMIN_PASSWORD. Hanya berjalan jika koleksi
+// user masih kosong; selebihnya akun dibuat admin lewat endpoint karyawan.
+func bootstrapAdmin() (bool, error) {
+	nama := strings.TrimSpace(os.Getenv("BOOTSTRAP_ADMIN_NAMA"))
+	email := strings.TrimSpace(os.Getenv("BOOTSTRAP_ADMIN_EMAIL"))
+	password := os.Getenv("BOOTSTRAP_ADMIN_PASSWORD")
+	if nama == "" {
+		nama = "Administrator"
+	}
+	if email == "" || password == "" {
+		return false, errors.New("BOOTSTRAP_ADMIN_EMAIL dan BOOTSTRAP_ADMIN_PASSWORD wajib diisi")
+	}
+	if len(password) < 8 {
+		return false, errors.New("BOOTSTRAP_ADMIN_PASSWORD minimal 8 karakter")
+	}
+
+	count, err := repository.CountUsers()
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	id, err := repository.GenerateUserID("admin")
+	if err != nil {
+		return false, err
+	}
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		return false, err
+	}
+	admin := models.User{
+		ID:        id,
+		Nama:      nama,
+		Email:     email,
+		Password:  hashed,
+		Role:      "admin",
+		Status:    "aktif",
+		CreatedAt: time.Now(),
+	}
+	if err := repository.CreateUser(&admin); err != nil {
+		return false, err
+	}
+	return true, nil
+}
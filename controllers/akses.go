@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"backend/policy"
+	"backend/repository"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// repoResolver menyediakan data lintas koleksi untuk aturan kepemilikan
+type repoResolver struct{}
+
+func (repoResolver) KasirTransaksi(transaksiID string) (string, error) {
+	t, err := repository.GetTransaksiByID(transaksiID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Transaksi terhapus/tidak ada: tidak ada kasir pemilik
+		return "", nil
+	}
+	if err != nil {
+		// Gangguan database (mis. timeout) diteruskan agar menjadi 500, bukan 403
+		return "", err
+	}
+	return t.KasirID, nil
+}
+
+func (repoResolver) TransaksiMilikKasir(kasirID string) ([]string, error) {
+	trx, err := repository.ListTransaksi(bson.M{"kasir_id": kasirID}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(trx))
+	for _, t := range trx {
+		if t.ID != "" {
+			ids = append(ids, t.ID)
+		}
+	}
+	return ids, nil
+}
+
+// akses adalah policy kepemilikan data yang dipakai seluruh controller
+var akses = policy.New(repoResolver{})
+
+func subjek(c *fiber.Ctx) policy.Subjek {
+	userID, _ := c.Locals("userID").(string)
+	role, _ := c.Locals("userRole").(string)
+	return policy.Subjek{UserID: userID, Role: role}
+}
+
+// scopeAkses mengembalikan filter kepemilikan untuk query list milik user saat ini
+func scopeAkses(c *fiber.Ctx, src policy.Sumber) (bson.M, error) {
+	return akses.Scope(subjek(c), src)
+}
+
+// izinkanAkses memutuskan akses user saat ini terhadap satu dokumen
+func izinkanAkses(c *fiber.Ctx, src policy.Sumber, a policy.Aksi, doc interface{}) (bool, error) {
+	return akses.Izinkan(subjek(c), src, a, doc)
+}
+
+// tolakAkses membalas 403, atau 500 jika pengecekan kepemilikan sendiri gagal
+func tolakAkses(c *fiber.Ctx, err error) error {
+	if err != nil && err != policy.ErrDitolak {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal memeriksa hak akses", "error": err.Error()})
+	}
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses ditolak"})
+}
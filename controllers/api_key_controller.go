@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetAllApiKeys godoc
+//
+//	@Summary		Daftar API key integrasi
+//	@Tags			API Key
+//	@Security		BearerAuth
+//	@Produce		json
+//	@Success		200	{array}		models.ApiKey
+//	@Router			/api-keys [get]
+func GetAllApiKeys(c *fiber.Ctx) error {
+	list, err := repository.ListApiKeys()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal mengambil API key"})
+	}
+	return c.JSON(list)
+}
+
+// CreateApiKey godoc
+//
+//	@Summary		Buat API key integrasi
+//	@Description	Key asli hanya dikembalikan sekali di respons ini. Kirim lewat header X-API-Key.
+//	@Tags			API Key
+//	@Security		BearerAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		models.ApiKeyInput	true	"Nama, scope & masa berlaku"
+//	@Success		201		{object}	map[string]interface{}
+//	@Failure		400		{object}	map[string]interface{}
+//	@Router			/api-keys [post]
+func CreateApiKey(c *fiber.Ctx) error {
+	var in models.ApiKeyInput
+	if err := c.BodyParser(&in); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Request tidak valid"})
+	}
+	in.Nama = strings.TrimSpace(in.Nama)
+	if in.Nama == "" || len(in.Scopes) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "nama dan scopes wajib diisi"})
+	}
+	if in.BerlakuHari < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "berlaku_hari tidak valid"})
+	}
+	seen := map[string]bool{}
+	scopes := []string{}
+	for _, p := range in.Scopes {
+		if !models.PermissionBolehApiKey(p) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Scope tidak diizinkan: " + p})
+		}
+		if !seen[p] {
+			seen[p] = true
+			scopes = append(scopes, p)
+		}
+	}
+	sort.Strings(scopes)
+
+	key, prefix, err := utils.GenerateApiKey()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal membuat API key"})
+	}
+	id, err := repository.GenerateID("api_key")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal generate ID"})
+	}
+	now := time.Now()
+	k := models.ApiKey{
+		ID:        id,
+		Nama:      in.Nama,
+		Prefix:    prefix,
+		Hash:      utils.HashToken(key),
+		Scopes:    scopes,
+		CreatedAt: now,
+	}
+	k.CreatedBy, _ = c.Locals("userID").(string)
+	if in.BerlakuHari > 0 {
+		exp := now.AddDate(0, 0, in.BerlakuHari)
+		k.ExpiresAt = &exp
+	}
+	if err := repository.CreateApiKey(&k); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal menyimpan API key"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "API key dibuat. Simpan key ini, tidak akan ditampilkan lagi.",
+		"key":     key,
+		"data":    k,
+	})
+}
+
+// RevokeApiKey godoc
+//
+//	@Summary		Cabut API key
+//	@Tags			API Key
+//	@Security		BearerAuth
+//	@Produce		json
+//	@Param			id	path		string	true	"ID API key"
+//	@Success		200	{object}	map[string]interface{}
+//	@Failure		404	{object}	map[string]interface{}
+//	@Router			/api-keys/{id} [delete]
+func RevokeApiKey(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if _, err := repository.GetApiKeyByID(id); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "API key tidak ditemukan"})
+	}
+	ok, err := repository.RevokeApiKey(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal mencabut API key"})
+	}
+	if !ok {
+		return c.JSON(fiber.Map{"message": "API key sudah dicabut sebelumnya"})
+	}
+	return c.JSON(fiber.Map{"message": "API key dicabut"})
+}
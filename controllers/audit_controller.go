@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"backend/repository"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// GetAuditLog godoc
+//
+//	@Summary		Audit log
+//	@Description	Riwayat operasi tulis (buat/ubah/hapus) terbaru dulu beserta field yang berubah
+//	@Tags			Audit
+//	@Security		BearerAuth
+//	@Produce		json
+//	@Param			entitas		query		string	false	"produk/kategori/pelanggan/transaksi/pembayaran/pengiriman/stok/user"
+//	@Param			ref_id		query		string	false	"ID dokumen"
+//	@Param			aktor_id	query		string	false	"ID user pelaku"
+//	@Param			aksi		query		string	false	"buat/ubah/hapus"
+//	@Param			start		query		string	false	"Tanggal mulai (YYYY-MM-DD)"
+//	@Param			end			query		string	false	"Tanggal akhir (YYYY-MM-DD)"
+//	@Param			page		query		int		false	"Halaman (default 1)"
+//	@Param			page_size	query		int		false	"Jumlah per halaman (default 50, maks 200)"
+//	@Success		200			{object}	map[string]interface{}
+//	@Router			/audit [get]
+func GetAuditLog(c *fiber.Ctx) error {
+	filter := bson.M{}
+	for _, k := range []string{"entitas", "ref_id", "aktor_id", "aksi"} {
+		if v := c.Query(k); v != "" {
+			filter[k] = v
+		}
+	}
+	dateFilter, err := buildCreatedAtFilterFromQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if v, ok := dateFilter["created_at"]; ok {
+		filter["waktu"] = v
+	}
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 50
+	}
+	list, total, err := repository.ListAuditLog(filter, page, pageSize)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal mengambil audit log"})
+	}
+	return c.JSON(fiber.Map{
+		"data":      list,
+		"page":      page,
+		"page_size": pageSize,
+		"total":     total,
+	})
+}
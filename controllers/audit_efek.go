@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"backend/middleware"
+	"backend/models"
+	"backend/repository"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Tulisan yang terjadi sebagai efek samping request lain (mis. mutasi stok saat transaksi
+// dibuat) tidak tertangkap middleware Audit route, sehingga dicatat langsung di sini.
+
+// buatMutasiDenganAudit menyimpan mutasi stok lalu mencatatnya di audit log
+func buatMutasiDenganAudit(c *fiber.Ctx, m *models.StokMutasi) error {
+	if _, err := repository.CreateMutasi(m); err != nil {
+		return err
+	}
+	middleware.CatatAudit(c, "stok", m.ID, "buat", nil)
+	return nil
+}
+
+// ubahDenganAudit menjalankan tulis atas satu dokumen dan mencatat perubahannya di audit log
+func ubahDenganAudit(c *fiber.Ctx, entitas, id string, tulis func() error) error {
+	sebelum := middleware.AuditSebelum(entitas, id)
+	if err := tulis(); err != nil {
+		return err
+	}
+	middleware.CatatAudit(c, entitas, id, "ubah", sebelum)
+	return nil
+}
+
+// ubahKeteranganMutasiDenganAudit mengganti keterangan semua mutasi stok milik transaksi;
+// tiap dokumen mutasi dicatat terpisah
+func ubahKeteranganMutasiDenganAudit(c *fiber.Ctx, transaksiID, keterangan string) error {
+	list, err := repository.ListMutasi(bson.M{"ref_id": transaksiID}, 0, 0, false)
+	if err != nil {
+		return err
+	}
+	sebelum := make(map[string]bson.M, len(list))
+	for _, m := range list {
+		sebelum[m.ID] = middleware.AuditSebelum("stok", m.ID)
+	}
+	if err := repository.UpdateMutasiKeteranganByRef(transaksiID, keterangan); err != nil {
+		return err
+	}
+	for _, m := range list {
+		if m.Keterangan != keterangan {
+			middleware.CatatAudit(c, "stok", m.ID, "ubah", sebelum[m.ID])
+		}
+	}
+	return nil
+}
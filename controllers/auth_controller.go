@@ -4,53 +4,12 @@ import (
 	"backend/models"
 	"backend/repository"
 	"backend/utils"
-	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// Register function (internal use only, tidak ditampilkan di Swagger)
-func Register(c *fiber.Ctx) error {
-	var input models.User
-
-	if err := c.BodyParser(&input); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
-	}
-
-	// Validasi role wajib (admin/kasir/gudang/driver)
-	role := strings.ToLower(input.Role)
-	if role != "admin" && role != "kasir" && role != "gudang" && role != "driver" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Role harus admin, kasir, gudang, atau driver"})
-	}
-
-	// Generate ID otomatis dari counters.go (jangan ambil dari frontend)
-	id, err := repository.GenerateID(role)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat ID user"})
-	}
-	input.ID = id
-
-	// Hash password
-	hashed, err := bcrypt.GenerateFromPassword([]byte(input.Password), 12)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal enkripsi password"})
-	}
-	input.Password = string(hashed)
-	input.CreatedAt = time.Now()
-
-	// Simpan user
-	if err := repository.CreateUser(&input); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal registrasi user"})
-	}
-
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "Registrasi berhasil",
-		"id":      input.ID,
-	})
-}
-
 // Login godoc
 //
 //	@Summary		Login user
@@ -59,9 +18,10 @@ func Register(c *fiber.Ctx) error {
 //	@Accept			json
 //	@Produce		json
 //	@Param			user	body		models.LoginInput		true	"Login credentials"
-//	@Success		200		{object}	map[string]interface{}	"Login berhasil"
+//	@Success		200		{object}	map[string]interface{}	"Login berhasil (token, refresh_token, expires_in) atau 2fa_required + interim_token"
 //	@Failure		400		{object}	map[string]interface{}	"Request tidak valid"
 //	@Failure		401		{object}	map[string]interface{}	"Email atau password salah"
+//	@Failure		429		{object}	map[string]interface{}	"Terlalu banyak percobaan login"
 //	@Router			/auth/login [post]
 func Login(c *fiber.Ctx) error {
 	var input models.LoginInput
@@ -69,29 +29,210 @@ func Login(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Data tidak valid"})
 	}
 
+	// Backoff per IP & per email sebelum menyentuh bcrypt
+	ip := c.IP()
+	if sisa := loginDibatasi(ip, input.Email); sisa > 0 {
+		catatLoginAttempt(c, input.Email, "", false, "dibatasi")
+		return tolakLoginDibatasi(c, sisa)
+	}
+
 	user, err := repository.FindUserByEmail(input.Email)
 	if err != nil {
+		catatLoginGagal(ip, input.Email)
+		catatLoginAttempt(c, input.Email, "", false, "user_tidak_ada")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Email atau password salah"})
+	}
+
+	// Akun sedang dikunci karena terlalu banyak login gagal. Respons sama dengan password
+	// salah agar tidak membocorkan bahwa email terdaftar.
+	if user.TerkunciSampai != nil && time.Now().Before(*user.TerkunciSampai) {
+		catatLoginGagal(ip, input.Email)
+		catatLoginAttempt(c, input.Email, user.ID, false, "terkunci")
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Email atau password salah"})
 	}
 
 	// Cek status aktif/nonaktif
 	if user.Status != "aktif" {
+		catatLoginAttempt(c, input.Email, user.ID, false, "nonaktif")
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Akun karyawan nonaktif, tidak dapat login"})
 	}
 
 	// Bandingkan password plaintext dan hashed
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+		catatLoginGagal(ip, input.Email)
+		if n, err := repository.IncrementUserGagalLogin(user.ID); err == nil && n >= loginMaxGagal() {
+			_ = repository.LockUser(user.ID, time.Now().Add(loginLockDurasi()))
+		}
+		catatLoginAttempt(c, input.Email, user.ID, false, "password_salah")
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Email atau password salah"})
 	}
 
-	// ✅ Perbaikan: tambahkan user.Nama
-	token, err := utils.GenerateToken(user.ID, user.Role, user.Nama)
+	// Langkah kedua: kode TOTP diverifikasi lewat POST /auth/login/2fa
+	if user.TOTPAktif {
+		resp, err := buatLoginChallenge(user)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token"})
+		}
+		return c.JSON(resp)
+	}
+	return selesaikanLogin(c, user, input.Email)
+}
+
+// selesaikanLogin dipanggil setelah semua faktor terverifikasi: reset penghitung gagal,
+// catat login berhasil, lalu terbitkan pasangan token.
+func selesaikanLogin(c *fiber.Ctx, user *models.User, email string) error {
+	// Hitungan gagal milik email & akun dimulai ulang (penghitung IP tetap)
+	_ = repository.DeleteLoginThrottle(loginKeyEmail(email))
+	if user.GagalLogin > 0 || user.TerkunciSampai != nil {
+		_ = repository.UnlockUser(user.ID)
+	}
+	catatLoginAttempt(c, email, user.ID, true, "")
+
+	resp, err := issueTokenPair(c, user, "")
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token"})
 	}
+	resp["message"] = "Login berhasil"
+	if user.WajibGantiPwd {
+		// Password direset admin: frontend harus mengarahkan ke POST /users/me/password
+		resp["wajib_ganti_password"] = true
+	}
+	if user.Role == "admin" && !user.TOTPAktif {
+		if p, err := repository.GetPengaturanKeamanan(); err == nil && p.Wajib2FAAdmin {
+			// Kebijakan mewajibkan 2FA: frontend harus mengarahkan ke /auth/2fa/setup
+			resp["wajib_2fa"] = true
+		}
+	}
+	return c.JSON(resp)
+}
 
-	return c.JSON(fiber.Map{
-		"message": "Login berhasil",
-		"token":   token,
-	})
+// issueTokenPair membuat access token + refresh token baru.
+// familyID kosong berarti login baru (keluarga refresh token baru).
+func issueTokenPair(c *fiber.Ctx, user *models.User, familyID string) (fiber.Map, error) {
+	token, err := utils.GenerateToken(user.ID, user.Role, user.Nama, user.TokenVersion)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	if familyID == "" {
+		if familyID, err = utils.GenerateRandomToken(12); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now()
+	rt := models.RefreshToken{
+		ID:           utils.HashToken(refresh),
+		UserID:       user.ID,
+		FamilyID:     familyID,
+		TokenVersion: user.TokenVersion,
+		UserAgent:    c.Get("User-Agent"),
+		IP:           c.IP(),
+		ExpiresAt:    now.Add(utils.RefreshTokenTTL()),
+		CreatedAt:    now,
+	}
+	if err := repository.CreateRefreshToken(&rt); err != nil {
+		return nil, err
+	}
+	return fiber.Map{
+		"token":         token,
+		"refresh_token": refresh,
+		"expires_in":    int(utils.AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+// Refresh godoc
+//
+//	@Summary		Refresh access token
+//	@Description	Tukar refresh token dengan pasangan access + refresh token baru (refresh token lama langsung tidak berlaku)
+//	@Tags			Authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		object{refresh_token=string}	true	"Refresh token"
+//	@Success		200		{object}	map[string]interface{}
+//	@Failure		401		{object}	map[string]interface{}	"Refresh token tidak valid"
+//	@Router			/auth/refresh [post]
+func Refresh(c *fiber.Ctx) error {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "refresh_token wajib"})
+	}
+	hash := utils.HashToken(body.RefreshToken)
+	rt, err := repository.GetRefreshToken(hash)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token tidak valid"})
+	}
+	if rt.RevokedAt != nil {
+		// Token lama dipakai ulang: kemungkinan bocor, cabut seluruh keluarga token
+		_ = repository.RevokeRefreshTokenFamily(rt.FamilyID)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token sudah tidak berlaku"})
+	}
+	if time.Now().After(rt.ExpiresAt) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token kadaluarsa"})
+	}
+	user, err := repository.FindUserByID(rt.UserID)
+	if err != nil || user.Status != "aktif" || user.TokenVersion != rt.TokenVersion {
+		_ = repository.RevokeRefreshTokenFamily(rt.FamilyID)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token sudah tidak berlaku"})
+	}
+
+	resp, err := issueTokenPair(c, user, rt.FamilyID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token"})
+	}
+	newHash := utils.HashToken(resp["refresh_token"].(string))
+	if ok, err := repository.RotateRefreshToken(hash, newHash); err != nil || !ok {
+		// Kalah balapan dengan request refresh lain: token baru ikut dicabut
+		_ = repository.RevokeRefreshToken(newHash)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token sudah tidak berlaku"})
+	}
+	return c.JSON(resp)
+}
+
+// Logout godoc
+//
+//	@Summary		Logout
+//	@Description	Cabut access token saat ini dan refresh token yang dikirim. semua=true mencabut semua sesi user.
+//	@Tags			Authentication
+//	@Security		BearerAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		object{refresh_token=string,semua=bool}	false	"Refresh token sesi ini"
+//	@Success		200		{object}	map[string]interface{}
+//	@Router			/auth/logout [post]
+func Logout(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+		Semua        bool   `json:"semua"`
+	}
+	_ = c.BodyParser(&body)
+
+	if body.Semua {
+		if err := repository.RevokeAllUserTokens(userID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal logout"})
+		}
+		return c.JSON(fiber.Map{"message": "Logout dari semua perangkat berhasil"})
+	}
+
+	if jti, _ := c.Locals("tokenID").(string); jti != "" {
+		exp, ok := c.Locals("tokenExp").(time.Time)
+		if !ok {
+			exp = time.Now().Add(utils.AccessTokenTTL())
+		}
+		if err := repository.RevokeAccessToken(jti, userID, exp); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal logout"})
+		}
+	}
+	if body.RefreshToken != "" {
+		// Hanya refresh token milik user ini yang dicabut
+		if rt, err := repository.GetRefreshToken(utils.HashToken(body.RefreshToken)); err == nil && rt.UserID == userID {
+			_ = repository.RevokeRefreshTokenFamily(rt.FamilyID)
+		}
+	}
+	return c.JSON(fiber.Map{"message": "Logout berhasil"})
 }
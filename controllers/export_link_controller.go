@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// exportTujuan memetakan nama export ke path unduhan beserta filter yang boleh dibawa link
+var exportTujuan = map[string]struct {
+	Path   string
+	Filter []string
+}{
+	"excel":      {Path: "/laporan/export/excel", Filter: []string{"start", "end", "month", "year", "status", "kasir_id", "driver_id", "pelanggan_id", "metode", "format"}},
+	"kinerja":    {Path: "/laporan/export/kinerja", Filter: []string{"start", "end", "month", "year", "format"}},
+	"laba":       {Path: "/laporan/export/laba", Filter: []string{"start", "end", "month", "year", "format"}},
+	"persediaan": {Path: "/laporan/export/persediaan", Filter: []string{"tanggal", "metode", "format"}},
+	"kartu-stok": {Path: "/laporan/export/kartu-stok", Filter: []string{"start", "end", "month", "year", "produk_id", "format"}},
+}
+
+// Masa berlaku link export (EXPORT_LINK_TTL, default 60 detik)
+func exportLinkTTL() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("EXPORT_LINK_TTL")); err == nil && d > 0 {
+		return d
+	}
+	return 60 * time.Second
+}
+
+// POST /laporan/export/links: buat link unduhan sekali pakai untuk satu export & filternya
+func (lc *LaporanController) BuatExportLink(c *fiber.Ctx) error {
+	var body struct {
+		Export string            `json:"export"`
+		Filter map[string]string `json:"filter"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+	}
+	tujuan, ok := exportTujuan[body.Export]
+	if !ok {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "export tidak dikenal"})
+	}
+	q := url.Values{}
+	for _, k := range tujuan.Filter {
+		if v := body.Filter[k]; v != "" {
+			q.Set(k, v)
+		}
+	}
+	for k := range body.Filter {
+		if q.Get(k) == "" && body.Filter[k] != "" {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "filter tidak didukung: " + k})
+		}
+	}
+	query := utils.KanonikQuery(q)
+
+	userID, _ := c.Locals("userID").(string)
+	user, err := repository.FindUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User tidak ditemukan"})
+	}
+
+	nonce, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat link"})
+	}
+	now := time.Now()
+	exp := now.Add(exportLinkTTL())
+	token, err := utils.BuatExportLinkToken(nonce, exp, tujuan.Path, query)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	link := &models.ExportLink{
+		ID:           utils.HashToken(nonce),
+		UserID:       user.ID,
+		TokenVersion: user.TokenVersion,
+		Path:         tujuan.Path,
+		Query:        query,
+		ExpiresAt:    exp,
+		CreatedAt:    now,
+	}
+	if err := repository.CreateExportLink(link); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan link"})
+	}
+
+	unduh := tujuan.Path + "?"
+	if query != "" {
+		unduh += query + "&"
+	}
+	unduh += "link=" + url.QueryEscape(token)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"url":        unduh,
+		"expires_at": exp,
+	})
+}
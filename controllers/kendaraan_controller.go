@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/repository"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func isDuplicateKey(err error) bool {
+	var we mongo.WriteException
+	if errors.As(err, &we) {
+		for _, e := range we.WriteErrors {
+			if e.Code == 11000 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GET /kendaraan (admin, kasir) - filter opsional: jenis, aktif, driver_id
+func GetAllKendaraan(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if jenis := strings.ToLower(c.Query("jenis")); jenis != "" {
+		filter["jenis"] = jenis
+	}
+	if aktif := c.Query("aktif"); aktif != "" {
+		filter["aktif"] = aktif == "true"
+	}
+	if driverID := c.Query("driver_id"); driverID != "" {
+		filter["driver_id"] = driverID
+	}
+	list, err := repository.GetKendaraanFiltered(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal mengambil kendaraan", "error": err.Error()})
+	}
+	if list == nil {
+		list = []models.Kendaraan{}
+	}
+	return c.JSON(list)
+}
+
+// GET /kendaraan/:id
+func GetKendaraanByID(c *fiber.Ctx) error {
+	k, err := repository.GetKendaraanByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Kendaraan tidak ditemukan"})
+	}
+	return c.JSON(k)
+}
+
+// Pastikan driver_id (jika diisi) adalah user driver
+func validasiDriverKendaraan(driverID string) error {
+	if driverID == "" {
+		return nil
+	}
+	u, err := repository.GetKaryawanByID(driverID)
+	if err != nil || u == nil || u.Role != "driver" {
+		return errors.New("driver tidak ditemukan")
+	}
+	return nil
+}
+
+// POST /kendaraan (admin)
+func CreateKendaraan(c *fiber.Ctx) error {
+	var k models.Kendaraan
+	if err := c.BodyParser(&k); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Data tidak valid"})
+	}
+	k.PlatNomor = strings.ToUpper(strings.TrimSpace(k.PlatNomor))
+	k.Jenis = strings.ToLower(strings.TrimSpace(k.Jenis))
+	if k.PlatNomor == "" {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": "plat_nomor wajib"})
+	}
+	if k.Jenis != "mobil" && k.Jenis != "motor" {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": "jenis harus salah satu dari: mobil, motor"})
+	}
+	if k.Kapasitas <= 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": "kapasitas harus lebih dari 0"})
+	}
+	if err := validasiDriverKendaraan(k.DriverID); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	id, err := repository.GenerateID("kendaraan")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal generate ID"})
+	}
+	k.ID = id
+	k.Aktif = true
+	k.CreatedAt = time.Now()
+	if _, err := repository.CreateKendaraan(&k); err != nil {
+		if isDuplicateKey(err) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Plat nomor sudah terdaftar"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal menambah kendaraan"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Kendaraan berhasil ditambah", "id": k.ID})
+}
+
+// PUT /kendaraan/:id (admin)
+func UpdateKendaraan(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if _, err := repository.GetKendaraanByID(id); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Kendaraan tidak ditemukan"})
+	}
+	var body struct {
+		PlatNomor string  `json:"plat_nomor"`
+		Jenis     string  `json:"jenis"`
+		Kapasitas int     `json:"kapasitas"`
+		DriverID  *string `json:"driver_id"`
+		Aktif     *bool   `json:"aktif"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Data tidak valid"})
+	}
+	update := bson.M{}
+	if plat := strings.ToUpper(strings.TrimSpace(body.PlatNomor)); plat != "" {
+		update["plat_nomor"] = plat
+	}
+	if jenis := strings.ToLower(strings.TrimSpace(body.Jenis)); jenis != "" {
+		if jenis != "mobil" && jenis != "motor" {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": "jenis harus salah satu dari: mobil, motor"})
+		}
+		update["jenis"] = jenis
+	}
+	if body.Kapasitas < 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": "kapasitas harus lebih dari 0"})
+	}
+	if body.Kapasitas > 0 {
+		update["kapasitas"] = body.Kapasitas
+	}
+	if body.DriverID != nil {
+		if err := validasiDriverKendaraan(*body.DriverID); err != nil {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": err.Error()})
+		}
+		update["driver_id"] = *body.DriverID
+	}
+	if body.Aktif != nil {
+		update["aktif"] = *body.Aktif
+	}
+	if len(update) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Tidak ada perubahan"})
+	}
+	if _, err := repository.UpdateKendaraan(id, update); err != nil {
+		if isDuplicateKey(err) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Plat nomor sudah terdaftar"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal update kendaraan"})
+	}
+	return c.JSON(fiber.Map{"message": "Kendaraan berhasil diupdate"})
+}
+
+// DELETE /kendaraan/:id (admin)
+func DeleteKendaraan(c *fiber.Ctx) error {
+	res, err := repository.DeleteKendaraan(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal hapus kendaraan"})
+	}
+	return c.JSON(fiber.Map{"message": "Kendaraan berhasil dihapus", "deleted": res.DeletedCount})
+}
@@ -0,0 +1,467 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+// lembarLaporan mendefinisikan satu tabel laporan (sheet di xlsx, bagian di pdf)
+type lembarLaporan struct {
+	Nama    string
+	Headers []string
+	// KolomTotal: kolom (1-based) tempat nilai total dicetak di bawah tabel, label di kolom
+	// sebelumnya. 0 = lembar tanpa baris total.
+	KolomTotal int
+}
+
+// totalLaporan adalah satu baris total di bawah tabel
+type totalLaporan struct {
+	Label string
+	Nilai float64
+}
+
+// builderLaporan merender dataset laporan yang sama ke format file tertentu.
+// Urutan pemakaian: Mulai → Baris (berulang, boleh berselang-seling antar lembar) → Selesai → Tutup.
+type builderLaporan interface {
+	Mulai(w io.Writer, lembar []lembarLaporan) error
+	Baris(lembar int, values []interface{}) error
+	Selesai(total []totalLaporan, params []parameterExport) error
+	// Tutup membersihkan file sementara; aman dipanggil berkali-kali
+	Tutup()
+}
+
+// formatExport menghubungkan parameter format dengan builder & header unduhan
+type formatExport struct {
+	ContentType string
+	Ekstensi    string
+	Baru        func() builderLaporan
+}
+
+var formatExportTersedia = map[string]formatExport{
+	"xlsx": {
+		ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		Ekstensi:    ".xlsx",
+		Baru:        func() builderLaporan { return &builderExcel{} },
+	},
+	"csv": {
+		ContentType: "text/csv; charset=utf-8",
+		Ekstensi:    ".csv",
+		Baru:        func() builderLaporan { return &builderCSV{} },
+	},
+	"pdf": {
+		ContentType: "application/pdf",
+		Ekstensi:    ".pdf",
+		Baru:        func() builderLaporan { return &builderPDF{kompres: true, maksBaris: exportPDFMaksBaris()} },
+	},
+}
+
+// parseFormatExport membaca parameter format (default xlsx)
+func parseFormatExport(s string) (formatExport, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		s = "xlsx"
+	}
+	f, ok := formatExportTersedia[s]
+	if !ok {
+		return formatExport{}, fmt.Errorf("format harus salah satu dari: xlsx, csv, pdf")
+	}
+	return f, nil
+}
+
+// Batas baris data export PDF (EXPORT_PDF_MAKS_BARIS, default 20000). PDF dirender utuh di
+// memori, jadi data yang lebih besar harus memakai xlsx/csv.
+func exportPDFMaksBaris() int {
+	if n, err := strconv.Atoi(os.Getenv("EXPORT_PDF_MAKS_BARIS")); err == nil && n > 0 {
+		return n
+	}
+	return 20000
+}
+
+// cekUkuranFormat menolak lebih awal export PDF yang pasti melewati batas baris
+// (setiap pembayaran menghasilkan minimal satu baris)
+func cekUkuranFormat(format formatExport, jumlahPembayaran int64) error {
+	if format.Ekstensi == ".pdf" && jumlahPembayaran > int64(exportPDFMaksBaris()) {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Export PDF dibatasi %d baris (%d pembayaran), gunakan format xlsx atau csv", exportPDFMaksBaris(), jumlahPembayaran))
+	}
+	return nil
+}
+
+// kirimLaporan merender laporan kecil (kartu stok, persediaan, dsb.) lewat builder format lalu
+// mengirimnya sebagai unduhan namaFile+ekstensi. isi menulis baris tiap lembar; total & params
+// diteruskan ke Selesai. Kegagalan render dikembalikan sebagai 500 sebelum ada byte terkirim.
+func kirimLaporan(c *fiber.Ctx, format formatExport, namaFile string, lembar []lembarLaporan, isi func(b builderLaporan) error, total []totalLaporan, params []parameterExport) error {
+	b := format.Baru()
+	defer b.Tutup()
+	var buf bytes.Buffer
+	err := b.Mulai(&buf, lembar)
+	if err == nil {
+		err = isi(b)
+	}
+	if err == nil {
+		err = b.Selesai(total, params)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat file laporan: " + err.Error()})
+	}
+	c.Set("Content-Type", format.ContentType)
+	c.Set("Content-Disposition", "attachment; filename="+namaFile+format.Ekstensi)
+	return c.Send(buf.Bytes())
+}
+
+// teksNilai mengubah nilai sel menjadi teks mesin (angka dengan titik desimal)
+func teksNilai(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(x)
+	}
+	return fmt.Sprint(v)
+}
+
+// formatAngka memformat angka gaya Indonesia untuk dicetak: 1.234.567,5
+func formatAngka(v float64) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', 2, 64)
+	bulat, desimal, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	if v < 0 {
+		b.WriteByte('-')
+	}
+	for i, r := range bulat {
+		if i > 0 && (len(bulat)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(r)
+	}
+	if desimal = strings.TrimRight(desimal, "0"); desimal != "" {
+		b.WriteString("," + desimal)
+	}
+	return b.String()
+}
+
+// ===============================
+// XLSX (StreamWriter)
+// ===============================
+
+type builderExcel struct {
+	w           io.Writer
+	f           *excelize.File
+	lembar      []lembarLaporan
+	sw          []*excelize.StreamWriter
+	row         []int
+	headerStyle int
+}
+
+func (b *builderExcel) Mulai(w io.Writer, lembar []lembarLaporan) error {
+	b.w = w
+	b.lembar = lembar
+	b.f = excelize.NewFile()
+	var err error
+	b.headerStyle, err = b.f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true},
+		Alignment: &excelize.Alignment{Horizontal: "center"},
+	})
+	if err != nil {
+		return err
+	}
+	for i, l := range lembar {
+		if i == 0 {
+			b.f.SetSheetName("Sheet1", l.Nama)
+		} else if _, err := b.f.NewSheet(l.Nama); err != nil {
+			return err
+		}
+		// AutoFilter dipasang sebelum StreamWriter dibuat (tidak tersedia di mode stream)
+		last, _ := excelize.CoordinatesToCellName(len(l.Headers), 1)
+		b.f.AutoFilter(l.Nama, "A1:"+last, []excelize.AutoFilterOptions{})
+	}
+	for _, l := range lembar {
+		sw, err := b.f.NewStreamWriter(l.Nama)
+		if err != nil {
+			return err
+		}
+		if err := sw.SetPanes(&excelize.Panes{Freeze: true, Split: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+			return err
+		}
+		cells := make([]interface{}, len(l.Headers))
+		for i, h := range l.Headers {
+			cells[i] = excelize.Cell{StyleID: b.headerStyle, Value: h}
+		}
+		if err := sw.SetRow("A1", cells); err != nil {
+			return err
+		}
+		b.sw = append(b.sw, sw)
+		b.row = append(b.row, 2)
+	}
+	return nil
+}
+
+func (b *builderExcel) Baris(lembar int, values []interface{}) error {
+	cell, _ := excelize.CoordinatesToCellName(1, b.row[lembar])
+	b.row[lembar]++
+	return b.sw[lembar].SetRow(cell, values)
+}
+
+func (b *builderExcel) Selesai(total []totalLaporan, params []parameterExport) error {
+	for i, l := range b.lembar {
+		if l.KolomTotal > 1 {
+			r := b.row[i] + 1
+			for _, t := range total {
+				cell, _ := excelize.CoordinatesToCellName(l.KolomTotal-1, r)
+				if err := b.sw[i].SetRow(cell, []interface{}{t.Label, t.Nilai}); err != nil {
+					return err
+				}
+				r++
+			}
+		}
+		if err := b.sw[i].Flush(); err != nil {
+			return err
+		}
+	}
+
+	sheetParam := "Parameter"
+	if _, err := b.f.NewSheet(sheetParam); err != nil {
+		return err
+	}
+	for i, h := range []string{"Parameter", "Nilai"} {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		b.f.SetCellValue(sheetParam, cell, h)
+		b.f.SetCellStyle(sheetParam, cell, cell, b.headerStyle)
+	}
+	for i, p := range params {
+		b.f.SetCellValue(sheetParam, fmt.Sprintf("A%d", i+2), p.Nama)
+		b.f.SetCellValue(sheetParam, fmt.Sprintf("B%d", i+2), p.Nilai)
+	}
+	b.f.SetColWidth(sheetParam, "A", "A", 26)
+	b.f.SetColWidth(sheetParam, "B", "B", 48)
+
+	b.f.SetActiveSheet(0)
+	return b.f.Write(b.w)
+}
+
+func (b *builderExcel) Tutup() {
+	if b.f != nil {
+		b.f.Close()
+	}
+}
+
+// ===============================
+// CSV (untuk impor akuntansi)
+// ===============================
+
+// builderCSV hanya menulis lembar pertama (baris data per item) langsung ke w, tanpa baris
+// total & parameter agar file bisa diimpor apa adanya
+type builderCSV struct {
+	cw *csv.Writer
+}
+
+func (b *builderCSV) Mulai(w io.Writer, lembar []lembarLaporan) error {
+	b.cw = csv.NewWriter(w)
+	if len(lembar) == 0 {
+		return nil
+	}
+	return b.cw.Write(lembar[0].Headers)
+}
+
+func (b *builderCSV) Baris(lembar int, values []interface{}) error {
+	if lembar != 0 {
+		return nil
+	}
+	rec := make([]string, len(values))
+	for i, v := range values {
+		rec[i] = teksNilai(v)
+		if _, teks := v.(string); teks {
+			rec[i] = amanCSV(rec[i])
+		}
+	}
+	return b.cw.Write(rec)
+}
+
+// amanCSV mencegah teks (nama pelanggan, produk, dsb.) dibaca sebagai rumus oleh aplikasi
+// spreadsheet: sel yang diawali = + - @ (atau tab/CR) diberi awalan petik tunggal. Hanya
+// dipakai untuk nilai teks; angka negatif tetap apa adanya.
+func amanCSV(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (b *builderCSV) Selesai(total []totalLaporan, params []parameterExport) error {
+	b.cw.Flush()
+	return b.cw.Error()
+}
+
+func (b *builderCSV) Tutup() {}
+
+// ===============================
+// PDF (untuk dicetak / dikirim email)
+// ===============================
+
+// builderPDF menampung baris per lembar lalu merender tabel A4 landscape saat Selesai,
+// karena halaman PDF harus ditulis berurutan per bagian. Karena seluruh baris ditampung,
+// jumlahnya dibatasi maksBaris (0 = tanpa batas).
+type builderPDF struct {
+	w         io.Writer
+	lembar    []lembarLaporan
+	baris     [][][]interface{}
+	jumlah    int
+	maksBaris int
+	kompres   bool
+}
+
+func (b *builderPDF) Mulai(w io.Writer, lembar []lembarLaporan) error {
+	b.w = w
+	b.lembar = lembar
+	b.baris = make([][][]interface{}, len(lembar))
+	return nil
+}
+
+func (b *builderPDF) Baris(lembar int, values []interface{}) error {
+	b.jumlah++
+	if b.maksBaris > 0 && b.jumlah > b.maksBaris {
+		return fmt.Errorf("export PDF melebihi batas %d baris, gunakan format xlsx atau csv", b.maksBaris)
+	}
+	b.baris[lembar] = append(b.baris[lembar], values)
+	return nil
+}
+
+// teksPDF: angka diformat gaya Indonesia, selain itu apa adanya
+func teksPDF(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case float64:
+		return formatAngka(x), true
+	case int:
+		return formatAngka(float64(x)), true
+	}
+	return teksNilai(v), false
+}
+
+func (b *builderPDF) Selesai(total []totalLaporan, params []parameterExport) error {
+	pdf := fpdf.New("L", "mm", "A4", "")
+	pdf.SetCompression(b.kompres)
+	pdf.SetMargins(10, 10, 10)
+	pdf.SetAutoPageBreak(false, 10)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	lebarHalaman, tinggiHalaman := pdf.GetPageSize()
+	lebarIsi := lebarHalaman - 20
+	const tinggiBaris = 6.0
+
+	potong := func(s string, lebar float64) string {
+		s = tr(s)
+		if pdf.GetStringWidth(s) <= lebar-2 {
+			return s
+		}
+		for len(s) > 0 && pdf.GetStringWidth(s+"...") > lebar-2 {
+			s = s[:len(s)-1]
+		}
+		return s + "..."
+	}
+
+	for i, l := range b.lembar {
+		// Lebar kolom proporsional terhadap teks terpanjang (dibatasi) per kolom
+		bobot := make([]float64, len(l.Headers))
+		for c, h := range l.Headers {
+			bobot[c] = float64(len(h))
+		}
+		for _, row := range b.baris[i] {
+			for c, v := range row {
+				if c < len(bobot) {
+					s, _ := teksPDF(v)
+					bobot[c] = math.Max(bobot[c], float64(len(s)))
+				}
+			}
+		}
+		var totalBobot float64
+		for c := range bobot {
+			bobot[c] = math.Min(math.Max(bobot[c], 4), 30)
+			totalBobot += bobot[c]
+		}
+		lebar := make([]float64, len(bobot))
+		for c := range bobot {
+			lebar[c] = lebarIsi * bobot[c] / totalBobot
+		}
+
+		header := func() {
+			pdf.SetFont("Helvetica", "B", 7)
+			pdf.SetFillColor(230, 230, 230)
+			for c, h := range l.Headers {
+				pdf.CellFormat(lebar[c], tinggiBaris, potong(h, lebar[c]), "1", 0, "C", true, 0, "")
+			}
+			pdf.Ln(-1)
+			pdf.SetFont("Helvetica", "", 7)
+		}
+		barisBaru := func() {
+			if pdf.GetY()+tinggiBaris > tinggiHalaman-10 {
+				pdf.AddPage()
+				header()
+			}
+		}
+
+		pdf.AddPage()
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.CellFormat(lebarIsi, 8, tr(l.Nama), "", 1, "L", false, 0, "")
+		header()
+		for _, row := range b.baris[i] {
+			barisBaru()
+			for c := range l.Headers {
+				var v interface{}
+				if c < len(row) {
+					v = row[c]
+				}
+				s, angka := teksPDF(v)
+				align := "L"
+				if angka {
+					align = "R"
+				}
+				pdf.CellFormat(lebar[c], tinggiBaris, potong(s, lebar[c]), "1", 0, align, false, 0, "")
+			}
+			pdf.Ln(-1)
+		}
+
+		if l.KolomTotal > 1 && l.KolomTotal <= len(lebar) {
+			pdf.SetFont("Helvetica", "B", 7)
+			var kiri float64
+			for c := 0; c < l.KolomTotal-2; c++ {
+				kiri += lebar[c]
+			}
+			lebarLabel := lebar[l.KolomTotal-2]
+			lebarNilai := lebar[l.KolomTotal-1]
+			for _, t := range total {
+				barisBaru()
+				pdf.SetX(10 + kiri)
+				// Label total bisa lebih lebar dari kolomnya: rata kanan ke kolom nilai
+				pdf.CellFormat(lebarLabel, tinggiBaris, tr(t.Label), "", 0, "R", false, 0, "")
+				pdf.CellFormat(lebarNilai, tinggiBaris, formatAngka(t.Nilai), "1", 1, "R", false, 0, "")
+			}
+		}
+	}
+
+	// Bagian parameter
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(lebarIsi, 8, "Parameter", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	for _, p := range params {
+		pdf.CellFormat(60, tinggiBaris, tr(p.Nama), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(lebarIsi-60, tinggiBaris, potong(p.Nilai, lebarIsi-60), "1", 1, "L", false, 0, "")
+	}
+
+	return pdf.Output(b.w)
+}
+
+func (b *builderPDF) Tutup() {}
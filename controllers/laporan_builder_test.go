@@ -0,0 +1,254 @@
+package controllers
+
+import (
+	"backend/models"
+	"bytes"
+	"encoding/csv"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// dataUji: 3 pembayaran; PAY2 tanpa pengiriman, PAY3 transaksinya tidak ditemukan
+func dataUji() []barisPembayaran {
+	waktu := time.Date(2026, 3, 14, 10, 30, 0, 0, time.Local)
+	return []barisPembayaran{
+		{
+			Pembayaran: models.Pembayaran{ID: "PAY1", TransaksiID: "TRX1", TotalBayar: 47500, Status: "selesai", CreatedAt: waktu},
+			Transaksi: &models.Transaksi{ID: "TRX1", TotalProduk: 3, Items: []models.TransaksiItem{
+				{ProdukID: "PRD1", NamaProduk: "Beras 5kg", Jumlah: 1, Harga: 30000},
+				{ProdukID: "PRD2", Jumlah: 2, Harga: 5000},
+			}},
+			Pengiriman: models.Pengiriman{Jenis: "antar", Ongkir: 7500},
+			Pelanggan:  "Bu Sari",
+			Kasir:      "Kasir Satu",
+			Driver:     "Driver Satu",
+			NamaProduk: map[string]string{"PRD2": "Telur (butir)"},
+		},
+		{
+			Pembayaran: models.Pembayaran{ID: "PAY2", TransaksiID: "TRX2", TotalBayar: 12345.5, Status: "pending", CreatedAt: waktu},
+			Transaksi: &models.Transaksi{ID: "TRX2", TotalProduk: 1, Items: []models.TransaksiItem{
+				{ProdukID: "PRD3", NamaProduk: "Minyak, 1L", Jumlah: 1, Harga: 12345.5},
+			}},
+			Pelanggan: "Pak Budi",
+			Kasir:     "Kasir Dua",
+		},
+		{
+			Pembayaran: models.Pembayaran{ID: "PAY3", TransaksiID: "TRX404", TotalBayar: 1000, Status: "selesai", CreatedAt: waktu},
+		},
+	}
+}
+
+// render menjalankan dataset uji lewat builder dan mengembalikan file beserta totalnya
+func render(t *testing.T, b builderLaporan, data []barisPembayaran) ([]byte, []totalLaporan) {
+	t.Helper()
+	var buf bytes.Buffer
+	defer b.Tutup()
+	lap, err := newLaporanPembayaran(b, &buf)
+	if err != nil {
+		t.Fatalf("Mulai: %v", err)
+	}
+	for _, r := range data {
+		if err := lap.tambah(r); err != nil {
+			t.Fatalf("tambah %s: %v", r.Pembayaran.ID, err)
+		}
+	}
+	if err := lap.selesai([]parameterExport{{"Periode", "14-03-2026 s/d 14-03-2026"}}); err != nil {
+		t.Fatalf("Selesai: %v", err)
+	}
+	return buf.Bytes(), lap.total()
+}
+
+func samaKira(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+
+func TestTotalLaporanPembayaran(t *testing.T) {
+	_, total := render(t, &builderCSV{}, dataUji())
+	want := []float64{30000 + 2*5000 + 12345.5, 7500, 30000 + 2*5000 + 12345.5 + 7500}
+	for i, tt := range total {
+		if !samaKira(tt.Nilai, want[i]) {
+			t.Errorf("%s = %v, want %v", tt.Label, tt.Nilai, want[i])
+		}
+	}
+}
+
+func TestOngkirDihitungSekaliPerTransaksi(t *testing.T) {
+	data := dataUji()[:1]
+	// Pembayaran kedua untuk transaksi yang sama
+	kedua := data[0]
+	kedua.Pembayaran.ID = "PAY1B"
+	data = append(data, kedua)
+
+	_, total := render(t, &builderCSV{}, data)
+	if !samaKira(total[1].Nilai, 7500) {
+		t.Fatalf("TOTAL ONGKIR = %v, want 7500", total[1].Nilai)
+	}
+	if !samaKira(total[0].Nilai, 2*40000) {
+		t.Fatalf("TOTAL SUBTOTAL = %v, want 80000", total[0].Nilai)
+	}
+}
+
+func TestTotalSamaAntarFormat(t *testing.T) {
+	data := dataUji()
+
+	// XLSX: baris total di bawah kolom Subtotal sheet Detail Produk
+	xlsx, total := render(t, &builderExcel{}, data)
+	f, err := excelize.OpenReader(bytes.NewReader(xlsx))
+	if err != nil {
+		t.Fatalf("buka xlsx: %v", err)
+	}
+	defer f.Close()
+	rows, err := f.GetRows("Detail Produk")
+	if err != nil {
+		t.Fatalf("baca sheet: %v", err)
+	}
+	totalXlsx := map[string]float64{}
+	var subtotalXlsx float64
+	for _, row := range rows[1:] {
+		if len(row) >= 10 && row[0] == "" && row[8] != "" {
+			v, _ := strconv.ParseFloat(row[9], 64)
+			totalXlsx[row[8]] = v
+			continue
+		}
+		if len(row) >= 10 && row[0] != "" {
+			v, _ := strconv.ParseFloat(row[9], 64)
+			subtotalXlsx += v
+		}
+	}
+	for _, tt := range total {
+		if got, ok := totalXlsx[tt.Label]; !ok || !samaKira(got, tt.Nilai) {
+			t.Errorf("xlsx %s = %v, want %v", tt.Label, got, tt.Nilai)
+		}
+	}
+	if !samaKira(subtotalXlsx, total[0].Nilai) {
+		t.Errorf("xlsx jumlah subtotal = %v, want %v", subtotalXlsx, total[0].Nilai)
+	}
+	if sheets := f.GetSheetList(); len(sheets) != 3 || sheets[2] != "Parameter" {
+		t.Errorf("sheet = %v", sheets)
+	}
+
+	// CSV: hanya baris data; jumlah kolom Subtotal & Ongkir harus sama dengan total
+	csvData, _ := render(t, &builderCSV{}, data)
+	records, err := csv.NewReader(bytes.NewReader(csvData)).ReadAll()
+	if err != nil {
+		t.Fatalf("baca csv: %v", err)
+	}
+	if got := len(records); got != 1+3 {
+		t.Fatalf("csv baris = %d, want 4 (header + 3 item)", got)
+	}
+	var subtotalCSV, ongkirCSV float64
+	for _, rec := range records[1:] {
+		v, err := strconv.ParseFloat(rec[9], 64)
+		if err != nil {
+			t.Fatalf("subtotal csv %q: %v", rec[9], err)
+		}
+		subtotalCSV += v
+		if rec[10] != "" {
+			o, _ := strconv.ParseFloat(rec[10], 64)
+			ongkirCSV += o
+		}
+	}
+	if !samaKira(subtotalCSV, total[0].Nilai) || !samaKira(ongkirCSV, total[1].Nilai) {
+		t.Errorf("csv subtotal/ongkir = %v/%v, want %v/%v", subtotalCSV, ongkirCSV, total[0].Nilai, total[1].Nilai)
+	}
+	if records[3][6] != "Minyak, 1L" || records[2][6] != "Telur (butir)" {
+		t.Errorf("nama produk csv = %q, %q", records[3][6], records[2][6])
+	}
+
+	// PDF: tanpa kompresi agar teks total bisa diperiksa langsung
+	pdf, _ := render(t, &builderPDF{}, data)
+	if !bytes.HasPrefix(pdf, []byte("%PDF-")) {
+		t.Fatalf("bukan file pdf")
+	}
+	escape := strings.NewReplacer("(", `\(`, ")", `\)`)
+	for _, tt := range total {
+		for _, s := range []string{tt.Label, formatAngka(tt.Nilai)} {
+			if !bytes.Contains(pdf, []byte("("+escape.Replace(s)+")")) {
+				t.Errorf("pdf tidak memuat %q", s)
+			}
+		}
+	}
+}
+
+func TestFormatAngka(t *testing.T) {
+	tests := map[float64]string{
+		0:          "0",
+		999:        "999",
+		1000:       "1.000",
+		52345.5:    "52.345,5",
+		1234567.25: "1.234.567,25",
+		-7500:      "-7.500",
+	}
+	for v, want := range tests {
+		if got := formatAngka(v); got != want {
+			t.Errorf("formatAngka(%v) = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func TestParseFormatExport(t *testing.T) {
+	for _, s := range []string{"", "xlsx", "CSV", " pdf "} {
+		if _, err := parseFormatExport(s); err != nil {
+			t.Errorf("parseFormatExport(%q) error: %v", s, err)
+		}
+	}
+	if _, err := parseFormatExport("docx"); err == nil {
+		t.Errorf("parseFormatExport(docx) harus error")
+	}
+}
+
+func TestCSVTanpaRumus(t *testing.T) {
+	data := dataUji()
+	data[0].Pelanggan = "=HYPERLINK(\"http://contoh\")"
+	data[1].Pelanggan = "@SUM(A1)"
+	data[1].Kasir = "+62 812"
+	out, _ := render(t, &builderCSV{}, data)
+	rows, err := csv.NewReader(bytes.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("csv tidak valid: %v", err)
+	}
+	for _, row := range rows[1:] {
+		for _, cell := range row {
+			if cell == "" {
+				continue
+			}
+			if strings.ContainsRune("=+@", rune(cell[0])) {
+				t.Errorf("sel %q masih bisa dibaca sebagai rumus", cell)
+			}
+		}
+	}
+
+	tests := map[string]string{
+		"=1+1":    "'=1+1",
+		"-SUM()":  "'-SUM()",
+		"@A1":     "'@A1",
+		"Bu Sari": "Bu Sari",
+		"":        "",
+	}
+	for in, want := range tests {
+		if got := amanCSV(in); got != want {
+			t.Errorf("amanCSV(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPDFDibatasiJumlahBaris(t *testing.T) {
+	b := &builderPDF{maksBaris: 2}
+	defer b.Tutup()
+	lap, err := newLaporanPembayaran(b, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Mulai: %v", err)
+	}
+	var gagal error
+	for _, r := range dataUji() {
+		if gagal = lap.tambah(r); gagal != nil {
+			break
+		}
+	}
+	if gagal == nil {
+		t.Fatalf("export PDF melebihi batas baris harus error")
+	}
+}
@@ -1,8 +1,13 @@
 package controllers
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
 	"time"
 
 	"backend/config"
@@ -10,17 +15,36 @@ import (
 	"backend/repository"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/xuri/excelize/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func buildCreatedAtFilterFromQuery(c *fiber.Ctx) (bson.M, error) {
-	startStr := c.Query("start", "")
-	endStr := c.Query("end", "")
-	monthStr := c.Query("month", "")
-	yearStr := c.Query("year", "")
+	return buildCreatedAtFilter(c.Query)
+}
+
+// ambilQuery membaca parameter query: c.Query untuk request, atau queryDari untuk job laporan
+type ambilQuery func(key string, defaultValue ...string) string
+
+// queryDari membungkus filter tersimpan (mis. milik job laporan) sebagai ambilQuery
+func queryDari(v map[string]string) ambilQuery {
+	return func(key string, defaultValue ...string) string {
+		if s := v[key]; s != "" {
+			return s
+		}
+		if len(defaultValue) > 0 {
+			return defaultValue[0]
+		}
+		return ""
+	}
+}
+
+func buildCreatedAtFilter(query ambilQuery) (bson.M, error) {
+	startStr := query("start", "")
+	endStr := query("end", "")
+	monthStr := query("month", "")
+	yearStr := query("year", "")
 
 	// Priority:
 	// 1) explicit start/end
@@ -133,382 +157,424 @@ func parseInt(s string) (int, error) {
 	return i, err
 }
 
-func (lc *LaporanController) ExportExcel(c *fiber.Ctx) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-	defer cancel()
+// Batas jumlah pembayaran untuk export langsung (EXPORT_SYNC_MAKS, default 5000);
+// di atas itu export harus lewat job laporan (POST /laporan/jobs)
+func exportSyncMaks() int64 {
+	if n, err := strconv.ParseInt(os.Getenv("EXPORT_SYNC_MAKS"), 10, 64); err == nil && n > 0 {
+		return n
+	}
+	return 5000
+}
 
-	// ===============================
-	// FILTERS (match UI: pembayaran-centric)
-	// ===============================
-	dateFilter, err := buildCreatedAtFilterFromQuery(c)
+// ExportExcel godoc
+//
+//	@Summary		Export laporan pembayaran
+//	@Description	Format xlsx (default), csv, atau pdf lewat ?format=. File dikirim bertahap (streaming).
+//	@Description	Jika jumlah pembayaran melebihi EXPORT_SYNC_MAKS (default 5000) ditolak 413; gunakan POST /laporan/jobs.
+//	@Tags			Laporan
+//	@Security		BearerAuth
+//	@Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Success		200	{file}		file
+//	@Failure		400	{object}	map[string]interface{}	"Filter tidak valid"
+//	@Failure		413	{object}	map[string]interface{}	"Data terlalu besar untuk export langsung"
+//	@Router			/laporan/export/excel [get]
+func (lc *LaporanController) ExportExcel(c *fiber.Ctx) error {
+	format, err := parseFormatExport(c.Query("format"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	filter := bson.M{}
-	for k, v := range dateFilter {
-		filter[k] = v
-	}
-
-	// UI excludes these pembayaran IDs
-	excludeIDs := []string{"PMB005", "PMB004", "PMB003", "PMB002", "PMB001"}
-	filter["_id"] = bson.M{"$nin": excludeIDs}
-
-	// Export only Pending/Selesai (case defensive)
-	filter["status"] = bson.M{"$in": []string{"pending", "selesai", "Pending", "Selesai"}}
-
 	// ===============================
-	// LOAD DATA (pembayaran as source of truth)
+	// FILTERS (pembayaran-centric, lihat filterExportExcel)
 	// ===============================
-	pembayaranColl := lc.DB.Collection("pembayaran")
-	trxColl := lc.DB.Collection("transaksi")
-	userColl := lc.DB.Collection("user")
-	pelangganColl := lc.DB.Collection("pelanggan")
-	pengirimanColl := lc.DB.Collection("pengiriman")
-	produkColl := lc.DB.Collection("produk")
-
-	findOpts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
-	curPay, err := pembayaranColl.Find(ctx, filter, findOpts)
+	pengaturan, err := repository.GetPengaturanLaporan()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
-	defer curPay.Close(ctx)
-
-	payments := make([]models.Pembayaran, 0)
-	trxIDSet := map[string]struct{}{}
-	for curPay.Next(ctx) {
-		var p models.Pembayaran
-		if err := curPay.Decode(&p); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "gagal decode pembayaran"})
-		}
-		payments = append(payments, p)
-		if p.TransaksiID != "" {
-			trxIDSet[p.TransaksiID] = struct{}{}
-		}
+	filter, params, err := filterExportExcel(c.Query, pengaturan)
+	if err != nil {
+		return tolakLaporan(c, err)
 	}
-	if err := curPay.Err(); err != nil {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	n, err := lc.DB.Collection("pembayaran").CountDocuments(ctx, filter)
+	cancel()
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+	if n > exportSyncMaks() {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error":  fmt.Sprintf("Data export terlalu besar (%d pembayaran), gunakan POST /laporan/jobs", n),
+			"jumlah": n,
+		})
+	}
+	if err := cekUkuranFormat(format, n); err != nil {
+		return tolakLaporan(c, err)
+	}
 
-	trxIDs := make([]string, 0, len(trxIDSet))
-	for id := range trxIDSet {
-		trxIDs = append(trxIDs, id)
+	dibuatOleh, _ := c.Locals("userNama").(string)
+	if dibuatOleh == "" {
+		dibuatOleh, _ = c.Locals("userID").(string)
 	}
 
-	trxMap := map[string]models.Transaksi{}
-	if len(trxIDs) > 0 {
-		curTrx, err := trxColl.Find(ctx, bson.M{"_id": bson.M{"$in": trxIDs}})
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	// Response: file ditulis langsung ke koneksi, tidak ditampung utuh di memori. Header
+	// sudah terkirim saat penulisan berjalan, jadi kegagalan di tengah hanya bisa dicatat di log
+	// (file di sisi klien terpotong).
+	c.Set("Content-Type", format.ContentType)
+	c.Set("Content-Disposition", "attachment; filename=laporan_mbg"+format.Ekstensi)
+	db := lc.DB
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		if _, err := tulisLaporanPembayaran(ctx, db, filter, params, dibuatOleh, format.Baru(), w); err != nil {
+			log.Printf("⚠️ Export laporan gagal di tengah streaming: %v", err)
 		}
-		defer curTrx.Close(ctx)
-		for curTrx.Next(ctx) {
-			var t models.Transaksi
-			if err := curTrx.Decode(&t); err == nil {
-				trxMap[t.ID] = t
-			}
+		if err := w.Flush(); err != nil {
+			log.Printf("⚠️ Export laporan gagal dikirim: %v", err)
 		}
-	}
+	})
+	return nil
+}
 
-	shipByTrx := map[string]models.Pengiriman{}
-	userIDSet := map[string]struct{}{}
-	pelangganIDSet := map[string]struct{}{}
-	produkIDSet := map[string]struct{}{}
+// Jumlah pembayaran per batch saat export: transaksi & pengiriman di-lookup per batch
+const exportBatch = 500
 
-	// collect pelanggan/kasir/product IDs from transaksi
-	for _, t := range trxMap {
-		if t.PelangganID != "" {
-			pelangganIDSet[t.PelangganID] = struct{}{}
+// muatNama mengisi dst[id] dengan field nama dokumen koleksi untuk id yang belum ada di dst.
+// ID yang tidak ditemukan dipetakan ke dirinya sendiri agar tidak dicari ulang.
+func muatNama(ctx context.Context, coll *mongo.Collection, ids map[string]struct{}, field string, dst map[string]string) error {
+	cari := make([]string, 0, len(ids))
+	for id := range ids {
+		if _, ok := dst[id]; !ok {
+			cari = append(cari, id)
 		}
-		if t.KasirID != "" {
-			userIDSet[t.KasirID] = struct{}{}
+	}
+	if len(cari) == 0 {
+		return nil
+	}
+	cur, err := coll.Find(ctx, bson.M{"_id": bson.M{"$in": cari}}, options.Find().SetProjection(bson.M{field: 1}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		var doc bson.M
+		if err := cur.Decode(&doc); err != nil {
+			continue
 		}
-		for _, it := range t.Items {
-			if it.NamaProduk == "" && it.ProdukID != "" {
-				produkIDSet[it.ProdukID] = struct{}{}
-			}
+		id, _ := doc["_id"].(string)
+		nama, _ := doc[field].(string)
+		dst[id] = nama
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+	for _, id := range cari {
+		if dst[id] == "" {
+			dst[id] = id
 		}
 	}
+	return nil
+}
 
-	// fetch pengiriman in bulk
-	if len(trxIDs) > 0 {
-		curShip, err := pengirimanColl.Find(ctx, bson.M{"transaksi_id": bson.M{"$in": trxIDs}})
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-		}
-		defer curShip.Close(ctx)
-		for curShip.Next(ctx) {
-			var s models.Pengiriman
-			if err := curShip.Decode(&s); err == nil {
-				// keep first record per transaksi_id (match UI behavior)
-				if _, exists := shipByTrx[s.TransaksiID]; !exists {
-					shipByTrx[s.TransaksiID] = s
-				}
-				if s.DriverID != "" {
-					userIDSet[s.DriverID] = struct{}{}
-				}
-			}
-		}
+// Lembar dataset export pembayaran; urutan = indeks pada builderLaporan.Baris
+const (
+	lembarDetail = iota
+	lembarRingkas
+)
+
+var lembarPembayaran = []lembarLaporan{
+	lembarDetail: {
+		Nama: "Detail Produk",
+		Headers: []string{
+			"ID Transaksi",
+			"Tanggal",
+			"Pelanggan",
+			"Kasir",
+			"Driver",
+			"Jenis Pengiriman",
+			"Nama Produk",
+			"Jumlah",
+			"Harga",
+			"Subtotal",
+			"Ongkir",
+			"Status Pembayaran",
+		},
+		KolomTotal: 10, // di bawah kolom Subtotal
+	},
+	lembarRingkas: {
+		Nama: "Ringkasan Transaksi",
+		Headers: []string{
+			"ID Transaksi",
+			"Tanggal",
+			"Pelanggan",
+			"Total Produk",
+			"Ongkir",
+			"Total Toko",
+			"Status Pembayaran",
+		},
+	},
+}
+
+// barisPembayaran adalah satu pembayaran beserta data terkait yang sudah di-lookup
+type barisPembayaran struct {
+	Pembayaran models.Pembayaran
+	Transaksi  *models.Transaksi // nil jika transaksi tidak ditemukan
+	Pengiriman models.Pengiriman
+	Pelanggan  string
+	Kasir      string
+	Driver     string
+	// NamaProduk: fallback nama per produk_id jika item transaksi tidak menyimpan NamaProduk
+	NamaProduk map[string]string
+}
+
+// laporanPembayaran menyusun dataset export pembayaran ke builder (format apa pun)
+// dan menghitung totalnya, sehingga semua format memakai baris & total yang sama
+type laporanPembayaran struct {
+	b               builderLaporan
+	jumlah          int
+	totalSubtotal   float64
+	totalOngkir     float64
+	seenOngkirByTrx map[string]struct{}
+}
+
+func newLaporanPembayaran(b builderLaporan, w io.Writer) (*laporanPembayaran, error) {
+	if err := b.Mulai(w, lembarPembayaran); err != nil {
+		return nil, err
 	}
+	return &laporanPembayaran{b: b, seenOngkirByTrx: map[string]struct{}{}}, nil
+}
+
+func (l *laporanPembayaran) tambah(r barisPembayaran) error {
+	pay := r.Pembayaran
+	ongkir := r.Pengiriman.Ongkir
+	l.jumlah++
 
-	// fetch pelanggan in bulk
-	pelangganIDs := make([]string, 0, len(pelangganIDSet))
-	for id := range pelangganIDSet {
-		pelangganIDs = append(pelangganIDs, id)
+	// Ringkasan Transaksi (satu baris per pembayaran)
+	totalToko := pay.TotalBayar - ongkir
+	if totalToko < 0 {
+		totalToko = 0
 	}
-	pelangganMap := map[string]models.Pelanggan{}
-	if len(pelangganIDs) > 0 {
-		curPel, err := pelangganColl.Find(ctx, bson.M{"_id": bson.M{"$in": pelangganIDs}})
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-		}
-		defer curPel.Close(ctx)
-		for curPel.Next(ctx) {
-			var p models.Pelanggan
-			if err := curPel.Decode(&p); err == nil {
-				pelangganMap[p.ID] = p
-			}
-		}
+	totalProduk := 0
+	if r.Transaksi != nil {
+		totalProduk = r.Transaksi.TotalProduk
+	}
+	if err := l.b.Baris(lembarRingkas, []interface{}{
+		pay.ID,
+		pay.CreatedAt.Format("02-01-2006"),
+		r.Pelanggan,
+		totalProduk,
+		ongkir,
+		totalToko,
+		pay.Status,
+	}); err != nil {
+		return err
 	}
 
-	// fetch users (kasir + driver) in bulk
-	userIDs := make([]string, 0, len(userIDSet))
-	for id := range userIDSet {
-		userIDs = append(userIDs, id)
+	// Detail Produk (pembayaran -> transaksi -> items)
+	if r.Transaksi == nil {
+		return nil
 	}
-	userMap := map[string]models.User{}
-	if len(userIDs) > 0 {
-		curUser, err := userColl.Find(ctx, bson.M{"_id": bson.M{"$in": userIDs}})
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	for idx, it := range r.Transaksi.Items {
+		namaProduk := it.NamaProduk
+		if namaProduk == "" {
+			if n := r.NamaProduk[it.ProdukID]; n != "" {
+				namaProduk = n
+			} else {
+				namaProduk = it.ProdukID
+			}
 		}
-		defer curUser.Close(ctx)
-		for curUser.Next(ctx) {
-			var u models.User
-			if err := curUser.Decode(&u); err == nil {
-				userMap[u.ID] = u
+		subtotal := float64(it.Jumlah) * it.Harga
+		l.totalSubtotal += subtotal
+		var ongkirCell interface{} = ""
+		if idx == 0 {
+			ongkirCell = ongkir
+			// Ongkir dijumlah sekali per transaksi
+			if _, already := l.seenOngkirByTrx[pay.TransaksiID]; !already {
+				l.totalOngkir += ongkir
+				l.seenOngkirByTrx[pay.TransaksiID] = struct{}{}
 			}
 		}
+		if err := l.b.Baris(lembarDetail, []interface{}{
+			pay.ID,
+			pay.CreatedAt.Format("02-01-2006 15:04"),
+			r.Pelanggan,
+			r.Kasir,
+			r.Driver,
+			r.Pengiriman.Jenis,
+			namaProduk,
+			it.Jumlah,
+			it.Harga,
+			subtotal,
+			ongkirCell,
+			pay.Status,
+		}); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	// fetch produk (fallback name when transaksi item doesn't include NamaProduk)
-	produkIDs := make([]string, 0, len(produkIDSet))
-	for id := range produkIDSet {
-		produkIDs = append(produkIDs, id)
-	}
-	produkNameMap := map[string]string{}
-	if len(produkIDs) > 0 {
-		curProduk, err := produkColl.Find(ctx, bson.M{"_id": bson.M{"$in": produkIDs}})
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-		}
-		defer curProduk.Close(ctx)
-		for curProduk.Next(ctx) {
-			var pr models.Produk
-			if err := curProduk.Decode(&pr); err == nil {
-				produkNameMap[pr.ID] = pr.NamaProduk
-			}
-		}
+// total: ringkasan di bawah tabel Detail Produk
+func (l *laporanPembayaran) total() []totalLaporan {
+	return []totalLaporan{
+		{"TOTAL SUBTOTAL", l.totalSubtotal},
+		{"TOTAL ONGKIR", l.totalOngkir},
+		{"TOTAL BAYAR (SUBTOTAL+ONGKIR)", l.totalSubtotal + l.totalOngkir},
 	}
+}
 
-	// ===============================
-	// BUILD EXCEL
-	// ===============================
-	f := excelize.NewFile()
-	headerStyle, _ := f.NewStyle(&excelize.Style{
-		Font:      &excelize.Font{Bold: true},
-		Alignment: &excelize.Alignment{Horizontal: "center"},
-	})
+func (l *laporanPembayaran) selesai(params []parameterExport) error {
+	return l.b.Selesai(l.total(), params)
+}
 
-	writeHeaders := func(sheet string, headers []string) {
-		for i, h := range headers {
-			cell, _ := excelize.CoordinatesToCellName(i+1, 1)
-			f.SetCellValue(sheet, cell, h)
-			f.SetCellStyle(sheet, cell, cell, headerStyle)
-		}
+// tulisLaporanPembayaran membaca pembayaran lewat cursor per batch (transaksi, pengiriman &
+// nama di-lookup per batch) lalu merendernya dengan builder ke w. Mengembalikan jumlah pembayaran.
+func tulisLaporanPembayaran(ctx context.Context, db *mongo.Database, filter bson.M, params []parameterExport, dibuatOleh string, b builderLaporan, w io.Writer) (int, error) {
+	pembayaranColl := db.Collection("pembayaran")
+	trxColl := db.Collection("transaksi")
+	userColl := db.Collection("user")
+	pelangganColl := db.Collection("pelanggan")
+	pengirimanColl := db.Collection("pengiriman")
+	produkColl := db.Collection("produk")
+
+	defer b.Tutup()
+	lap, err := newLaporanPembayaran(b, w)
+	if err != nil {
+		return 0, err
 	}
 
-	sheetDetail := "Detail Produk"
-	f.SetSheetName("Sheet1", sheetDetail)
-	writeHeaders(sheetDetail, []string{
-		"ID Transaksi",
-		"Tanggal",
-		"Pelanggan",
-		"Kasir",
-		"Driver",
-		"Jenis Pengiriman",
-		"Nama Produk",
-		"Jumlah",
-		"Harga",
-		"Subtotal",
-		"Ongkir",
-		"Status Pembayaran",
-	})
-
-	sheetRingkas := "Ringkasan Transaksi"
-	f.NewSheet(sheetRingkas)
-	writeHeaders(sheetRingkas, []string{
-		"ID Transaksi",
-		"Tanggal",
-		"Pelanggan",
-		"Total Produk",
-		"Ongkir",
-		"Total Toko",
-		"Status Pembayaran",
-	})
+	// Nama kasir/driver, pelanggan & produk di-cache lintas batch
+	userMap := map[string]string{}
+	pelangganMap := map[string]string{}
+	produkNameMap := map[string]string{}
 
-	// Sheet 2: Ringkasan Transaksi (loop pembayaran)
-	rowR := 2
-	for _, pay := range payments {
-		tx, hasTx := trxMap[pay.TransaksiID]
-		ship := shipByTrx[pay.TransaksiID]
-		ongkir := ship.Ongkir
-		totalToko := pay.TotalBayar - ongkir
-		if totalToko < 0 {
-			totalToko = 0
+	tulisBatch := func(payments []models.Pembayaran) error {
+		trxIDs := make([]string, 0, len(payments))
+		for _, p := range payments {
+			if p.TransaksiID != "" {
+				trxIDs = append(trxIDs, p.TransaksiID)
+			}
 		}
 
-		pelName := ""
-		kasirName := ""
-		if hasTx {
-			if p, ok := pelangganMap[tx.PelangganID]; ok {
-				pelName = p.Nama
-			} else {
-				pelName = tx.PelangganID
+		trxMap := map[string]models.Transaksi{}
+		shipByTrx := map[string]models.Pengiriman{}
+		userIDSet := map[string]struct{}{}
+		pelangganIDSet := map[string]struct{}{}
+		produkIDSet := map[string]struct{}{}
+		if len(trxIDs) > 0 {
+			curTrx, err := trxColl.Find(ctx, bson.M{"_id": bson.M{"$in": trxIDs}})
+			if err != nil {
+				return err
 			}
-			if u, ok := userMap[tx.KasirID]; ok {
-				kasirName = u.Nama
-			} else {
-				kasirName = tx.KasirID
+			defer curTrx.Close(ctx)
+			for curTrx.Next(ctx) {
+				var t models.Transaksi
+				if err := curTrx.Decode(&t); err == nil {
+					trxMap[t.ID] = t
+				}
 			}
-		}
-		_ = kasirName // kept for parity with detail sheet; not used in ringkasan columns
 
-		values := []interface{}{
-			pay.ID,
-			pay.CreatedAt.Format("02-01-2006"),
-			pelName,
-			func() int {
-				if hasTx {
-					return tx.TotalProduk
+			curShip, err := pengirimanColl.Find(ctx, bson.M{"transaksi_id": bson.M{"$in": trxIDs}})
+			if err != nil {
+				return err
+			}
+			defer curShip.Close(ctx)
+			for curShip.Next(ctx) {
+				var s models.Pengiriman
+				if err := curShip.Decode(&s); err == nil {
+					// keep first record per transaksi_id (match UI behavior)
+					if _, exists := shipByTrx[s.TransaksiID]; !exists {
+						shipByTrx[s.TransaksiID] = s
+					}
+					if s.DriverID != "" {
+						userIDSet[s.DriverID] = struct{}{}
+					}
 				}
-				return 0
-			}(),
-			ongkir,
-			totalToko,
-			pay.Status,
-		}
-		for i, v := range values {
-			cell, _ := excelize.CoordinatesToCellName(i+1, rowR)
-			f.SetCellValue(sheetRingkas, cell, v)
+			}
 		}
-		rowR++
-	}
 
-	// Sheet 1: Detail Produk (loop pembayaran -> transaksi -> items)
-	rowD := 2
-	totalSubtotal := 0.0
-	totalOngkir := 0.0
-	seenOngkirByTrx := map[string]struct{}{}
-	for _, pay := range payments {
-		tx, hasTx := trxMap[pay.TransaksiID]
-		if !hasTx || len(tx.Items) == 0 {
-			continue
+		// collect pelanggan/kasir/product IDs from transaksi
+		for _, t := range trxMap {
+			if t.PelangganID != "" {
+				pelangganIDSet[t.PelangganID] = struct{}{}
+			}
+			if t.KasirID != "" {
+				userIDSet[t.KasirID] = struct{}{}
+			}
+			for _, it := range t.Items {
+				if it.NamaProduk == "" && it.ProdukID != "" {
+					produkIDSet[it.ProdukID] = struct{}{}
+				}
+			}
 		}
-		ship := shipByTrx[pay.TransaksiID]
-		ongkir := ship.Ongkir
-
-		pelName := ""
-		if p, ok := pelangganMap[tx.PelangganID]; ok {
-			pelName = p.Nama
-		} else {
-			pelName = tx.PelangganID
+		if err := muatNama(ctx, pelangganColl, pelangganIDSet, "nama", pelangganMap); err != nil {
+			return err
 		}
-		kasirName := ""
-		if u, ok := userMap[tx.KasirID]; ok {
-			kasirName = u.Nama
-		} else {
-			kasirName = tx.KasirID
+		if err := muatNama(ctx, userColl, userIDSet, "nama", userMap); err != nil {
+			return err
 		}
-		driverName := ""
-		if ship.DriverID != "" {
-			if u, ok := userMap[ship.DriverID]; ok {
-				driverName = u.Nama
-			} else {
-				driverName = ship.DriverID
-			}
+		if err := muatNama(ctx, produkColl, produkIDSet, "nama_produk", produkNameMap); err != nil {
+			return err
 		}
 
-		for idx, it := range tx.Items {
-			namaProduk := it.NamaProduk
-			if namaProduk == "" {
-				if n, ok := produkNameMap[it.ProdukID]; ok && n != "" {
-					namaProduk = n
-				} else {
-					namaProduk = it.ProdukID
-				}
+		for _, pay := range payments {
+			r := barisPembayaran{
+				Pembayaran: pay,
+				Pengiriman: shipByTrx[pay.TransaksiID],
+				NamaProduk: produkNameMap,
 			}
-			subtotal := float64(it.Jumlah) * it.Harga
-			totalSubtotal += subtotal
-			if idx == 0 {
-				if _, already := seenOngkirByTrx[pay.TransaksiID]; !already {
-					totalOngkir += ongkir
-					seenOngkirByTrx[pay.TransaksiID] = struct{}{}
-				}
+			if tx, ok := trxMap[pay.TransaksiID]; ok {
+				r.Transaksi = &tx
+				r.Pelanggan = pelangganMap[tx.PelangganID]
+				r.Kasir = userMap[tx.KasirID]
 			}
-			ongkirCell := func() interface{} {
-				if idx == 0 {
-					return ongkir
-				}
-				return ""
-			}()
-
-			values := []interface{}{
-				pay.ID,
-				pay.CreatedAt.Format("02-01-2006 15:04"),
-				pelName,
-				kasirName,
-				driverName,
-				ship.Jenis,
-				namaProduk,
-				it.Jumlah,
-				it.Harga,
-				subtotal,
-				ongkirCell,
-				pay.Status,
+			if r.Pengiriman.DriverID != "" {
+				r.Driver = userMap[r.Pengiriman.DriverID]
 			}
-			for i, v := range values {
-				cell, _ := excelize.CoordinatesToCellName(i+1, rowD)
-				f.SetCellValue(sheetDetail, cell, v)
+			if err := lap.tambah(r); err != nil {
+				return err
 			}
-			rowD++
+		}
+		return nil
+	}
+
+	// ===============================
+	// LOAD DATA (pembayaran as source of truth, per batch)
+	// ===============================
+	findOpts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetBatchSize(exportBatch)
+	curPay, err := pembayaranColl.Find(ctx, filter, findOpts)
+	if err != nil {
+		return 0, err
+	}
+	defer curPay.Close(ctx)
+
+	batch := make([]models.Pembayaran, 0, exportBatch)
+	for curPay.Next(ctx) {
+		var p models.Pembayaran
+		if err := curPay.Decode(&p); err != nil {
+			return 0, fmt.Errorf("gagal decode pembayaran: %w", err)
+		}
+		batch = append(batch, p)
+		if len(batch) == exportBatch {
+			if err := tulisBatch(batch); err != nil {
+				return 0, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := curPay.Err(); err != nil {
+		return 0, err
+	}
+	if len(batch) > 0 {
+		if err := tulisBatch(batch); err != nil {
+			return 0, err
 		}
 	}
 
-	// Tambahkan ringkasan total di bawah tabel (sesuai permintaan)
-	// - Total Subtotal: jumlah semua subtotal item
-	// - Total Ongkir: dijumlah sekali per transaksi
-	// - Total Bayar: subtotal + ongkir
-	summaryRow := rowD + 1
-	f.SetCellValue(sheetDetail, fmt.Sprintf("I%d", summaryRow), "TOTAL SUBTOTAL")
-	f.SetCellValue(sheetDetail, fmt.Sprintf("J%d", summaryRow), totalSubtotal)
-	summaryRow++
-	f.SetCellValue(sheetDetail, fmt.Sprintf("I%d", summaryRow), "TOTAL ONGKIR")
-	f.SetCellValue(sheetDetail, fmt.Sprintf("J%d", summaryRow), totalOngkir)
-	summaryRow++
-	f.SetCellValue(sheetDetail, fmt.Sprintf("I%d", summaryRow), "TOTAL BAYAR (SUBTOTAL+ONGKIR)")
-	f.SetCellValue(sheetDetail, fmt.Sprintf("J%d", summaryRow), totalSubtotal+totalOngkir)
-
-	f.AutoFilter(sheetDetail, "A1:L1", []excelize.AutoFilterOptions{})
-	f.SetPanes(sheetDetail, &excelize.Panes{Freeze: true, Split: true, YSplit: 1})
-
-	f.AutoFilter(sheetRingkas, "A1:G1", []excelize.AutoFilterOptions{})
-	f.SetPanes(sheetRingkas, &excelize.Panes{Freeze: true, Split: true, YSplit: 1})
-
-	// Response
-	f.SetActiveSheet(0)
-	c.Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	c.Set("Content-Disposition", "attachment; filename=laporan_mbg.xlsx")
-	buf, _ := f.WriteToBuffer()
-	return c.Send(buf.Bytes())
+	params = append(params,
+		parameterExport{"Jumlah pembayaran", fmt.Sprintf("%d", lap.jumlah)},
+		parameterExport{"Dibuat oleh", dibuatOleh},
+		parameterExport{"Dibuat pada", time.Now().Format("02-01-2006 15:04:05")},
+	)
+	return lap.jumlah, lap.selesai(params)
 }
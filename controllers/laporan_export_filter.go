@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/repository"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// parseStatusPembayaran menormalkan daftar status (huruf kecil, tanpa duplikat) dan
+// menolak status yang tidak dikenal
+func parseStatusPembayaran(list []string) ([]string, error) {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, s := range list {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" || seen[s] {
+			continue
+		}
+		valid := false
+		for _, v := range models.StatusPembayaran {
+			if s == v {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("status tidak dikenal: %s (pilihan: %s)", s, strings.Join(models.StatusPembayaran, ", "))
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// variasiStatus menambahkan bentuk kapital (mis. Selesai) karena data lama menyimpan keduanya
+func variasiStatus(status []string) []string {
+	out := make([]string, 0, len(status)*2)
+	for _, s := range status {
+		out = append(out, s, strings.ToUpper(s[:1])+s[1:])
+	}
+	return out
+}
+
+// variasiMetode mengembalikan semua penulisan metode tersimpan yang setara dengan m
+// (mis. cash: "cash", "Tunai", ""). Metode yang tidak pernah tercatat ditolak.
+func variasiMetode(m string) ([]string, error) {
+	tersimpan, err := repository.GetMetodePembayaran()
+	if err != nil {
+		return nil, err
+	}
+	nilai := []string{}
+	pilihan := []string{"cash"}
+	seen := map[string]bool{"cash": true}
+	for _, v := range tersimpan {
+		n := normalisasiMetode(v)
+		if n == m {
+			nilai = append(nilai, v)
+		}
+		if !seen[n] {
+			seen[n] = true
+			pilihan = append(pilihan, n)
+		}
+	}
+	if !seen[m] {
+		sort.Strings(pilihan)
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("metode tidak dikenal: %s (pilihan: %s)", m, strings.Join(pilihan, ", ")))
+	}
+	if m == "cash" {
+		nilai = append(nilai, "cash")
+	}
+	return nilai, nil
+}
+
+// parameterExport adalah satu baris sheet "Parameter" di workbook export
+type parameterExport struct {
+	Nama  string
+	Nilai string
+}
+
+// labelPeriode menuliskan rentang created_at dari buildCreatedAtFilter untuk parameter export
+func labelPeriode(dateFilter bson.M) string {
+	r, ok := dateFilter["created_at"].(bson.M)
+	if !ok {
+		return "Semua tanggal"
+	}
+	start, _ := r["$gte"].(time.Time)
+	end, _ := r["$lt"].(time.Time)
+	return start.Format("02-01-2006") + " s/d " + end.AddDate(0, 0, -1).Format("02-01-2006")
+}
+
+// filterExportExcel membangun filter pembayaran untuk export Excel dari query:
+// start/end | month+year | year, status (koma), kasir_id, driver_id, pelanggan_id, metode.
+// Status default & ID pembayaran yang dikecualikan diambil dari pengaturan laporan.
+func filterExportExcel(query ambilQuery, pengaturan *models.PengaturanLaporan) (bson.M, []parameterExport, error) {
+	dateFilter, err := buildCreatedAtFilter(query)
+	if err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	filter := bson.M{}
+	for k, v := range dateFilter {
+		filter[k] = v
+	}
+
+	params := []parameterExport{{"Periode", labelPeriode(dateFilter)}}
+
+	// Status: dari query, atau default pengaturan laporan
+	status := pengaturan.ExportStatus
+	if raw := strings.TrimSpace(query("status")); raw != "" {
+		status, err = parseStatusPembayaran(strings.Split(raw, ","))
+		if err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+	if len(status) > 0 {
+		filter["status"] = bson.M{"$in": variasiStatus(status)}
+	}
+	params = append(params, parameterExport{"Status", strings.Join(status, ", ")})
+
+	if kasirID := strings.TrimSpace(query("kasir_id")); kasirID != "" {
+		u, err := repository.FindUserByID(kasirID)
+		if err != nil || u == nil {
+			return nil, nil, fiber.NewError(fiber.StatusUnprocessableEntity, "kasir_id tidak ditemukan")
+		}
+		if u.Role != "kasir" {
+			return nil, nil, fiber.NewError(fiber.StatusUnprocessableEntity, "kasir_id bukan kasir")
+		}
+		filter["kasir_id"] = kasirID
+		params = append(params, parameterExport{"Kasir", u.Nama + " (" + kasirID + ")"})
+	}
+
+	if metode := strings.TrimSpace(query("metode")); metode != "" {
+		m := normalisasiMetode(metode)
+		nilai, err := variasiMetode(m)
+		if err != nil {
+			return nil, nil, err
+		}
+		if m == "cash" {
+			// Pembayaran lama tanpa field metode dihitung sebagai cash
+			filter["$or"] = bson.A{
+				bson.M{"metode": bson.M{"$in": nilai}},
+				bson.M{"metode": bson.M{"$exists": false}},
+			}
+		} else {
+			filter["metode"] = bson.M{"$in": nilai}
+		}
+		params = append(params, parameterExport{"Metode", m})
+	}
+
+	// Driver & pelanggan ada di pengiriman/transaksi: dipetakan ke daftar transaksi_id
+	var trxIDs []string
+	batasiTransaksi := func(ids []string) {
+		if trxIDs == nil {
+			trxIDs = ids
+			return
+		}
+		ada := map[string]bool{}
+		for _, id := range ids {
+			ada[id] = true
+		}
+		irisan := []string{}
+		for _, id := range trxIDs {
+			if ada[id] {
+				irisan = append(irisan, id)
+			}
+		}
+		trxIDs = irisan
+	}
+	if driverID := strings.TrimSpace(query("driver_id")); driverID != "" {
+		u, err := repository.FindUserByID(driverID)
+		if err != nil || u == nil {
+			return nil, nil, fiber.NewError(fiber.StatusUnprocessableEntity, "driver_id tidak ditemukan")
+		}
+		if u.Role != "driver" {
+			return nil, nil, fiber.NewError(fiber.StatusUnprocessableEntity, "driver_id bukan driver")
+		}
+		list, err := repository.GetPengirimanFiltered(bson.M{"driver_id": driverID})
+		if err != nil {
+			return nil, nil, err
+		}
+		ids := make([]string, 0, len(list))
+		for _, p := range list {
+			ids = append(ids, p.TransaksiID)
+		}
+		batasiTransaksi(ids)
+		params = append(params, parameterExport{"Driver", u.Nama + " (" + driverID + ")"})
+	}
+	if pelangganID := strings.TrimSpace(query("pelanggan_id")); pelangganID != "" {
+		p, err := repository.GetPelangganByID(pelangganID)
+		if err != nil || p == nil {
+			return nil, nil, fiber.NewError(fiber.StatusUnprocessableEntity, "pelanggan_id tidak ditemukan")
+		}
+		list, err := repository.ListTransaksi(bson.M{"pelanggan_id": pelangganID}, 0, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		ids := make([]string, 0, len(list))
+		for _, t := range list {
+			ids = append(ids, t.ID)
+		}
+		batasiTransaksi(ids)
+		params = append(params, parameterExport{"Pelanggan", p.Nama + " (" + pelangganID + ")"})
+	}
+	if trxIDs != nil {
+		filter["transaksi_id"] = bson.M{"$in": trxIDs}
+	}
+
+	// Pengecualian dari pengaturan laporan (dapat diubah admin)
+	if len(pengaturan.ExportKecualiPembayaran) > 0 {
+		filter["_id"] = bson.M{"$nin": pengaturan.ExportKecualiPembayaran}
+	}
+	dikecualikan := strings.Join(pengaturan.ExportKecualiPembayaran, ", ")
+	if dikecualikan == "" {
+		dikecualikan = "-"
+	}
+	params = append(params, parameterExport{"Pembayaran dikecualikan", dikecualikan})
+	return filter, params, nil
+}
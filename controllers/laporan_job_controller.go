@@ -0,0 +1,344 @@
+package controllers
+
+import (
+	"backend/config"
+	"backend/models"
+	"backend/repository"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// jenisJob mendefinisikan export yang bisa dikerjakan sebagai job laporan
+type jenisJob struct {
+	NamaFile string
+	// Ekstensi file hasil sesuai filter job (mis. format xlsx/csv/pdf)
+	Ekstensi func(filter map[string]string) string
+	// Validasi memeriksa filter saat job dibuat agar kesalahan filter langsung ditolak
+	Validasi func(query ambilQuery) error
+	// Tulis membangun file export ke w dan mengembalikan jumlah baris data
+	Tulis func(ctx context.Context, j *models.LaporanJob, w io.Writer) (int, error)
+}
+
+var jobTersedia = map[string]jenisJob{
+	"excel": {
+		NamaFile: "laporan_mbg",
+		Ekstensi: func(filter map[string]string) string {
+			format, _ := parseFormatExport(filter["format"])
+			return format.Ekstensi
+		},
+		Validasi: func(query ambilQuery) error {
+			format, err := parseFormatExport(query("format"))
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, err.Error())
+			}
+			pengaturan, err := repository.GetPengaturanLaporan()
+			if err != nil {
+				return err
+			}
+			filter, _, err := filterExportExcel(query, pengaturan)
+			if err != nil {
+				return err
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			n, err := config.DB.Collection("pembayaran").CountDocuments(ctx, filter)
+			if err != nil {
+				return err
+			}
+			return cekUkuranFormat(format, n)
+		},
+		Tulis: func(ctx context.Context, j *models.LaporanJob, w io.Writer) (int, error) {
+			format, err := parseFormatExport(j.Filter["format"])
+			if err != nil {
+				return 0, err
+			}
+			pengaturan, err := repository.GetPengaturanLaporan()
+			if err != nil {
+				return 0, err
+			}
+			filter, params, err := filterExportExcel(queryDari(j.Filter), pengaturan)
+			if err != nil {
+				return 0, err
+			}
+			dibuatOleh := j.DibuatNama
+			if dibuatOleh == "" {
+				dibuatOleh = j.DibuatOleh
+			}
+			params = append(params, parameterExport{"ID job", j.ID})
+			return tulisLaporanPembayaran(ctx, config.DB, filter, params, dibuatOleh, format.Baru(), w)
+		},
+	},
+}
+
+// Folder penyimpanan file hasil job (REPORT_DIR, default storage/laporan)
+func laporanJobDir() string {
+	if d := os.Getenv("REPORT_DIR"); d != "" {
+		return d
+	}
+	return filepath.Join("storage", "laporan")
+}
+
+// Masa simpan job beserta filenya (REPORT_JOB_HARI, default 7 hari)
+func laporanJobMasaSimpan() time.Duration {
+	if n, err := strconv.Atoi(os.Getenv("REPORT_JOB_HARI")); err == nil && n > 0 {
+		return time.Duration(n) * 24 * time.Hour
+	}
+	return 7 * 24 * time.Hour
+}
+
+// Batas waktu pengerjaan satu job
+const laporanJobTimeout = 15 * time.Minute
+
+var antrianLaporanJob = make(chan string, 100)
+
+// antrekanLaporanJob memasukkan job ke antrean worker. Jika antrean penuh job tetap
+// berstatus antri dan akan diambil pada pemindaian berikutnya.
+func antrekanLaporanJob(id string) {
+	select {
+	case antrianLaporanJob <- id:
+	default:
+	}
+}
+
+// StartLaporanWorker menjalankan worker job laporan (REPORT_WORKERS, default 1) dan
+// pemindaian berkala: job antri yang belum masuk antrean serta job kadaluarsa.
+func StartLaporanWorker() {
+	if err := os.MkdirAll(laporanJobDir(), 0o755); err != nil {
+		log.Printf("⚠️ Gagal membuat folder laporan %s: %v", laporanJobDir(), err)
+	}
+	if err := repository.KembalikanLaporanJobProses(); err != nil {
+		log.Printf("⚠️ Gagal mengembalikan job laporan ke antrean: %v", err)
+	}
+
+	workers := 1
+	if n, err := strconv.Atoi(os.Getenv("REPORT_WORKERS")); err == nil && n > 0 {
+		workers = n
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for id := range antrianLaporanJob {
+				prosesLaporanJob(id)
+			}
+		}()
+	}
+
+	go func() {
+		pindaiLaporanJob()
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			pindaiLaporanJob()
+		}
+	}()
+}
+
+func pindaiLaporanJob() {
+	// Job proses yang melewati batas waktu (plus jeda) dianggap gagal agar tidak menggantung
+	// selamanya; file tmp-nya tidak pernah di-rename sehingga tidak ada hasil yang tertinggal
+	batas := time.Now().Add(-(laporanJobTimeout + time.Minute))
+	if n, err := repository.GagalkanLaporanJobMacet(batas, "Job melebihi batas waktu pengerjaan"); err != nil {
+		log.Printf("⚠️ Gagal menandai job laporan macet: %v", err)
+	} else if n > 0 {
+		log.Printf("⚠️ %d job laporan melebihi batas waktu, ditandai gagal", n)
+	}
+
+	antri, err := repository.ListLaporanJob(bson.M{"status": models.JobAntri}, 0)
+	if err != nil {
+		log.Printf("⚠️ Gagal membaca antrean job laporan: %v", err)
+		return
+	}
+	for _, j := range antri {
+		antrekanLaporanJob(j.ID)
+	}
+
+	kadaluarsa, err := repository.ListLaporanJob(bson.M{
+		"expires_at": bson.M{"$lte": time.Now()},
+		"status":     bson.M{"$ne": models.JobProses},
+	}, 0)
+	if err != nil {
+		log.Printf("⚠️ Gagal membaca job laporan kadaluarsa: %v", err)
+		return
+	}
+	for _, j := range kadaluarsa {
+		if j.Path != "" {
+			if err := os.Remove(j.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("⚠️ Gagal menghapus file job %s: %v", j.ID, err)
+				continue
+			}
+		}
+		if err := repository.DeleteLaporanJob(j.ID); err != nil {
+			log.Printf("⚠️ Gagal menghapus job %s: %v", j.ID, err)
+		}
+	}
+}
+
+// prosesLaporanJob mengerjakan satu job: file ditulis ke .tmp lalu di-rename setelah lengkap
+func prosesLaporanJob(id string) {
+	j, err := repository.AmbilLaporanJob(id)
+	if err != nil {
+		return // sudah diambil worker lain / dihapus
+	}
+	gagal := func(err error) {
+		log.Printf("⚠️ Job laporan %s gagal: %v", j.ID, err)
+		if err := repository.SelesaikanLaporanJob(j.ID, bson.M{"status": models.JobGagal, "pesan": err.Error()}); err != nil {
+			log.Printf("⚠️ Gagal menyimpan status job %s: %v", j.ID, err)
+		}
+	}
+	jenis, ok := jobTersedia[j.Export]
+	if !ok {
+		gagal(errors.New("export tidak dikenal: " + j.Export))
+		return
+	}
+
+	ekstensi := jenis.Ekstensi(j.Filter)
+	path := filepath.Join(laporanJobDir(), j.ID+ekstensi)
+	tmp := path + ".tmp"
+	// Panic saat menulis tidak boleh mematikan worker atau meninggalkan job di status proses
+	defer func() {
+		if r := recover(); r != nil {
+			os.Remove(tmp)
+			gagal(fmt.Errorf("panic: %v", r))
+		}
+	}()
+	file, err := os.Create(tmp)
+	if err != nil {
+		gagal(err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), laporanJobTimeout)
+	defer cancel()
+	jumlah, err := jenis.Tulis(ctx, j, file)
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		gagal(err)
+		return
+	}
+
+	var ukuran int64
+	if info, err := os.Stat(path); err == nil {
+		ukuran = info.Size()
+	}
+	err = repository.SelesaikanLaporanJob(j.ID, bson.M{
+		"status":       models.JobSelesai,
+		"path":         path,
+		"nama_file":    jenis.NamaFile + "_" + j.ID + ekstensi,
+		"ukuran":       ukuran,
+		"jumlah_baris": jumlah,
+	})
+	if err != nil {
+		log.Printf("⚠️ Gagal menyimpan status job %s: %v", j.ID, err)
+	}
+}
+
+// POST /laporan/jobs: buat job export di latar belakang untuk data besar
+func (lc *LaporanController) BuatLaporanJob(c *fiber.Ctx) error {
+	var body struct {
+		Export string            `json:"export"`
+		Filter map[string]string `json:"filter"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Request tidak valid"})
+	}
+	jenis, ok := jobTersedia[body.Export]
+	tujuan, adaTujuan := exportTujuan[body.Export]
+	if !ok || !adaTujuan {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "export tidak dikenal"})
+	}
+	filter := map[string]string{}
+	for _, k := range tujuan.Filter {
+		if v := body.Filter[k]; v != "" {
+			filter[k] = v
+		}
+	}
+	for k, v := range body.Filter {
+		if filter[k] == "" && v != "" {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "filter tidak didukung: " + k})
+		}
+	}
+	if err := jenis.Validasi(queryDari(filter)); err != nil {
+		return tolakLaporan(c, err)
+	}
+
+	id, err := repository.GenerateID("laporan_job")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal generate ID"})
+	}
+	userID, _ := c.Locals("userID").(string)
+	userNama, _ := c.Locals("userNama").(string)
+	now := time.Now()
+	j := models.LaporanJob{
+		ID:         id,
+		Export:     body.Export,
+		Filter:     filter,
+		Status:     models.JobAntri,
+		DibuatOleh: userID,
+		DibuatNama: userNama,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(laporanJobMasaSimpan()),
+	}
+	if err := repository.CreateLaporanJob(&j); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat job laporan"})
+	}
+	antrekanLaporanJob(j.ID)
+	return c.Status(fiber.StatusAccepted).JSON(j)
+}
+
+// GET /laporan/jobs: daftar job milik user (terbaru dulu)
+func (lc *LaporanController) GetLaporanJobs(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	list, err := repository.ListLaporanJob(bson.M{"dibuat_oleh": userID}, 50)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(list)
+}
+
+// laporanJobMilikUser mengambil job; job milik user lain diperlakukan tidak ada
+func laporanJobMilikUser(c *fiber.Ctx) (*models.LaporanJob, error) {
+	userID, _ := c.Locals("userID").(string)
+	j, err := repository.GetLaporanJobByID(c.Params("id"))
+	if err != nil || j.DibuatOleh != userID {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Job laporan tidak ditemukan")
+	}
+	return j, nil
+}
+
+// GET /laporan/jobs/:id: status job
+func (lc *LaporanController) GetLaporanJob(c *fiber.Ctx) error {
+	j, err := laporanJobMilikUser(c)
+	if err != nil {
+		return tolakLaporan(c, err)
+	}
+	return c.JSON(j)
+}
+
+// GET /laporan/jobs/:id/download: unduh file job yang sudah selesai
+func (lc *LaporanController) DownloadLaporanJob(c *fiber.Ctx) error {
+	j, err := laporanJobMilikUser(c)
+	if err != nil {
+		return tolakLaporan(c, err)
+	}
+	if j.Status != models.JobSelesai {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Job laporan belum selesai", "status": j.Status})
+	}
+	if _, err := os.Stat(j.Path); err != nil {
+		return c.Status(fiber.StatusGone).JSON(fiber.Map{"error": "File laporan sudah tidak tersedia"})
+	}
+	return c.Download(j.Path, j.NamaFile)
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"backend/repository"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// periodeKartuStok mengembalikan rentang [start, end) dari query start/end, month+year
+// atau year. Tanpa filter tanggal dipakai bulan berjalan.
+func periodeKartuStok(c *fiber.Ctx) (time.Time, time.Time, error) {
+	dateFilter, err := buildCreatedAtFilterFromQuery(c)
+	if err != nil {
+		return time.Time{}, time.Time{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if r, ok := dateFilter["created_at"].(bson.M); ok {
+		start, _ := r["$gte"].(time.Time)
+		end, _ := r["$lt"].(time.Time)
+		return start, end, nil
+	}
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// ambilKartuStok menyusun kartu stok untuk periode dari query, opsional satu produk (produk_id)
+func ambilKartuStok(c *fiber.Ctx) ([]repository.KartuStok, time.Time, time.Time, error) {
+	start, end, err := periodeKartuStok(c)
+	if err != nil {
+		return nil, start, end, err
+	}
+	produkID := strings.TrimSpace(c.Query("produk_id"))
+	if produkID != "" {
+		if _, err := repository.GetProdukByID(produkID); err != nil {
+			return nil, start, end, fiber.NewError(fiber.StatusNotFound, "Produk tidak ditemukan")
+		}
+	}
+	kartu, err := repository.GetKartuStok(produkID, start, end)
+	return kartu, start, end, err
+}
+
+// KartuStok returns the stock card per product: opening balance, movements with running balance, closing balance
+func (lc *LaporanController) KartuStok(c *fiber.Ctx) error {
+	kartu, start, end, err := ambilKartuStok(c)
+	if err != nil {
+		return tolakLaporan(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"start":  start.Format("2006-01-02"),
+		"end":    end.AddDate(0, 0, -1).Format("2006-01-02"),
+		"produk": kartu,
+	})
+}
+
+// ExportKartuStokExcel writes the stock card as xlsx (default), csv or pdf (?format=): movement sheet + summary sheet
+func (lc *LaporanController) ExportKartuStokExcel(c *fiber.Ctx) error {
+	format, err := parseFormatExport(c.Query("format"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	kartu, start, end, err := ambilKartuStok(c)
+	if err != nil {
+		return tolakLaporan(c, err)
+	}
+
+	// Lembar mutasi di depan agar csv (hanya lembar pertama) berisi kartu stok lengkap
+	lembar := []lembarLaporan{
+		{Nama: "Kartu Stok", Headers: []string{"ID Produk", "Nama Produk", "Tanggal", "Jenis", "Referensi", "Keterangan", "Masuk", "Keluar", "Saldo"}},
+		{Nama: "Ringkasan", Headers: []string{"ID Produk", "Nama Produk", "Saldo Awal", "Masuk", "Keluar", "Saldo Akhir"}},
+	}
+	tanggalAwal := start.Format("02-01-2006")
+	tanggalAkhir := end.AddDate(0, 0, -1).Format("02-01-2006")
+	isi := func(b builderLaporan) error {
+		// Per produk: baris SALDO AWAL, mutasi dengan saldo berjalan, lalu SALDO AKHIR
+		for _, k := range kartu {
+			if err := b.Baris(0, []interface{}{k.ProdukID, k.NamaProduk, tanggalAwal, "SALDO AWAL", "", "", "", "", k.SaldoAwal}); err != nil {
+				return err
+			}
+			for _, m := range k.Mutasi {
+				ref := m.RefType
+				if m.RefID != "" {
+					ref = strings.TrimSpace(ref + " " + m.RefID)
+				}
+				if err := b.Baris(0, []interface{}{k.ProdukID, k.NamaProduk, m.Tanggal.Format("02-01-2006 15:04"), m.Jenis, ref, m.Keterangan, m.Masuk, m.Keluar, m.Saldo}); err != nil {
+					return err
+				}
+			}
+			if err := b.Baris(0, []interface{}{k.ProdukID, k.NamaProduk, tanggalAkhir, "SALDO AKHIR", "", "", k.Masuk, k.Keluar, k.SaldoAkhir}); err != nil {
+				return err
+			}
+			if err := b.Baris(1, []interface{}{k.ProdukID, k.NamaProduk, k.SaldoAwal, k.Masuk, k.Keluar, k.SaldoAkhir}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	produk := strings.TrimSpace(c.Query("produk_id"))
+	if produk == "" {
+		produk = "Semua produk"
+	}
+	params := []parameterExport{
+		{"Periode", tanggalAwal + " s/d " + tanggalAkhir},
+		{"Produk", produk},
+		{"Jumlah produk", strconv.Itoa(len(kartu))},
+		{"Dibuat", time.Now().Format("02-01-2006 15:04")},
+	}
+	return kirimLaporan(c, format, "kartu_stok", lembar, isi, nil, params)
+}
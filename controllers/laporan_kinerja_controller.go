@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"backend/repository"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ambilKinerja menghitung kinerja kasir (dari transaksi) dan driver (dari pengiriman)
+// untuk periode dari query start/end, month+year atau year.
+func ambilKinerja(c *fiber.Ctx) ([]repository.KinerjaKasir, []repository.KinerjaDriver, error) {
+	dateFilter, err := buildCreatedAtFilterFromQuery(c)
+	if err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	kasir, err := repository.GetKinerjaKasir(dateFilter)
+	if err != nil {
+		return nil, nil, err
+	}
+	driver, err := repository.GetKinerjaDriver(dateFilter)
+	if err != nil {
+		return nil, nil, err
+	}
+	return kasir, driver, nil
+}
+
+// tolakLaporan memakai status dari fiber.Error (validasi filter); error lain menjadi 500
+func tolakLaporan(c *fiber.Ctx, err error) error {
+	if fe, ok := err.(*fiber.Error); ok {
+		return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+}
+
+// Kinerja returns performance per kasir and per driver within the requested period
+func (lc *LaporanController) Kinerja(c *fiber.Ctx) error {
+	kasir, driver, err := ambilKinerja(c)
+	if err != nil {
+		return tolakLaporan(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"kasir":  kasir,
+		"driver": driver,
+	})
+}
+
+// ExportKinerjaExcel writes the performance report as xlsx (default), csv or pdf (?format=): sheet kasir & driver
+func (lc *LaporanController) ExportKinerjaExcel(c *fiber.Ctx) error {
+	format, err := parseFormatExport(c.Query("format"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	kasir, driver, err := ambilKinerja(c)
+	if err != nil {
+		return tolakLaporan(c, err)
+	}
+	dateFilter, _ := buildCreatedAtFilterFromQuery(c)
+
+	lembar := []lembarLaporan{
+		{Nama: "Kinerja Kasir", Headers: []string{"ID Kasir", "Nama Kasir", "Jumlah Transaksi", "Pendapatan", "Rata-rata Keranjang", "Dibatalkan"}},
+		{Nama: "Kinerja Driver", Headers: []string{"ID Driver", "Nama Driver", "Total Pengiriman", "Selesai", "Dibatalkan", "Rata-rata Waktu Antar (menit)", "Ongkir Terkumpul"}},
+	}
+	isi := func(b builderLaporan) error {
+		for _, k := range kasir {
+			if err := b.Baris(0, []interface{}{k.KasirID, k.KasirNama, k.JumlahTransaksi, k.Pendapatan, k.RataRataKeranjang, k.Dibatalkan}); err != nil {
+				return err
+			}
+		}
+		for _, d := range driver {
+			if err := b.Baris(1, []interface{}{d.DriverID, d.DriverNama, d.TotalPengiriman, d.Selesai, d.Dibatalkan, d.RataRataMenit, d.OngkirTerkumpul}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	params := []parameterExport{
+		{"Periode", labelPeriode(dateFilter)},
+		{"Jumlah kasir", strconv.Itoa(len(kasir))},
+		{"Jumlah driver", strconv.Itoa(len(driver))},
+		{"Dibuat", time.Now().Format("02-01-2006 15:04")},
+	}
+	return kirimLaporan(c, format, "laporan_kinerja", lembar, isi, nil, params)
+}
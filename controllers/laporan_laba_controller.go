@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"backend/repository"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ambilLaba menghitung laba kotor untuk periode dari query start/end, month+year atau year
+// dengan metode HPP dari pengaturan laporan.
+func ambilLaba(c *fiber.Ctx) (*repository.LaporanLaba, error) {
+	dateFilter, err := buildCreatedAtFilterFromQuery(c)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	pengaturan, err := repository.GetPengaturanLaporan()
+	if err != nil {
+		return nil, err
+	}
+	return repository.GetLaporanLaba(dateFilter, pengaturan.MetodeHPP)
+}
+
+// Laba returns gross profit and margin per product, category, kasir and day within the requested period
+func (lc *LaporanController) Laba(c *fiber.Ctx) error {
+	lap, err := ambilLaba(c)
+	if err != nil {
+		return tolakLaporan(c, err)
+	}
+	return c.JSON(lap)
+}
+
+// ExportLabaExcel writes the profit report as xlsx (default), csv or pdf (?format=): one sheet per grouping
+func (lc *LaporanController) ExportLabaExcel(c *fiber.Ctx) error {
+	format, err := parseFormatExport(c.Query("format"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	lap, err := ambilLaba(c)
+	if err != nil {
+		return tolakLaporan(c, err)
+	}
+	dateFilter, _ := buildCreatedAtFilterFromQuery(c)
+
+	kolom := func(kunci, nama string) []string {
+		return []string{kunci, nama, "Jumlah Terjual", "Penjualan", "HPP", "Laba Kotor", "Margin (%)"}
+	}
+	// Lembar per produk di depan agar csv (hanya lembar pertama) tetap berguna
+	lembar := []lembarLaporan{
+		{Nama: "Laba per Produk", Headers: kolom("ID Produk", "Nama Produk")},
+		{Nama: "Laba per Kategori", Headers: kolom("ID Kategori", "Nama Kategori")},
+		{Nama: "Laba per Kasir", Headers: kolom("ID Kasir", "Nama Kasir")},
+		{Nama: "Laba per Hari", Headers: kolom("Tanggal", "Keterangan")},
+	}
+	isi := func(b builderLaporan) error {
+		for i, rows := range [][]repository.BarisLaba{lap.Produk, lap.Kategori, lap.Kasir, lap.Hari} {
+			// Baris total di akhir setiap lembar
+			for _, r := range append(rows, lap.Total) {
+				if err := b.Baris(i, []interface{}{r.Kunci, r.Nama, r.Jumlah, r.Penjualan, r.HPP, r.LabaKotor, r.Margin}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	params := []parameterExport{
+		{"Periode", labelPeriode(dateFilter)},
+		{"Metode HPP", lap.MetodeHPP},
+		{"Item tanpa HPP", strconv.Itoa(lap.TanpaHPP)},
+		{"Dibuat", time.Now().Format("02-01-2006 15:04")},
+	}
+	return kirimLaporan(c, format, "laporan_laba", lembar, isi, nil, params)
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"backend/policy"
+	"backend/repository"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// rentangPenjualan menentukan periode [start, end) dari query (start/end, month+year, year)
+// beserta periode pembanding sebelumnya dengan panjang yang sama. Tanpa filter tanggal:
+// 30 hari (day), 12 minggu (week), atau 12 bulan (month) terakhir termasuk hari ini.
+func rentangPenjualan(c *fiber.Ctx, interval string) (start, end, prevStart, prevEnd time.Time, err error) {
+	dateFilter, err := buildCreatedAtFilterFromQuery(c)
+	if err != nil {
+		return
+	}
+	if r, ok := dateFilter["created_at"].(bson.M); ok {
+		start, _ = r["$gte"].(time.Time)
+		end, _ = r["$lt"].(time.Time)
+	} else {
+		end = awalHari(time.Now()).AddDate(0, 0, 1)
+		switch interval {
+		case repository.IntervalMinggu:
+			// Senin minggu ini dikurangi 11 minggu agar setiap minggu utuh
+			hariIni := awalHari(time.Now())
+			start = hariIni.AddDate(0, 0, -((int(hariIni.Weekday())+6)%7)-7*11)
+		case repository.IntervalBulan:
+			start = time.Date(end.Year(), end.Month()-11, 1, 0, 0, 0, 0, time.Local)
+		default:
+			start = end.AddDate(0, 0, -30)
+		}
+	}
+
+	switch {
+	case c.Query("start") == "" && c.Query("month") != "":
+		prevStart, prevEnd = start.AddDate(0, -1, 0), start
+	case c.Query("start") == "" && c.Query("year") != "":
+		prevStart, prevEnd = start.AddDate(-1, 0, 0), start
+	default:
+		prevStart, prevEnd = start.Add(-end.Sub(start)), start
+	}
+	return
+}
+
+// persenPerubahan mengembalikan perubahan (%) terhadap periode sebelumnya; nil jika sebelumnya 0
+func persenPerubahan(sekarang, sebelumnya float64) *float64 {
+	if sebelumnya == 0 {
+		return nil
+	}
+	p := (sekarang - sebelumnya) / sebelumnya * 100
+	return &p
+}
+
+// Penjualan returns a time-bucketed sales series (day/week/month) with totals compared to the previous period
+func (lc *LaporanController) Penjualan(c *fiber.Ctx) error {
+	interval := c.Query("interval", repository.IntervalHari)
+	if interval != repository.IntervalHari && interval != repository.IntervalMinggu && interval != repository.IntervalBulan {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "interval harus salah satu dari: day, week, month"})
+	}
+	start, end, prevStart, prevEnd, err := rentangPenjualan(c, interval)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// Dashboard kasir berbasis data miliknya sendiri (scope kepemilikan transaksi)
+	scope, err := scopeAkses(c, policy.Transaksi)
+	if err == policy.ErrDitolak {
+		err = fiber.NewError(fiber.StatusForbidden, "Role ini tidak boleh melihat data penjualan")
+	}
+	if err != nil {
+		return tolakLaporan(c, err)
+	}
+
+	seri, err := repository.GetSeriPenjualan(start, end, interval, scope)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	seriLalu, err := repository.GetSeriPenjualan(prevStart, prevEnd, interval, scope)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var total, totalLalu repository.TitikPenjualan
+	for _, t := range seri {
+		total.Tambah(t)
+	}
+	for _, t := range seriLalu {
+		totalLalu.Tambah(t)
+	}
+
+	return c.JSON(fiber.Map{
+		"interval": interval,
+		"periode": fiber.Map{
+			"start": start,
+			"end":   end,
+		},
+		"periode_sebelumnya": fiber.Map{
+			"start": prevStart,
+			"end":   prevEnd,
+		},
+		"data": seri,
+		"total": fiber.Map{
+			"pendapatan":       total.Pendapatan,
+			"jumlah_transaksi": total.JumlahTransaksi,
+			"item_terjual":     total.ItemTerjual,
+			"ongkir":           total.Ongkir,
+		},
+		"total_sebelumnya": fiber.Map{
+			"pendapatan":       totalLalu.Pendapatan,
+			"jumlah_transaksi": totalLalu.JumlahTransaksi,
+			"item_terjual":     totalLalu.ItemTerjual,
+			"ongkir":           totalLalu.Ongkir,
+		},
+		"perubahan_persen": fiber.Map{
+			"pendapatan":       persenPerubahan(total.Pendapatan, totalLalu.Pendapatan),
+			"jumlah_transaksi": persenPerubahan(float64(total.JumlahTransaksi), float64(totalLalu.JumlahTransaksi)),
+			"item_terjual":     persenPerubahan(float64(total.ItemTerjual), float64(totalLalu.ItemTerjual)),
+			"ongkir":           persenPerubahan(total.Ongkir, totalLalu.Ongkir),
+		},
+	})
+}
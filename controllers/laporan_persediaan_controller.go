@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/repository"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ambilPersediaan menghitung nilai persediaan per akhir tanggal (query tanggal, default hari ini)
+// dengan metode HPP dari query metode atau pengaturan laporan.
+func ambilPersediaan(c *fiber.Ctx) (*repository.LaporanPersediaan, error) {
+	tanggal := awalHari(time.Now())
+	if s := strings.TrimSpace(c.Query("tanggal")); s != "" {
+		t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "format tanggal harus YYYY-MM-DD")
+		}
+		tanggal = t
+	}
+	metode := strings.TrimSpace(c.Query("metode"))
+	if metode == "" {
+		pengaturan, err := repository.GetPengaturanLaporan()
+		if err != nil {
+			return nil, err
+		}
+		metode = pengaturan.MetodeHPP
+	} else if metode != models.MetodeHPPTerakhir && metode != models.MetodeHPPRataRata {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "metode harus salah satu dari: terakhir, rata_rata")
+	}
+	return repository.GetNilaiPersediaan(tanggal, tanggal.AddDate(0, 0, 1), metode)
+}
+
+// Persediaan returns inventory on hand per product at the end of a date, valued at cost and grouped by category
+func (lc *LaporanController) Persediaan(c *fiber.Ctx) error {
+	lap, err := ambilPersediaan(c)
+	if err != nil {
+		return tolakLaporan(c, err)
+	}
+	return c.JSON(lap)
+}
+
+// ExportPersediaanExcel writes the inventory valuation as xlsx (default), csv or pdf (?format=): detail per product + category summary
+func (lc *LaporanController) ExportPersediaanExcel(c *fiber.Ctx) error {
+	format, err := parseFormatExport(c.Query("format"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	lap, err := ambilPersediaan(c)
+	if err != nil {
+		return tolakLaporan(c, err)
+	}
+
+	// Total nilai dicetak builder di bawah kolom Nilai kedua lembar
+	lembar := []lembarLaporan{
+		{Nama: "Persediaan", Headers: []string{"Kategori", "ID Produk", "Nama Produk", "Jumlah", "Harga Pokok", "Nilai", "Sumber Harga"}, KolomTotal: 6},
+		{Nama: "Per Kategori", Headers: []string{"ID Kategori", "Nama Kategori", "Jumlah Produk", "Jumlah Unit", "Nilai"}, KolomTotal: 5},
+	}
+	isi := func(b builderLaporan) error {
+		// Detail: produk per kategori diikuti subtotal kategori
+		for _, k := range lap.Kategori {
+			for _, p := range k.Produk {
+				if err := b.Baris(0, []interface{}{k.NamaKategori, p.ProdukID, p.NamaProduk, p.Jumlah, p.HargaPokok, p.Nilai, p.SumberHarga}); err != nil {
+					return err
+				}
+			}
+			if err := b.Baris(0, []interface{}{k.NamaKategori, "", "SUBTOTAL", k.Jumlah, "", k.Nilai, ""}); err != nil {
+				return err
+			}
+			if err := b.Baris(1, []interface{}{k.KategoriID, k.NamaKategori, len(k.Produk), k.Jumlah, k.Nilai}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	total := []totalLaporan{{Label: "TOTAL NILAI", Nilai: lap.Nilai}}
+
+	// Parameter laporan untuk arsip tutup buku
+	params := []parameterExport{
+		{"Per tanggal", lap.Tanggal},
+		{"Metode HPP", lap.MetodeHPP},
+		{"Jumlah unit", strconv.Itoa(lap.Jumlah)},
+		{"Produk dengan harga dari data produk", strconv.Itoa(lap.HargaDariProduk)},
+		{"Produk bersaldo negatif (tidak dinilai)", strconv.Itoa(lap.SaldoNegatif)},
+		{"Dibuat", time.Now().Format("02-01-2006 15:04")},
+	}
+	return kirimLaporan(c, format, "laporan_persediaan_"+lap.Tanggal, lembar, isi, total, params)
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Jumlah login gagal yang dibiarkan tanpa jeda sebelum backoff eksponensial berlaku
+const (
+	loginBebasPerEmail  = 3
+	loginBebasPerIP     = 10
+	loginThrottleWindow = time.Hour
+)
+
+// Batas login gagal berturut-turut sebelum akun dikunci (env LOGIN_MAX_GAGAL), default 5
+func loginMaxGagal() int {
+	if n, err := strconv.Atoi(os.Getenv("LOGIN_MAX_GAGAL")); err == nil && n > 0 {
+		return n
+	}
+	return 5
+}
+
+// Lama akun dikunci (env LOGIN_LOCK_DURASI, contoh "15m"), default 15 menit
+func loginLockDurasi() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("LOGIN_LOCK_DURASI")); err == nil && d > 0 {
+		return d
+	}
+	return 15 * time.Minute
+}
+
+// Jeda backoff maksimum (env LOGIN_BACKOFF_MAKS), default 15 menit
+func loginBackoffMaks() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("LOGIN_BACKOFF_MAKS")); err == nil && d > 0 {
+		return d
+	}
+	return 15 * time.Minute
+}
+
+// loginBackoff menghitung jeda setelah gagal ke-n: 1s, 2s, 4s, ... setelah melewati jatah bebas
+func loginBackoff(gagal, bebas int) time.Duration {
+	if gagal < bebas {
+		return 0
+	}
+	maks := loginBackoffMaks()
+	exp := gagal - bebas
+	if exp > 30 {
+		return maks
+	}
+	d := time.Duration(math.Pow(2, float64(exp))) * time.Second
+	if d > maks {
+		return maks
+	}
+	return d
+}
+
+func loginKeyEmail(email string) string { return "email:" + strings.ToLower(strings.TrimSpace(email)) }
+func loginKeyIP(ip string) string       { return "ip:" + ip }
+
+// loginDibatasi mengembalikan sisa jeda terlama dari penghitung IP & email (0 jika boleh mencoba)
+func loginDibatasi(ip, email string) time.Duration {
+	list, err := repository.GetLoginThrottles(loginKeyIP(ip), loginKeyEmail(email))
+	if err != nil {
+		// Penyimpanan penghitung bermasalah: jangan blokir login
+		log.Printf("⚠️ Gagal membaca throttle login: %v", err)
+		return 0
+	}
+	var sisa time.Duration
+	now := time.Now()
+	for _, t := range list {
+		if d := t.BlokirSampai.Sub(now); d > sisa {
+			sisa = d
+		}
+	}
+	return sisa
+}
+
+// catatLoginGagal menaikkan penghitung IP & email lalu memasang jeda backoff jika perlu
+func catatLoginGagal(ip, email string) {
+	for key, bebas := range map[string]int{loginKeyIP(ip): loginBebasPerIP, loginKeyEmail(email): loginBebasPerEmail} {
+		t, err := repository.IncrementLoginThrottle(key, loginThrottleWindow)
+		if err != nil {
+			log.Printf("⚠️ Gagal mencatat throttle login: %v", err)
+			continue
+		}
+		if d := loginBackoff(t.Gagal, bebas); d > 0 {
+			_ = repository.SetLoginThrottleBlokir(key, t.TerakhirGagal.Add(d))
+		}
+	}
+}
+
+// catatLoginAttempt menyimpan log percobaan login; kegagalan menyimpan tidak menggagalkan login
+func catatLoginAttempt(c *fiber.Ctx, email, userID string, berhasil bool, alasan string) {
+	id, err := utils.GenerateRandomToken(12)
+	if err != nil {
+		return
+	}
+	a := models.LoginAttempt{
+		ID:        id,
+		Email:     strings.ToLower(strings.TrimSpace(email)),
+		UserID:    userID,
+		IP:        c.IP(),
+		UserAgent: c.Get("User-Agent"),
+		Berhasil:  berhasil,
+		Alasan:    alasan,
+		Waktu:     time.Now(),
+	}
+	if err := repository.CreateLoginAttempt(&a); err != nil {
+		log.Printf("⚠️ Gagal mencatat percobaan login: %v", err)
+	}
+}
+
+// tolakLoginDibatasi membalas 429 dengan header Retry-After
+func tolakLoginDibatasi(c *fiber.Ctx, sisa time.Duration) error {
+	detik := int(math.Ceil(sisa.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(detik))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error":       "Terlalu banyak percobaan login, coba lagi nanti",
+		"retry_after": detik,
+	})
+}
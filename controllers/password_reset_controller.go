@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Masa berlaku token lupa password (env PASSWORD_RESET_TTL, contoh "30m"), default 30 menit
+func passwordResetTTL() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("PASSWORD_RESET_TTL")); err == nil && d > 0 {
+		return d
+	}
+	return 30 * time.Minute
+}
+
+// Link reset untuk email (RESET_PASSWORD_URL opsional, mis. halaman frontend; token ditambahkan sebagai query)
+func resetPasswordLink(token string) string {
+	base := strings.TrimSpace(os.Getenv("RESET_PASSWORD_URL"))
+	if base == "" {
+		return token
+	}
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+	return base + sep + "token=" + token
+}
+
+// ForgotPassword godoc
+//
+//	@Summary		Lupa password
+//	@Description	Mengirim token reset sekali pakai ke email. Respons selalu sama agar keberadaan email tidak bocor.
+//	@Tags			Authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		object{email=string}	true	"Email akun"
+//	@Success		200		{object}	map[string]interface{}
+//	@Failure		429		{object}	map[string]interface{}	"Terlalu banyak percobaan"
+//	@Failure		503		{object}	map[string]interface{}	"Email (SMTP) belum dikonfigurasi di production"
+//	@Router			/auth/forgot [post]
+func ForgotPassword(c *fiber.Ctx) error {
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := c.BodyParser(&body); err != nil || strings.TrimSpace(body.Email) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "email wajib"})
+	}
+	email := strings.TrimSpace(body.Email)
+	resp := fiber.Map{"message": "Jika email terdaftar, link reset password telah dikirim"}
+
+	mailer := utils.GetMailer()
+	if mailer == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Lupa password tidak tersedia, hubungi admin"})
+	}
+
+	// Backoff seperti login, tetapi dengan penghitung terpisah (prefiks "forgot:") agar
+	// permintaan reset tidak ikut memperlambat login pemilik akun
+	ip := c.IP()
+	if sisa := loginDibatasi("forgot:"+ip, "forgot:"+email); sisa > 0 {
+		return tolakLoginDibatasi(c, sisa)
+	}
+	catatLoginGagal("forgot:"+ip, "forgot:"+email)
+
+	// Pencarian user, pembuatan token & pengiriman email berjalan di latar belakang agar
+	// waktu respons sama untuk email terdaftar maupun tidak
+	go kirimResetPassword(mailer, email, ip)
+	return c.JSON(resp)
+}
+
+// kirimResetPassword membuat token reset untuk user aktif dengan email tersebut lalu mengirimkannya.
+// Kegagalan hanya dicatat di log server, tidak dibocorkan ke klien.
+func kirimResetPassword(mailer utils.Mailer, email, ip string) {
+	user, err := repository.FindUserByEmail(email)
+	if err != nil || user.Status != "aktif" {
+		return
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		log.Printf("⚠️ Gagal membuat token reset password untuk %s: %v", user.ID, err)
+		return
+	}
+	now := time.Now()
+	ttl := passwordResetTTL()
+	rt := models.PasswordResetToken{
+		ID:        utils.HashToken(token),
+		UserID:    user.ID,
+		IP:        ip,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	if err := repository.CreatePasswordReset(&rt); err != nil {
+		log.Printf("⚠️ Gagal menyimpan token reset password untuk %s: %v", user.ID, err)
+		return
+	}
+
+	isi := "Halo " + user.Nama + ",\n\n" +
+		"Kami menerima permintaan reset password untuk akun Anda. Gunakan link/token berikut dalam " +
+		ttl.String() + ":\n\n" + resetPasswordLink(token) + "\n\n" +
+		"Abaikan email ini jika Anda tidak meminta reset password."
+	if err := mailer.Kirim(user.Email, "Reset password", isi); err != nil {
+		log.Printf("⚠️ Gagal mengirim email reset password ke %s: %v", user.Email, err)
+	}
+}
+
+// ResetPassword godoc
+//
+//	@Summary		Reset password
+//	@Description	Memakai token dari email untuk menetapkan password baru. Token hanya berlaku sekali dan semua sesi lama dicabut.
+//	@Tags			Authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		object{token=string,password_baru=string}	true	"Token & password baru"
+//	@Success		200		{object}	map[string]interface{}
+//	@Failure		400		{object}	map[string]interface{}	"Token tidak valid / password lemah"
+//	@Router			/auth/reset [post]
+func ResetPassword(c *fiber.Ctx) error {
+	var body struct {
+		Token        string `json:"token"`
+		PasswordBaru string `json:"password_baru"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "token wajib"})
+	}
+	// Validasi kebijakan dulu agar token tidak hangus karena password lemah
+	if err := utils.ValidasiPassword(body.PasswordBaru); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	rt, err := repository.ConsumePasswordReset(utils.HashToken(body.Token))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token reset tidak valid atau kadaluarsa"})
+	}
+	user, err := repository.FindUserByID(rt.UserID)
+	if err != nil || user.Status != "aktif" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token reset tidak valid atau kadaluarsa"})
+	}
+
+	hashed, err := utils.HashPassword(body.PasswordBaru)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal hash password"})
+	}
+	if err := repository.UpdatePassword(user.ID, hashed, false); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan password"})
+	}
+	// Kepemilikan email terbukti: lockout & jeda login ikut dihapus, semua sesi lama dicabut
+	_ = repository.UnlockUser(user.ID)
+	_ = repository.DeleteLoginThrottle(loginKeyEmail(user.Email))
+	if err := repository.RevokeAllUserTokens(user.ID); err != nil {
+		log.Printf("⚠️ Gagal mencabut sesi setelah reset password %s: %v", user.ID, err)
+	}
+	return c.JSON(fiber.Map{"message": "Password berhasil direset, silakan login"})
+}
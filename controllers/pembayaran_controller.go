@@ -2,12 +2,16 @@ package controllers
 
 import (
 	"backend/models"
+	"backend/policy"
 	"backend/repository"
+	"errors"
+	"log"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // GetAllPembayaran godoc
@@ -21,16 +25,10 @@ import (
 //	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
 //	@Router			/pembayaran [get]
 func GetAllPembayaran(c *fiber.Ctx) error {
-	role := c.Locals("userRole").(string)
-	id := c.Locals("userID").(string)
-	if role != "admin" && role != "kasir" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses ditolak"})
-	}
-
-	filter := bson.M{}
-	if role != "admin" {
-		// IMPORTANT: kasir hanya boleh melihat pembayaran miliknya sendiri
-		filter["kasir_id"] = id
+	// IMPORTANT: kasir hanya boleh melihat pembayaran miliknya sendiri
+	filter, err := scopeAkses(c, policy.Pembayaran)
+	if err != nil {
+		return tolakAkses(c, err)
 	}
 
 	data, err := repository.GetPembayaranFiltered(filter)
@@ -56,12 +54,6 @@ func GetAllPembayaran(c *fiber.Ctx) error {
 //	@Router			/pembayaran/{id} [get]
 func GetPembayaranByID(c *fiber.Ctx) error {
 	id := c.Params("id")
-	role := c.Locals("userRole").(string)
-	userID := c.Locals("userID").(string)
-	if role != "admin" && role != "kasir" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses ditolak"})
-	}
-
 	data, err := repository.GetPembayaranByID(id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -70,8 +62,8 @@ func GetPembayaranByID(c *fiber.Ctx) error {
 		})
 	}
 
-	if role != "admin" && data.KasirID != userID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses ditolak"})
+	if ok, err := izinkanAkses(c, policy.Pembayaran, policy.Lihat, data); !ok {
+		return tolakAkses(c, err)
 	}
 
 	return c.JSON(data)
@@ -93,12 +85,7 @@ func GetPembayaranByID(c *fiber.Ctx) error {
 //	@Failure		422			{object}	map[string]interface{}	"Validasi gagal"
 //	@Router			/pembayaran [post]
 func CreatePembayaran(c *fiber.Ctx) error {
-	role := c.Locals("userRole").(string)
 	userID := c.Locals("userID").(string)
-	if role != "kasir" {
-		// Admin read-only; driver/gudang ditolak
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses ditolak"})
-	}
 
 	var body struct {
 		TransaksiID    string `json:"transaksi_id"`
@@ -126,8 +113,17 @@ func CreatePembayaran(c *fiber.Ctx) error {
 	if err != nil || trx == nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Transaksi tidak ditemukan"})
 	}
-	if trx.KasirID != userID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses ditolak"})
+	if ok, err := izinkanAkses(c, policy.Transaksi, policy.Ubah, trx); !ok {
+		return tolakAkses(c, err)
+	}
+
+	// Pembayaran kasir dicatat ke shift (laci kas) yang sedang buka
+	shiftID, err := shiftKasirAktifUntukPembayaran(c)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "Buka shift kasir terlebih dahulu"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal cek shift kasir", "error": err.Error()})
 	}
 
 	// Hitung total_toko dari transaksi (server-side)
@@ -163,6 +159,7 @@ func CreatePembayaran(c *fiber.Ctx) error {
 		Metode:      metode,
 		TotalBayar:  totalToko + ongkir,
 		Status:      "pending",
+		ShiftID:     shiftID,
 	}
 
 	id, err := repository.GenerateID("pembayaran")
@@ -182,6 +179,14 @@ func CreatePembayaran(c *fiber.Ctx) error {
 			"error":   err.Error(),
 		})
 	}
+	// Shift ditutup bersamaan dengan pembayaran ini: hitung ulang rekap agar tetap termasuk
+	if shiftID != "" {
+		if sh, err := repository.GetShiftKasirByID(shiftID); err == nil && sh.Status == "tutup" {
+			if _, err := rekapShiftTertutup(shiftID); err != nil {
+				log.Printf("⚠️ Gagal menghitung ulang rekap shift %s: %v", shiftID, err)
+			}
+		}
+	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": "Berhasil ditambahkan",
@@ -204,12 +209,6 @@ func CreatePembayaran(c *fiber.Ctx) error {
 //	@Router			/pembayaran/selesaikan/{id} [put]
 func SelesaikanPembayaran(c *fiber.Ctx) error {
 	id := c.Params("id")
-	role := c.Locals("userRole").(string)
-	userID := c.Locals("userID").(string)
-	if role != "kasir" {
-		// Admin read-only; driver/gudang ditolak
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses ditolak"})
-	}
 
 	pembayaran, err := repository.GetPembayaranByID(id)
 	if err != nil {
@@ -218,8 +217,8 @@ func SelesaikanPembayaran(c *fiber.Ctx) error {
 		})
 	}
 
-	if pembayaran.KasirID != userID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses ditolak"})
+	if ok, err := izinkanAkses(c, policy.Pembayaran, policy.Ubah, pembayaran); !ok {
+		return tolakAkses(c, err)
 	}
 
 	if pembayaran.Status == "Selesai" || pembayaran.Status == "selesai" {
@@ -228,18 +227,24 @@ func SelesaikanPembayaran(c *fiber.Ctx) error {
 		})
 	}
 
-	err = repository.SelesaikanPembayaran(id)
+	// Pelunasan dicatat ke laci kas yang menerimanya; rekap shift asal yang sudah ditutup
+	// dihitung ulang agar pembayaran ini tidak lagi tercatat pending di sana
+	err = repository.SelesaikanPembayaran(id, shiftPenerimaPelunasan(c, pembayaran), time.Now())
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Gagal menyelesaikan",
 			"error":   err.Error(),
 		})
 	}
+	sinkronShiftAsal(pembayaran)
 	// Tandai transaksi terkait menjadi Selesai
 	if pembayaran.TransaksiID != "" {
-		_, _ = repository.UpdateTransaksi(pembayaran.TransaksiID, bson.M{"status": "Selesai"})
+		_ = ubahDenganAudit(c, "transaksi", pembayaran.TransaksiID, func() error {
+			_, err := repository.UpdateTransaksi(pembayaran.TransaksiID, bson.M{"status": "Selesai"})
+			return err
+		})
 		// Mutasi stok yang berelasi dengan transaksi tersebut diberi keterangan 'terjual'
-		_ = repository.UpdateMutasiKeteranganByRef(pembayaran.TransaksiID, "terjual")
+		_ = ubahKeteranganMutasiDenganAudit(c, pembayaran.TransaksiID, "terjual")
 	}
 	return c.JSON(fiber.Map{
 		"message": "Transaksi berhasil diselesaikan",
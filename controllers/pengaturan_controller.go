@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/repository"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetPengaturanKeamanan godoc
+//
+//	@Summary		Pengaturan keamanan
+//	@Tags			Pengaturan
+//	@Security		BearerAuth
+//	@Produce		json
+//	@Success		200	{object}	models.PengaturanKeamanan
+//	@Router			/pengaturan/keamanan [get]
+func GetPengaturanKeamanan(c *fiber.Ctx) error {
+	p, err := repository.GetPengaturanKeamanan()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal mengambil pengaturan"})
+	}
+	return c.JSON(p)
+}
+
+// UpdatePengaturanKeamanan godoc
+//
+//	@Summary		Ubah pengaturan keamanan
+//	@Description	wajib_2fa_admin=true memaksa setiap admin mengaktifkan TOTP sebelum bisa memakai endpoint lain
+//	@Tags			Pengaturan
+//	@Security		BearerAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		object{wajib_2fa_admin=bool}	true	"Pengaturan"
+//	@Success		200		{object}	models.PengaturanKeamanan
+//	@Router			/pengaturan/keamanan [put]
+func UpdatePengaturanKeamanan(c *fiber.Ctx) error {
+	var body struct {
+		Wajib2FAAdmin *bool `json:"wajib_2fa_admin"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Request tidak valid"})
+	}
+	p, err := repository.GetPengaturanKeamanan()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal mengambil pengaturan"})
+	}
+	if body.Wajib2FAAdmin != nil {
+		p.Wajib2FAAdmin = *body.Wajib2FAAdmin
+	}
+	p.UpdatedBy, _ = c.Locals("userID").(string)
+	p.UpdatedAt = time.Now()
+	if err := repository.SavePengaturanKeamanan(p); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal menyimpan pengaturan"})
+	}
+	return c.JSON(p)
+}
+
+// GetPengaturanLaporan godoc
+//
+//	@Summary		Pengaturan laporan
+//	@Tags			Pengaturan
+//	@Security		BearerAuth
+//	@Produce		json
+//	@Success		200	{object}	models.PengaturanLaporan
+//	@Router			/pengaturan/laporan [get]
+func GetPengaturanLaporan(c *fiber.Ctx) error {
+	p, err := repository.GetPengaturanLaporan()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal mengambil pengaturan"})
+	}
+	return c.JSON(p)
+}
+
+// UpdatePengaturanLaporan godoc
+//
+//	@Summary		Ubah pengaturan laporan
+//	@Description	metode_hpp: "terakhir" (harga beli penerimaan terakhir) atau "rata_rata" (rata-rata bergerak). Berlaku untuk transaksi berikutnya; HPP transaksi lama tidak berubah.
+//	@Description	export_status: status pembayaran default export Excel (pending, selesai, batal). export_kecuali_pembayaran: ID pembayaran yang tidak pernah diekspor.
+//	@Tags			Pengaturan
+//	@Security		BearerAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		object{metode_hpp=string,export_status=[]string,export_kecuali_pembayaran=[]string}	true	"Pengaturan"
+//	@Success		200		{object}	models.PengaturanLaporan
+//	@Router			/pengaturan/laporan [put]
+func UpdatePengaturanLaporan(c *fiber.Ctx) error {
+	var body struct {
+		MetodeHPP               *string   `json:"metode_hpp"`
+		ExportStatus            *[]string `json:"export_status"`
+		ExportKecualiPembayaran *[]string `json:"export_kecuali_pembayaran"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Request tidak valid"})
+	}
+	p, err := repository.GetPengaturanLaporan()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal mengambil pengaturan"})
+	}
+	if body.MetodeHPP != nil {
+		if *body.MetodeHPP != models.MetodeHPPTerakhir && *body.MetodeHPP != models.MetodeHPPRataRata {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "metode_hpp harus salah satu dari: terakhir, rata_rata"})
+		}
+		p.MetodeHPP = *body.MetodeHPP
+	}
+	if body.ExportStatus != nil {
+		status, err := parseStatusPembayaran(*body.ExportStatus)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "export_status: " + err.Error()})
+		}
+		if len(status) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "export_status tidak boleh kosong"})
+		}
+		p.ExportStatus = status
+	}
+	if body.ExportKecualiPembayaran != nil {
+		ids := []string{}
+		for _, id := range *body.ExportKecualiPembayaran {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		p.ExportKecualiPembayaran = ids
+	}
+	p.UpdatedBy, _ = c.Locals("userID").(string)
+	p.UpdatedAt = time.Now()
+	if err := repository.SavePengaturanLaporan(p); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal menyimpan pengaturan"})
+	}
+	return c.JSON(p)
+}
@@ -2,7 +2,9 @@ package controllers
 
 import (
 	"backend/models"
+	"backend/policy"
 	"backend/repository"
+	"backend/utils"
 	"strings"
 	"time"
 
@@ -10,33 +12,31 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 )
 
+// Bangun entri riwayat status dengan aktor dari JWT
+func newStatusLog(c *fiber.Ctx, entitas, refID, dari, ke, catatan string) models.StatusLog {
+	aktorID, _ := c.Locals("userID").(string)
+	aktorNama, _ := c.Locals("userNama").(string)
+	role, _ := c.Locals("userRole").(string)
+	return models.StatusLog{
+		Entitas:   entitas,
+		RefID:     refID,
+		Dari:      dari,
+		Ke:        ke,
+		AktorID:   aktorID,
+		AktorNama: aktorNama,
+		Role:      role,
+		Catatan:   catatan,
+		Waktu:     time.Now(),
+	}
+}
+
 // List pengiriman: admin/kasir view all, driver only own
 func GetAllPengiriman(c *fiber.Ctx) error {
-	role := c.Locals("userRole").(string)
-	id := c.Locals("userID").(string)
-	filter := bson.M{}
-	if role == "driver" {
-		// IMPORTANT: driver hanya boleh melihat pengiriman miliknya sendiri
-		// (field DB: driver_id  diperlakukan sebagai assigned_driver_id)
-		filter["driver_id"] = id
-	}
-	if role == "kasir" {
-		// IMPORTANT: kasir hanya boleh melihat pengiriman untuk transaksi miliknya sendiri
-		// agar dashboard/pengiriman tidak bocor antar kasir.
-		trx, err := repository.ListTransaksi(bson.M{"kasir_id": id}, 0, 0)
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"message": "Gagal ambil transaksi kasir", "error": err.Error()})
-		}
-		ids := make([]string, 0, len(trx))
-		for _, t := range trx {
-			if t.ID != "" {
-				ids = append(ids, t.ID)
-			}
-		}
-		if len(ids) == 0 {
-			return c.JSON([]models.Pengiriman{})
-		}
-		filter["transaksi_id"] = bson.M{"$in": ids}
+	// IMPORTANT: driver hanya melihat pengiriman miliknya; kasir hanya pengiriman untuk
+	// transaksi miliknya agar dashboard/pengiriman tidak bocor antar kasir.
+	filter, err := scopeAkses(c, policy.Pengiriman)
+	if err != nil {
+		return tolakAkses(c, err)
 	}
 	list, err := repository.GetPengirimanFiltered(filter)
 	if err != nil {
@@ -47,22 +47,13 @@ func GetAllPengiriman(c *fiber.Ctx) error {
 
 func GetPengirimanByID(c *fiber.Ctx) error {
 	id := c.Params("id")
-	role := c.Locals("userRole").(string)
-	userID := c.Locals("userID").(string)
 	data, err := repository.GetPengirimanByID(id)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"message": "Data tidak ditemukan"})
 	}
-	if role == "driver" && data.DriverID != userID {
-		// IMPORTANT: driver tidak boleh akses pengiriman driver lain
-		return c.Status(403).JSON(fiber.Map{"message": "Akses ditolak"})
-	}
-	if role == "kasir" {
-		// IMPORTANT: kasir tidak boleh akses pengiriman milik kasir lain
-		trx, err := repository.GetTransaksiByID(data.TransaksiID)
-		if err != nil || trx == nil || trx.KasirID != userID {
-			return c.Status(403).JSON(fiber.Map{"message": "Akses ditolak"})
-		}
+	// IMPORTANT: driver/kasir tidak boleh akses pengiriman milik driver/kasir lain
+	if ok, err := izinkanAkses(c, policy.Pengiriman, policy.Lihat, data); !ok {
+		return tolakAkses(c, err)
 	}
 	// Enrich detail: ambil transaksi dan pelanggan terkait untuk keperluan tampilan detail
 	var trx *models.Transaksi
@@ -92,6 +83,11 @@ func GetPengirimanByID(c *fiber.Ctx) error {
 			totalToko = sum
 		}
 	}
+	timeline := data.RiwayatStatus
+	if timeline == nil {
+		timeline = []models.StatusLog{}
+	}
+	tracking := trackingLink(data.TrackingNonce)
 	return c.JSON(fiber.Map{
 		"id":           data.ID,
 		"transaksi_id": data.TransaksiID,
@@ -106,6 +102,11 @@ func GetPengirimanByID(c *fiber.Ctx) error {
 		"pelanggan_nama": pelangganNama,
 		"total_toko":     totalToko, // total belanja tanpa ongkir
 		"items":          items,
+		// Timeline perubahan status (pengiriman + efek ke transaksi/pembayaran)
+		"timeline": timeline,
+		// Link tracking publik untuk pelanggan
+		"tracking_token": tracking["tracking_token"],
+		"tracking_url":   tracking["tracking_url"],
 	})
 }
 
@@ -118,8 +119,23 @@ func CreatePengiriman(c *fiber.Ctx) error {
 	if p.TransaksiID == "" || p.DriverID == "" {
 		return c.Status(422).JSON(fiber.Map{"message": "transaksi_id dan driver_id wajib"})
 	}
+	// Validasi driver: terdaftar, aktif, sedang dalam shift dan kapasitas belum penuh
+	sedia, err := cekKetersediaanDriver(p.DriverID, p.KendaraanID, time.Now())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"message": "Gagal cek ketersediaan driver", "error": err.Error()})
+	}
+	if !sedia.Tersedia {
+		return c.Status(422).JSON(fiber.Map{"message": sedia.Alasan})
+	}
 	// Hitung ongkir server-side berdasarkan jenis kendaraan
 	jenis := strings.ToLower(strings.TrimSpace(p.Jenis))
+	if sedia.Kendaraan != nil {
+		if jenis != "" && jenis != sedia.Kendaraan.Jenis {
+			return c.Status(422).JSON(fiber.Map{"message": "jenis tidak sesuai dengan kendaraan " + sedia.Kendaraan.PlatNomor})
+		}
+		jenis = sedia.Kendaraan.Jenis
+		p.KendaraanID = sedia.Kendaraan.ID
+	}
 	if jenis == "" {
 		jenis = "mobil"
 	}
@@ -140,44 +156,59 @@ func CreatePengiriman(c *fiber.Ctx) error {
 		p.Status = "diproses"
 	}
 	p.CreatedAt = time.Now()
+	p.PerjalananID = ""
+	if nonce, err := utils.GenerateTrackingNonce(); err == nil {
+		p.TrackingNonce = nonce
+	}
+	p.RiwayatStatus = []models.StatusLog{newStatusLog(c, "pengiriman", p.ID, "", p.Status, "Pengiriman dibuat")}
 	res, err := repository.CreatePengiriman(p)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"message": "Gagal simpan", "error": err.Error()})
 	}
-	return c.Status(201).JSON(fiber.Map{"message": "Berhasil ditambahkan", "data": res.InsertedID})
+	resp := trackingLink(p.TrackingNonce)
+	resp["message"] = "Berhasil ditambahkan"
+	resp["data"] = res.InsertedID
+	return c.Status(201).JSON(resp)
 }
 
 // Update: driver can update own status; admin/kasir can update any
 func UpdatePengiriman(c *fiber.Ctx) error {
 	id := c.Params("id")
-	role := c.Locals("userRole").(string)
-	userID := c.Locals("userID").(string)
 	existing, err := repository.GetPengirimanByID(id)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"message": "Data tidak ditemukan"})
 	}
-	if role == "driver" && existing.DriverID != userID {
-		// IMPORTANT: driver hanya boleh update status pengiriman miliknya sendiri
-		return c.Status(403).JSON(fiber.Map{"message": "Akses ditolak"})
-	}
-	if role == "kasir" {
-		// IMPORTANT: kasir hanya boleh update pengiriman untuk transaksi miliknya sendiri
-		trx, err := repository.GetTransaksiByID(existing.TransaksiID)
-		if err != nil || trx == nil || trx.KasirID != userID {
-			return c.Status(403).JSON(fiber.Map{"message": "Akses ditolak"})
-		}
+	// IMPORTANT: driver hanya update pengiriman miliknya; kasir hanya untuk transaksi miliknya
+	if ok, err := izinkanAkses(c, policy.Pengiriman, policy.Ubah, existing); !ok {
+		return tolakAkses(c, err)
 	}
 	var payload models.Pengiriman
 	if err := c.BodyParser(&payload); err != nil {
 		return c.Status(400).JSON(fiber.Map{"message": "Request tidak valid", "error": err.Error()})
 	}
+	var note struct {
+		Catatan string `json:"catatan"`
+	}
+	_ = c.BodyParser(&note)
 	upd, err := repository.UpdatePengiriman(id, payload)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"message": "Gagal update", "error": err.Error()})
 	}
 
-	// Sinkronkan status ke transaksi & pembayaran
+	// Riwayat hanya dicatat jika status benar-benar berubah
 	statusLower := strings.ToLower(payload.Status)
+	berubah := payload.Status != "" && !strings.EqualFold(payload.Status, existing.Status)
+	catatan := note.Catatan
+	if catatan == "" && statusLower == "batal" {
+		catatan = payload.AlasanBatal
+	}
+	logs := []models.StatusLog{newStatusLog(c, "pengiriman", existing.ID, existing.Status, payload.Status, catatan)}
+	var trxStatusLama string
+	if trx, err := repository.GetTransaksiByID(existing.TransaksiID); err == nil && trx != nil {
+		trxStatusLama = trx.Status
+	}
+
+	// Sinkronkan status ke transaksi & pembayaran
 	if statusLower == "selesai" || statusLower == "dikirim" || statusLower == "sedang diantarkan" || statusLower == "sedang di antar" {
 		// Map ke status transaksi yang ramah tampil
 		tStatus := ""
@@ -188,35 +219,76 @@ func UpdatePengiriman(c *fiber.Ctx) error {
 			tStatus = "Sedang Diantarkan"
 		}
 		// Update status transaksi
-		_, _ = repository.UpdateTransaksi(existing.TransaksiID, bson.M{"status": tStatus})
+		_ = ubahDenganAudit(c, "transaksi", existing.TransaksiID, func() error {
+			_, err := repository.UpdateTransaksi(existing.TransaksiID, bson.M{"status": tStatus})
+			return err
+		})
+		logs = append(logs, newStatusLog(c, "transaksi", existing.TransaksiID, trxStatusLama, tStatus, ""))
 
 		// Jika selesai: tandai pembayaran menjadi Selesai
 		if tStatus == "Selesai" {
 			pays, _ := repository.GetPembayaranFiltered(bson.M{"transaksi_id": existing.TransaksiID})
 			for _, p := range pays {
-				_, _ = repository.UpdatePembayaran(p.ID, models.Pembayaran{Status: "Selesai"})
+				if strings.EqualFold(p.Status, "selesai") {
+					continue
+				}
+				_ = ubahDenganAudit(c, "pembayaran", p.ID, func() error {
+					return repository.SelesaikanPembayaran(p.ID, shiftPenerimaPelunasan(c, &p), time.Now())
+				})
+				sinkronShiftAsal(&p)
+				logs = append(logs, newStatusLog(c, "pembayaran", p.ID, p.Status, "Selesai", ""))
 			}
 			// Perbarui keterangan mutasi stok dari 'reservasi' menjadi 'terjual'
-			_ = repository.UpdateMutasiKeteranganByRef(existing.TransaksiID, "terjual")
+			_ = ubahKeteranganMutasiDenganAudit(c, existing.TransaksiID, "terjual")
 		}
 	}
 
 	// Jika batal: wajib simpan alasan, transaksi kembali ke proses, pembayaran ditandai Batal
 	if statusLower == "batal" {
 		// Kembalikan transaksi ke status 'Proses'
-		_, _ = repository.UpdateTransaksi(existing.TransaksiID, bson.M{"status": "Proses"})
+		_ = ubahDenganAudit(c, "transaksi", existing.TransaksiID, func() error {
+			_, err := repository.UpdateTransaksi(existing.TransaksiID, bson.M{"status": "Proses"})
+			return err
+		})
+		logs = append(logs, newStatusLog(c, "transaksi", existing.TransaksiID, trxStatusLama, "Proses", ""))
 		// Tandai semua pembayaran terkait sebagai 'Batal'
 		pays, _ := repository.GetPembayaranFiltered(bson.M{"transaksi_id": existing.TransaksiID})
 		for _, p := range pays {
-			_, _ = repository.UpdatePembayaran(p.ID, models.Pembayaran{Status: "Batal"})
+			_ = ubahDenganAudit(c, "pembayaran", p.ID, func() error {
+				_, err := repository.UpdatePembayaran(p.ID, models.Pembayaran{Status: "Batal"})
+				return err
+			})
+			sinkronShiftAsal(&p)
+			logs = append(logs, newStatusLog(c, "pembayaran", p.ID, p.Status, "Batal", ""))
 		}
 	}
+
+	// Jika pengiriman bagian dari perjalanan: tandai stop terkait
+	if existing.PerjalananID != "" && (statusLower == "selesai" || statusLower == "batal") {
+		_, _ = repository.UpdatePerjalananStopStatus(existing.PerjalananID, existing.ID, statusLower, time.Now())
+	}
+
+	// Simpan riwayat (pengiriman + efek samping) jika status berubah
+	if !berubah {
+		logs = nil
+	}
+	if err := repository.AppendPengirimanRiwayat(existing.ID, logs...); err != nil {
+		return c.Status(500).JSON(fiber.Map{"message": "Status diupdate, tetapi gagal mencatat riwayat", "error": err.Error()})
+	}
 	return c.JSON(fiber.Map{"message": "Berhasil diupdate", "modified": upd.ModifiedCount})
 }
 
 // Delete: admin/kasir only
 func DeletePengiriman(c *fiber.Ctx) error {
 	id := c.Params("id")
+	existing, err := repository.GetPengirimanByID(id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"message": "Data tidak ditemukan"})
+	}
+	// IMPORTANT: kasir hanya boleh hapus pengiriman untuk transaksi miliknya
+	if ok, err := izinkanAkses(c, policy.Pengiriman, policy.Hapus, existing); !ok {
+		return tolakAkses(c, err)
+	}
 	res, err := repository.DeletePengiriman(id)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"message": "Gagal hapus", "error": err.Error()})
@@ -0,0 +1,412 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/policy"
+	"backend/repository"
+	"backend/utils"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Titik awal rute (gudang/toko) dari env DEPOT_LAT & DEPOT_LNG, nil jika tidak diset
+func depotTitik() *utils.Titik {
+	lat, errLat := strconv.ParseFloat(os.Getenv("DEPOT_LAT"), 64)
+	lng, errLng := strconv.ParseFloat(os.Getenv("DEPOT_LNG"), 64)
+	if errLat != nil || errLng != nil {
+		return nil
+	}
+	return &utils.Titik{Latitude: lat, Longitude: lng}
+}
+
+// Urutkan ulang stops dengan nearest-neighbour lalu set ulang nomor urutan
+func urutkanStops(start *utils.Titik, stops []models.PerjalananStop) []models.PerjalananStop {
+	titik := make([]*utils.Titik, len(stops))
+	for i, s := range stops {
+		if s.Latitude != nil && s.Longitude != nil {
+			titik[i] = &utils.Titik{Latitude: *s.Latitude, Longitude: *s.Longitude}
+		}
+	}
+	order := utils.UrutkanNearestNeighbour(start, titik)
+	hasil := make([]models.PerjalananStop, 0, len(stops))
+	for i, idx := range order {
+		s := stops[idx]
+		s.Urutan = i + 1
+		hasil = append(hasil, s)
+	}
+	return hasil
+}
+
+func awalHari(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// GET /perjalanan (admin/kasir semua; driver hanya miliknya)
+func GetAllPerjalanan(c *fiber.Ctx) error {
+	// IMPORTANT: driver hanya boleh melihat perjalanan miliknya sendiri
+	filter, err := scopeAkses(c, policy.Perjalanan)
+	if err != nil {
+		return tolakAkses(c, err)
+	}
+	if driverID := c.Query("driver_id"); driverID != "" && filter["driver_id"] == nil {
+		filter["driver_id"] = driverID
+	}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	list, err := repository.GetPerjalananFiltered(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal ambil data perjalanan", "error": err.Error()})
+	}
+	if list == nil {
+		list = []models.Perjalanan{}
+	}
+	return c.JSON(list)
+}
+
+// GET /perjalanan/hari-ini (driver: perjalanan hari ini beserta detail stop)
+func GetPerjalananHariIni(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	start := awalHari(time.Now())
+	filter := bson.M{
+		"driver_id": userID,
+		"tanggal":   bson.M{"$gte": start, "$lt": start.AddDate(0, 0, 1)},
+		"status":    bson.M{"$ne": "batal"},
+	}
+	list, err := repository.GetPerjalananFiltered(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal ambil data perjalanan", "error": err.Error()})
+	}
+
+	// Enrich stop dengan nama & alamat pelanggan agar driver tidak perlu request tambahan
+	pelangganCache := map[string]*models.Pelanggan{}
+	result := make([]fiber.Map, 0, len(list))
+	for _, p := range list {
+		stops := make([]fiber.Map, 0, len(p.Stops))
+		for _, s := range p.Stops {
+			pel, ok := pelangganCache[s.PelangganID]
+			if !ok && s.PelangganID != "" {
+				pel, _ = repository.GetPelangganByID(s.PelangganID)
+				pelangganCache[s.PelangganID] = pel
+			}
+			row := fiber.Map{
+				"urutan":        s.Urutan,
+				"pengiriman_id": s.PengirimanID,
+				"transaksi_id":  s.TransaksiID,
+				"pelanggan_id":  s.PelangganID,
+				"latitude":      s.Latitude,
+				"longitude":     s.Longitude,
+				"status":        s.Status,
+				"tiba_at":       s.TibaAt,
+			}
+			if pel != nil {
+				row["pelanggan_nama"] = pel.Nama
+				row["pelanggan_alamat"] = pel.Alamat
+				row["pelanggan_no_hp"] = pel.NoHP
+			}
+			stops = append(stops, row)
+		}
+		result = append(result, fiber.Map{
+			"id":         p.ID,
+			"driver_id":  p.DriverID,
+			"kendaraan":  p.Kendaraan,
+			"tanggal":    p.Tanggal,
+			"status":     p.Status,
+			"mulai_at":   p.MulaiAt,
+			"selesai_at": p.SelesaiAt,
+			"catatan":    p.Catatan,
+			"stops":      stops,
+		})
+	}
+	return c.JSON(result)
+}
+
+// GET /perjalanan/:id (driver hanya miliknya)
+func GetPerjalananByID(c *fiber.Ctx) error {
+	p, err := repository.GetPerjalananByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Perjalanan tidak ditemukan"})
+	}
+	// IMPORTANT: driver tidak boleh akses perjalanan driver lain
+	if ok, err := izinkanAkses(c, policy.Perjalanan, policy.Lihat, p); !ok {
+		return tolakAkses(c, err)
+	}
+	return c.JSON(p)
+}
+
+// POST /perjalanan (kasir): kelompokkan beberapa pengiriman menjadi satu trip driver
+func CreatePerjalanan(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	var body struct {
+		DriverID       string   `json:"driver_id"`
+		Kendaraan      string   `json:"kendaraan"`
+		KendaraanID    string   `json:"kendaraan_id"`
+		Tanggal        string   `json:"tanggal"` // YYYY-MM-DD, default hari ini
+		PengirimanIDs  []string `json:"pengiriman_ids"`
+		Catatan        string   `json:"catatan"`
+		Optimasi       *bool    `json:"optimasi"` // default true
+		StartLatitude  *float64 `json:"start_latitude"`
+		StartLongitude *float64 `json:"start_longitude"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Request tidak valid", "error": err.Error()})
+	}
+	if body.DriverID == "" || len(body.PengirimanIDs) == 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": "driver_id dan pengiriman_ids wajib"})
+	}
+	kendaraan := strings.ToLower(strings.TrimSpace(body.Kendaraan))
+	if kendaraan != "" && kendaraan != "mobil" && kendaraan != "motor" {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": "kendaraan harus salah satu dari: mobil, motor"})
+	}
+	now := time.Now()
+	tanggal := awalHari(now)
+	if body.Tanggal != "" {
+		t, err := time.ParseInLocation("2006-01-02", body.Tanggal, time.Local)
+		if err != nil {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": "format tanggal harus YYYY-MM-DD"})
+		}
+		tanggal = t
+	}
+
+	// Ketersediaan driver & kendaraan dicek sekarang untuk perjalanan hari ini, atau pada
+	// awal shift "tersedia" pertama untuk perjalanan di tanggal lain
+	at := now
+	if !tanggal.Equal(awalHari(now)) {
+		at = tanggal
+		shifts, err := repository.GetShiftDriverInRange(body.DriverID, tanggal, tanggal.AddDate(0, 0, 1))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal cek ketersediaan driver", "error": err.Error()})
+		}
+		ada := false
+		for _, s := range shifts {
+			if s.Status != "tersedia" {
+				continue
+			}
+			mulai := s.Mulai
+			if mulai.Before(tanggal) {
+				mulai = tanggal
+			}
+			if !ada || mulai.Before(at) {
+				at, ada = mulai, true
+			}
+		}
+	}
+	sedia, err := cekKetersediaanDriver(body.DriverID, body.KendaraanID, at)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal cek ketersediaan driver", "error": err.Error()})
+	}
+	// Kapasitas penuh tidak menghalangi: stop perjalanan adalah pengiriman yang sudah ditugaskan
+	if !sedia.Tersedia && !sedia.Penuh {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": sedia.Alasan})
+	}
+	kendaraanID := body.KendaraanID
+	if sedia.Kendaraan != nil {
+		if kendaraan != "" && kendaraan != sedia.Kendaraan.Jenis {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": "kendaraan tidak sesuai dengan kendaraan " + sedia.Kendaraan.PlatNomor})
+		}
+		kendaraan = sedia.Kendaraan.Jenis
+		kendaraanID = sedia.Kendaraan.ID
+	}
+	if kendaraan == "" {
+		kendaraan = "mobil"
+	}
+	if tanggal.Equal(awalHari(now)) {
+		berjalan, err := repository.GetPerjalananFiltered(bson.M{"driver_id": body.DriverID, "status": "berjalan"})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal cek perjalanan driver", "error": err.Error()})
+		}
+		if len(berjalan) > 0 {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": "Driver sedang menjalankan perjalanan " + berjalan[0].ID})
+		}
+	}
+
+	// Validasi setiap pengiriman & bangun daftar stop
+	seen := map[string]struct{}{}
+	stops := make([]models.PerjalananStop, 0, len(body.PengirimanIDs))
+	for _, pid := range body.PengirimanIDs {
+		if _, dup := seen[pid]; dup {
+			continue
+		}
+		seen[pid] = struct{}{}
+		krm, err := repository.GetPengirimanByID(pid)
+		if err != nil {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": fmt.Sprintf("Pengiriman tidak ditemukan: %s", pid)})
+		}
+		if krm.DriverID != body.DriverID {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": fmt.Sprintf("Pengiriman %s tidak ditugaskan ke driver ini", pid)})
+		}
+		if krm.PerjalananID != "" {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": fmt.Sprintf("Pengiriman %s sudah masuk perjalanan %s", pid, krm.PerjalananID)})
+		}
+		st := strings.ToLower(krm.Status)
+		if st == "selesai" || st == "batal" {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": fmt.Sprintf("Pengiriman %s sudah %s", pid, st)})
+		}
+		trx, err := repository.GetTransaksiByID(krm.TransaksiID)
+		if err != nil || trx == nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses ditolak"})
+		}
+		// IMPORTANT: kasir hanya boleh mengelompokkan pengiriman untuk transaksi miliknya sendiri
+		if ok, err := izinkanAkses(c, policy.Transaksi, policy.Ubah, trx); !ok {
+			return tolakAkses(c, err)
+		}
+		stop := models.PerjalananStop{
+			PengirimanID: krm.ID,
+			TransaksiID:  krm.TransaksiID,
+			PelangganID:  trx.PelangganID,
+			Status:       "menunggu",
+		}
+		if pel, err := repository.GetPelangganByID(trx.PelangganID); err == nil && pel != nil {
+			stop.Latitude = pel.Latitude
+			stop.Longitude = pel.Longitude
+		}
+		stops = append(stops, stop)
+	}
+
+	start := depotTitik()
+	if body.StartLatitude != nil && body.StartLongitude != nil {
+		start = &utils.Titik{Latitude: *body.StartLatitude, Longitude: *body.StartLongitude}
+	}
+	if body.Optimasi == nil || *body.Optimasi {
+		stops = urutkanStops(start, stops)
+	} else {
+		for i := range stops {
+			stops[i].Urutan = i + 1
+		}
+	}
+
+	id, err := repository.GenerateID("perjalanan")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal generate ID", "error": err.Error()})
+	}
+	p := models.Perjalanan{
+		ID:          id,
+		DriverID:    body.DriverID,
+		Kendaraan:   kendaraan,
+		KendaraanID: kendaraanID,
+		Tanggal:     tanggal,
+		Status:      "direncanakan",
+		Stops:       stops,
+		Catatan:     body.Catatan,
+		CreatedBy:   userID,
+		CreatedAt:   time.Now(),
+	}
+	if _, err := repository.CreatePerjalanan(&p); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal simpan perjalanan", "error": err.Error()})
+	}
+	ids := make([]string, 0, len(stops))
+	for _, s := range stops {
+		ids = append(ids, s.PengirimanID)
+	}
+	if err := repository.SetPerjalananPengiriman(ids, p.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal menautkan pengiriman", "error": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Perjalanan berhasil dibuat", "data": p})
+}
+
+// PUT /perjalanan/:id/optimasi (kasir): hitung ulang urutan stop dengan nearest-neighbour
+func OptimasiPerjalanan(c *fiber.Ctx) error {
+	p, err := repository.GetPerjalananByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Perjalanan tidak ditemukan"})
+	}
+	if ok, err := izinkanAkses(c, policy.Perjalanan, policy.Ubah, p); !ok {
+		return tolakAkses(c, err)
+	}
+	if p.Status != "direncanakan" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Hanya perjalanan berstatus direncanakan yang bisa diurutkan ulang"})
+	}
+	var body struct {
+		StartLatitude  *float64 `json:"start_latitude"`
+		StartLongitude *float64 `json:"start_longitude"`
+	}
+	_ = c.BodyParser(&body)
+	start := depotTitik()
+	if body.StartLatitude != nil && body.StartLongitude != nil {
+		start = &utils.Titik{Latitude: *body.StartLatitude, Longitude: *body.StartLongitude}
+	}
+	// Perbarui koordinat dari data pelanggan terbaru sebelum diurutkan
+	for i, s := range p.Stops {
+		if pel, err := repository.GetPelangganByID(s.PelangganID); err == nil && pel != nil {
+			p.Stops[i].Latitude = pel.Latitude
+			p.Stops[i].Longitude = pel.Longitude
+		}
+	}
+	stops := urutkanStops(start, p.Stops)
+	if _, err := repository.UpdatePerjalanan(p.ID, bson.M{"stops": stops}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal update perjalanan", "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "Urutan stop berhasil diperbarui", "stops": stops})
+}
+
+// PUT /perjalanan/:id/mulai (driver pemilik)
+func MulaiPerjalanan(c *fiber.Ctx) error {
+	p, err := repository.GetPerjalananByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Perjalanan tidak ditemukan"})
+	}
+	if ok, err := izinkanAkses(c, policy.Perjalanan, policy.Ubah, p); !ok {
+		return tolakAkses(c, err)
+	}
+	if p.Status != "direncanakan" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Perjalanan sudah dimulai atau selesai"})
+	}
+	now := time.Now()
+	if _, err := repository.UpdatePerjalanan(p.ID, bson.M{"status": "berjalan", "mulai_at": now}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal update perjalanan", "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "Perjalanan dimulai", "mulai_at": now})
+}
+
+// PUT /perjalanan/:id/selesai (driver pemilik)
+func SelesaikanPerjalanan(c *fiber.Ctx) error {
+	p, err := repository.GetPerjalananByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Perjalanan tidak ditemukan"})
+	}
+	if ok, err := izinkanAkses(c, policy.Perjalanan, policy.Ubah, p); !ok {
+		return tolakAkses(c, err)
+	}
+	if p.Status != "berjalan" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Perjalanan belum dimulai atau sudah selesai"})
+	}
+	now := time.Now()
+	if _, err := repository.UpdatePerjalanan(p.ID, bson.M{"status": "selesai", "selesai_at": now}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal update perjalanan", "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "Perjalanan selesai", "selesai_at": now})
+}
+
+// DELETE /perjalanan/:id (kasir): hanya saat masih direncanakan, pengiriman dilepas dari trip
+func DeletePerjalanan(c *fiber.Ctx) error {
+	p, err := repository.GetPerjalananByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Perjalanan tidak ditemukan"})
+	}
+	if ok, err := izinkanAkses(c, policy.Perjalanan, policy.Hapus, p); !ok {
+		return tolakAkses(c, err)
+	}
+	if p.Status != "direncanakan" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Perjalanan yang sudah berjalan tidak bisa dihapus"})
+	}
+	ids := make([]string, 0, len(p.Stops))
+	for _, s := range p.Stops {
+		ids = append(ids, s.PengirimanID)
+	}
+	if len(ids) > 0 {
+		if err := repository.SetPerjalananPengiriman(ids, ""); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal melepas pengiriman", "error": err.Error()})
+		}
+	}
+	res, err := repository.DeletePerjalanan(p.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal hapus", "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "Berhasil dihapus", "deleted": res.DeletedCount})
+}
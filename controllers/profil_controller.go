@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// GetMe godoc
+//
+//	@Summary		Profil saya
+//	@Description	Mengambil profil user yang sedang login (semua role)
+//	@Tags			Profil
+//	@Security		BearerAuth
+//	@Produce		json
+//	@Success		200	{object}	models.User
+//	@Failure		404	{object}	map[string]interface{}
+//	@Router			/users/me [get]
+func GetMe(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	user, err := repository.FindUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "User tidak ditemukan"})
+	}
+	user.Password = ""
+	return c.JSON(user)
+}
+
+// UpdateMe godoc
+//
+//	@Summary		Ubah profil saya
+//	@Description	Mengubah nama, no_hp dan alamat milik user yang sedang login. Email & role hanya bisa diubah admin.
+//	@Tags			Profil
+//	@Security		BearerAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		models.ProfilInput	true	"Field profil"
+//	@Success		200		{object}	models.User
+//	@Failure		400		{object}	map[string]interface{}
+//	@Router			/users/me [put]
+func UpdateMe(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	var input models.ProfilInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Request tidak valid"})
+	}
+
+	set := bson.M{}
+	if input.Nama != nil {
+		nama := strings.TrimSpace(*input.Nama)
+		if nama == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Nama tidak boleh kosong"})
+		}
+		if exists, err := repository.ExistsUserByNama(nama, userID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal validasi nama"})
+		} else if exists {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Nama sudah digunakan"})
+		}
+		set["nama"] = nama
+	}
+	if input.NoHP != nil {
+		set["no_hp"] = strings.TrimSpace(*input.NoHP)
+	}
+	if input.Alamat != nil {
+		set["alamat"] = strings.TrimSpace(*input.Alamat)
+	}
+	if len(set) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Tidak ada field yang diubah"})
+	}
+
+	if err := repository.UpdateProfil(userID, set); err != nil {
+		if isDuplicateKey(err) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Nama sudah digunakan"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal update profil"})
+	}
+	user, err := repository.FindUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal membaca profil"})
+	}
+	user.Password = ""
+	return c.JSON(user)
+}
+
+// GantiPasswordSaya godoc
+//
+//	@Summary		Ganti password saya
+//	@Description	Mengganti password dengan memverifikasi password lama. Semua sesi lain dicabut dan pasangan token baru dikembalikan.
+//	@Tags			Profil
+//	@Security		BearerAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		models.GantiPasswordInput	true	"Password lama & baru"
+//	@Success		200		{object}	map[string]interface{}
+//	@Failure		400		{object}	map[string]interface{}	"Password baru tidak memenuhi kebijakan"
+//	@Failure		401		{object}	map[string]interface{}	"Password lama salah"
+//	@Router			/users/me/password [post]
+func GantiPasswordSaya(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	var input models.GantiPasswordInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Request tidak valid"})
+	}
+	user, err := repository.FindUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "User tidak ditemukan"})
+	}
+	if !utils.CheckPasswordHash(input.PasswordLama, user.Password) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Password lama salah"})
+	}
+	if err := utils.ValidasiPassword(input.PasswordBaru); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if input.PasswordBaru == input.PasswordLama {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Password baru harus berbeda dari password lama"})
+	}
+
+	hashed, err := utils.HashPassword(input.PasswordBaru)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal hash password"})
+	}
+	if err := repository.UpdatePassword(userID, hashed, false); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal mengganti password"})
+	}
+	// Semua sesi lama (termasuk token saat ini) dicabut, lalu sesi ini diberi token baru
+	if err := repository.RevokeAllUserTokens(userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Password diganti, tetapi gagal mencabut sesi lama"})
+	}
+	user, err = repository.FindUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal membaca user"})
+	}
+	resp, err := issueTokenPair(c, user, "")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Password diganti, silakan login ulang"})
+	}
+	resp["message"] = "Password berhasil diganti"
+	return c.JSON(resp)
+}
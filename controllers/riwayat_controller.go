@@ -1,35 +1,86 @@
 package controllers
 
 import (
+	"backend/policy"
 	"backend/repository"
+	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 // GetRiwayatPembayaran godoc
+//
 //	@Summary		Get payment history
-//	@Description	Mengambil riwayat pembayaran berdasarkan role user
+//	@Description	Riwayat pembayaran (gabungan transaksi & pengiriman) berdasarkan role user.
+//	@Description	Kasir: pembayaran miliknya (kasir_id). Driver: pembayaran yang pengirimannya ditugaskan kepadanya.
 //	@Tags			Riwayat
 //	@Security		BearerAuth
 //	@Produce		json
-//	@Success		200	{array}		models.Pembayaran
-//	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
+//	@Param			start		query		string	false	"Tanggal mulai (YYYY-MM-DD), wajib bersama end"
+//	@Param			end			query		string	false	"Tanggal akhir (YYYY-MM-DD), wajib bersama start"
+//	@Param			month		query		int		false	"Bulan (1-12), wajib bersama year"
+//	@Param			year		query		int		false	"Tahun"
+//	@Param			status		query		string	false	"Status pembayaran, pisahkan dengan koma (default: Selesai)"
+//	@Param			page		query		int		false	"Halaman (default 1)"
+//	@Param			page_size	query		int		false	"Jumlah per halaman (default 20, maks 100)"
+//	@Success		200			{object}	map[string]interface{}
+//	@Failure		400			{object}	map[string]interface{}	"Filter tidak valid"
+//	@Failure		403			{object}	map[string]interface{}	"Role tidak boleh melihat riwayat"
+//	@Failure		500			{object}	map[string]interface{}	"Internal Server Error"
 //	@Router			/riwayat [get]
 func GetRiwayatPembayaran(c *fiber.Ctx) error {
-	role := c.Locals("userRole").(string)
-	id := c.Locals("userID").(string)
-	filter := map[string]interface{}{}
-	if role == "driver" {
-		filter["id_driver"] = id
-	} else if role == "kasir" {
-		filter["id_kasir"] = id
+	scope, err := scopeAkses(c, policy.Riwayat)
+	if err != nil {
+		return tolakAkses(c, err)
+	}
+
+	dateFilter, err := buildCreatedAtFilterFromQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	// Default riwayat = pembayaran selesai (case defensive)
+	status := []string{"Selesai", "selesai"}
+	if raw := strings.TrimSpace(c.Query("status")); raw != "" {
+		status = status[:0]
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				status = append(status, s)
+			}
+		}
 	}
-	data, err := repository.GetRiwayatPembayaran(filter)
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	f := repository.RiwayatFilter{
+		Status:    status,
+		CreatedAt: dateFilter,
+		Page:      page,
+		PageSize:  pageSize,
+	}
+	// Scope riwayat berupa kasir_id atau driver_id (lihat policy.Riwayat)
+	f.KasirID, _ = scope["kasir_id"].(string)
+	f.DriverID, _ = scope["driver_id"].(string)
+
+	rows, total, err := repository.GetRiwayatPembayaran(f)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Gagal ambil riwayat pembayaran",
 			"error":   err.Error(),
 		})
 	}
-	return c.JSON(data)
+	return c.JSON(fiber.Map{
+		"data":      rows,
+		"page":      page,
+		"page_size": pageSize,
+		"total":     total,
+	})
 }
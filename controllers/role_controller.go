@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/repository"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetAllRolePermissions godoc
+//
+//	@Summary		Daftar role & permission
+//	@Tags			Role
+//	@Security		BearerAuth
+//	@Produce		json
+//	@Success		200	{array}		models.RolePermission
+//	@Router			/roles [get]
+func GetAllRolePermissions(c *fiber.Ctx) error {
+	list, err := repository.GetAllRolePermissions()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal mengambil data role"})
+	}
+	return c.JSON(list)
+}
+
+// GetKatalogPermission godoc
+//
+//	@Summary		Katalog permission
+//	@Description	Semua permission yang bisa diberikan ke role
+//	@Tags			Role
+//	@Security		BearerAuth
+//	@Produce		json
+//	@Success		200	{array}		models.PermissionInfo
+//	@Router			/roles/permissions [get]
+func GetKatalogPermission(c *fiber.Ctx) error {
+	return c.JSON(models.KatalogPermission)
+}
+
+// GetRolePermission godoc
+//
+//	@Summary		Permission satu role
+//	@Tags			Role
+//	@Security		BearerAuth
+//	@Produce		json
+//	@Param			role	path		string	true	"Nama role"
+//	@Success		200		{object}	models.RolePermission
+//	@Failure		404		{object}	map[string]interface{}
+//	@Router			/roles/{role} [get]
+func GetRolePermission(c *fiber.Ctx) error {
+	rp, err := repository.GetRolePermission(c.Params("role"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Role tidak ditemukan"})
+	}
+	return c.JSON(rp)
+}
+
+// UpdateRolePermission godoc
+//
+//	@Summary		Ubah permission role
+//	@Description	Mengganti seluruh permission role. Berlaku untuk semua user dengan role tersebut (maks. 30 detik karena cache).
+//	@Tags			Role
+//	@Security		BearerAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			role	path		string						true	"Nama role"
+//	@Param			body	body		object{permissions=[]string}	true	"Daftar permission"
+//	@Success		200		{object}	models.RolePermission
+//	@Failure		400		{object}	map[string]interface{}
+//	@Failure		404		{object}	map[string]interface{}
+//	@Router			/roles/{role} [put]
+func UpdateRolePermission(c *fiber.Ctx) error {
+	role := c.Params("role")
+	if _, err := repository.GetRolePermission(role); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Role tidak ditemukan"})
+	}
+	var body struct {
+		Permissions []string `json:"permissions"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Request tidak valid"})
+	}
+
+	seen := map[string]bool{}
+	perms := []string{}
+	for _, p := range body.Permissions {
+		if !models.PermissionDikenal(p) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Permission tidak dikenal: " + p})
+		}
+		if !seen[p] {
+			seen[p] = true
+			perms = append(perms, p)
+		}
+	}
+	// Jangan sampai tidak ada lagi yang bisa mengelola role
+	if role == "admin" && !seen[models.PermRoleKelola] {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Role admin wajib memiliki " + models.PermRoleKelola})
+	}
+	sort.Strings(perms)
+
+	rp := models.RolePermission{Role: role, Permissions: perms, UpdatedAt: time.Now()}
+	rp.UpdatedBy, _ = c.Locals("userID").(string)
+	if err := repository.SaveRolePermission(&rp); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal menyimpan permission role"})
+	}
+	return c.JSON(rp)
+}
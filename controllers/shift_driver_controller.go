@@ -0,0 +1,346 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/policy"
+	"backend/repository"
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Kapasitas default jika driver tidak punya kendaraan terdaftar (env MAX_PENGIRIMAN_DRIVER)
+func kapasitasDefaultDriver() int {
+	if n, err := strconv.Atoi(os.Getenv("MAX_PENGIRIMAN_DRIVER")); err == nil && n > 0 {
+		return n
+	}
+	return 10
+}
+
+// Driver tanpa shift pada hari tersebut tetap dianggap tersedia (operasional yang belum memakai
+// jadwal shift); DRIVER_WAJIB_SHIFT=true mengaktifkan penegakan shift yang ketat
+func driverWajibShift() bool {
+	v, _ := strconv.ParseBool(os.Getenv("DRIVER_WAJIB_SHIFT"))
+	return v
+}
+
+type ketersediaanDriver struct {
+	Driver    *models.User
+	Kendaraan *models.Kendaraan
+	Shift     *models.ShiftDriver
+	Beban     int64
+	Kapasitas int
+	Tersedia  bool
+	// Penuh: semua syarat terpenuhi kecuali kapasitas (pengiriman yang sudah ditugaskan
+	// tetap boleh dikelompokkan menjadi perjalanan)
+	Penuh  bool
+	Alasan string
+}
+
+// cekKetersediaanDriver memeriksa apakah driver bisa menerima pengiriman baru pada waktu `at`.
+// Aturan shift: `at` harus berada di dalam shift berstatus "tersedia"; driver tanpa shift pada
+// hari tersebut tetap tersedia kecuali shift diwajibkan (lihat driverWajibShift).
+// kendaraanID opsional; jika kosong dipakai kendaraan dari shift lalu kendaraan milik driver.
+// Kendaraan ditolak jika terdaftar untuk driver lain, dipakai shift driver lain pada `at`,
+// atau masih dipakai pengiriman aktif driver lain.
+func cekKetersediaanDriver(driverID, kendaraanID string, at time.Time) (*ketersediaanDriver, error) {
+	hasil := &ketersediaanDriver{}
+	u, err := repository.GetKaryawanByID(driverID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			hasil.Alasan = "Driver tidak ditemukan"
+			return hasil, nil
+		}
+		return nil, err
+	}
+	hasil.Driver = u
+	if u.Role != "driver" {
+		hasil.Alasan = "User bukan driver"
+		return hasil, nil
+	}
+	if u.Status != "aktif" {
+		hasil.Alasan = "Driver nonaktif"
+		return hasil, nil
+	}
+
+	start := awalHari(at)
+	shifts, err := repository.GetShiftDriverInRange(driverID, start, start.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	if len(shifts) == 0 && driverWajibShift() {
+		hasil.Alasan = "Driver tidak punya shift pada hari tersebut"
+		return hasil, nil
+	}
+	if len(shifts) > 0 {
+		for i := range shifts {
+			s := shifts[i]
+			if !at.Before(s.Mulai) && at.Before(s.Selesai) {
+				hasil.Shift = &s
+				break
+			}
+		}
+		if hasil.Shift == nil {
+			hasil.Alasan = "Driver tidak sedang dalam shift"
+			return hasil, nil
+		}
+		if hasil.Shift.Status != "tersedia" {
+			hasil.Alasan = "Driver sedang " + hasil.Shift.Status
+			return hasil, nil
+		}
+	}
+
+	if kendaraanID == "" && hasil.Shift != nil {
+		kendaraanID = hasil.Shift.KendaraanID
+	}
+	if kendaraanID != "" {
+		k, err := repository.GetKendaraanByID(kendaraanID)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				hasil.Alasan = "Kendaraan tidak ditemukan"
+				return hasil, nil
+			}
+			return nil, err
+		}
+		hasil.Kendaraan = k
+	} else {
+		list, err := repository.GetKendaraanFiltered(bson.M{"driver_id": driverID, "aktif": true})
+		if err != nil {
+			return nil, err
+		}
+		if len(list) > 0 {
+			hasil.Kendaraan = &list[0]
+		}
+	}
+	if k := hasil.Kendaraan; k != nil {
+		if !k.Aktif {
+			hasil.Alasan = "Kendaraan tidak aktif"
+			return hasil, nil
+		}
+		if k.DriverID != "" && k.DriverID != driverID {
+			hasil.Alasan = "Kendaraan " + k.PlatNomor + " terdaftar untuk driver lain"
+			return hasil, nil
+		}
+		lain, err := repository.GetShiftDriverFiltered(bson.M{
+			"kendaraan_id": k.ID,
+			"driver_id":    bson.M{"$ne": driverID},
+			"status":       "tersedia",
+			"mulai":        bson.M{"$lte": at},
+			"selesai":      bson.M{"$gt": at},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(lain) > 0 {
+			hasil.Alasan = "Kendaraan " + k.PlatNomor + " dipakai shift driver lain"
+			return hasil, nil
+		}
+		dipakai, err := repository.CountPengirimanAktifKendaraanLain(k.ID, driverID)
+		if err != nil {
+			return nil, err
+		}
+		if dipakai > 0 {
+			hasil.Alasan = "Kendaraan " + k.PlatNomor + " masih dipakai pengiriman driver lain"
+			return hasil, nil
+		}
+	}
+
+	hasil.Kapasitas = kapasitasDefaultDriver()
+	if hasil.Kendaraan != nil && hasil.Kendaraan.Kapasitas > 0 {
+		hasil.Kapasitas = hasil.Kendaraan.Kapasitas
+	}
+	beban, err := repository.CountPengirimanAktifByDriver(driverID)
+	if err != nil {
+		return nil, err
+	}
+	hasil.Beban = beban
+	if beban >= int64(hasil.Kapasitas) {
+		hasil.Penuh = true
+		hasil.Alasan = "Kapasitas driver penuh"
+		return hasil, nil
+	}
+	hasil.Tersedia = true
+	return hasil, nil
+}
+
+// GET /users/drivers/tersedia?jenis=motor (admin, kasir): saran driver yang bisa menerima pengiriman
+func GetDriverTersedia(c *fiber.Ctx) error {
+	jenis := strings.ToLower(strings.TrimSpace(c.Query("jenis")))
+	if jenis != "" && jenis != "mobil" && jenis != "motor" {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": "jenis harus salah satu dari: mobil, motor"})
+	}
+	drivers, err := repository.GetAllDrivers()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal mengambil data driver", "error": err.Error()})
+	}
+	now := time.Now()
+	hasil := make([]*ketersediaanDriver, 0, len(drivers))
+	for _, d := range drivers {
+		if d.Status != "aktif" {
+			continue
+		}
+		k, err := cekKetersediaanDriver(d.ID, "", now)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal cek ketersediaan driver", "error": err.Error()})
+		}
+		if !k.Tersedia {
+			continue
+		}
+		if jenis != "" && (k.Kendaraan == nil || k.Kendaraan.Jenis != jenis) {
+			continue
+		}
+		hasil = append(hasil, k)
+	}
+	// Driver dengan sisa kapasitas terbanyak ditampilkan lebih dulu
+	sort.SliceStable(hasil, func(i, j int) bool {
+		return int64(hasil[i].Kapasitas)-hasil[i].Beban > int64(hasil[j].Kapasitas)-hasil[j].Beban
+	})
+	out := make([]fiber.Map, 0, len(hasil))
+	for _, k := range hasil {
+		out = append(out, fiber.Map{
+			"driver_id": k.Driver.ID,
+			"nama":      k.Driver.Nama,
+			"no_hp":     k.Driver.NoHP,
+			"kendaraan": k.Kendaraan,
+			"beban":     k.Beban,
+			"kapasitas": k.Kapasitas,
+			"sisa":      int64(k.Kapasitas) - k.Beban,
+		})
+	}
+	return c.JSON(out)
+}
+
+// GET /shift-driver (admin/kasir semua; driver hanya miliknya) - filter: driver_id, tanggal (YYYY-MM-DD)
+func GetAllShiftDriver(c *fiber.Ctx) error {
+	// Driver hanya melihat jadwalnya sendiri
+	filter, err := scopeAkses(c, policy.ShiftDriver)
+	if err != nil {
+		return tolakAkses(c, err)
+	}
+	if driverID := c.Query("driver_id"); driverID != "" && filter["driver_id"] == nil {
+		filter["driver_id"] = driverID
+	}
+	if tgl := c.Query("tanggal"); tgl != "" {
+		t, err := time.ParseInLocation("2006-01-02", tgl, time.Local)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "format tanggal harus YYYY-MM-DD"})
+		}
+		filter["mulai"] = bson.M{"$lt": t.AddDate(0, 0, 1)}
+		filter["selesai"] = bson.M{"$gt": t}
+	}
+	list, err := repository.GetShiftDriverFiltered(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal mengambil shift", "error": err.Error()})
+	}
+	if list == nil {
+		list = []models.ShiftDriver{}
+	}
+	return c.JSON(list)
+}
+
+func validasiShiftDriver(s *models.ShiftDriver) error {
+	if s.DriverID == "" {
+		return errors.New("driver_id wajib")
+	}
+	if err := validasiDriverKendaraan(s.DriverID); err != nil {
+		return err
+	}
+	if s.Mulai.IsZero() || s.Selesai.IsZero() || !s.Selesai.After(s.Mulai) {
+		return errors.New("mulai dan selesai wajib, selesai harus setelah mulai")
+	}
+	if s.Status == "" {
+		s.Status = "tersedia"
+	}
+	if s.Status != "tersedia" && s.Status != "libur" {
+		return errors.New("status harus salah satu dari: tersedia, libur")
+	}
+	if s.KendaraanID != "" {
+		if _, err := repository.GetKendaraanByID(s.KendaraanID); err != nil {
+			return errors.New("kendaraan tidak ditemukan")
+		}
+	}
+	return nil
+}
+
+// POST /shift-driver (admin)
+func CreateShiftDriver(c *fiber.Ctx) error {
+	var s models.ShiftDriver
+	if err := c.BodyParser(&s); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Data tidak valid", "error": err.Error()})
+	}
+	if err := validasiShiftDriver(&s); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": err.Error()})
+	}
+	overlap, err := repository.GetShiftDriverInRange(s.DriverID, s.Mulai, s.Selesai)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal validasi shift"})
+	}
+	if len(overlap) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": "Shift bertabrakan dengan shift " + overlap[0].ID})
+	}
+	id, err := repository.GenerateID("shift_driver")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal generate ID"})
+	}
+	s.ID = id
+	s.CreatedAt = time.Now()
+	if _, err := repository.CreateShiftDriver(&s); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal menambah shift"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Shift berhasil ditambah", "id": s.ID})
+}
+
+// PUT /shift-driver/:id (admin)
+func UpdateShiftDriver(c *fiber.Ctx) error {
+	id := c.Params("id")
+	existing, err := repository.GetShiftDriverByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Shift tidak ditemukan"})
+	}
+	s := *existing
+	if err := c.BodyParser(&s); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Data tidak valid", "error": err.Error()})
+	}
+	s.ID = existing.ID
+	s.CreatedAt = existing.CreatedAt
+	if err := validasiShiftDriver(&s); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": err.Error()})
+	}
+	overlap, err := repository.GetShiftDriverInRange(s.DriverID, s.Mulai, s.Selesai)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal validasi shift"})
+	}
+	for _, o := range overlap {
+		if o.ID != id {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": "Shift bertabrakan dengan shift " + o.ID})
+		}
+	}
+	update := bson.M{
+		"driver_id":    s.DriverID,
+		"kendaraan_id": s.KendaraanID,
+		"mulai":        s.Mulai,
+		"selesai":      s.Selesai,
+		"status":       s.Status,
+		"catatan":      s.Catatan,
+	}
+	if _, err := repository.UpdateShiftDriver(id, update); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal update shift"})
+	}
+	return c.JSON(fiber.Map{"message": "Shift berhasil diupdate"})
+}
+
+// DELETE /shift-driver/:id (admin)
+func DeleteShiftDriver(c *fiber.Ctx) error {
+	res, err := repository.DeleteShiftDriver(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal hapus shift"})
+	}
+	return c.JSON(fiber.Map{"message": "Shift berhasil dihapus", "deleted": res.DeletedCount})
+}
@@ -0,0 +1,407 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/policy"
+	"backend/repository"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Metode pembayaran dinormalisasi huruf kecil; kosong dianggap tunai (default CreatePembayaran)
+func normalisasiMetode(m string) string {
+	m = strings.ToLower(strings.TrimSpace(m))
+	if m == "" || m == "tunai" {
+		return "cash"
+	}
+	return m
+}
+
+// shiftPenerima mengembalikan shift yang menerima uang pembayaran selesai. Data lama (tanpa
+// selesai_at) dianggap dilunasi di shift asalnya.
+func shiftPenerima(p models.Pembayaran) string {
+	if p.ShiftSelesaiID == "" && p.SelesaiAt == nil {
+		return p.ShiftID
+	}
+	return p.ShiftSelesaiID
+}
+
+// hitungRekapShift membandingkan total pembayaran per metode dengan hasil hitung kasir.
+// Pembayaran selesai masuk nilai diharapkan shift yang menerima pelunasannya; pending milik
+// shift ini dilaporkan terpisah agar pembayaran yang belum lunas tidak tampil sebagai
+// kekurangan laci, begitu juga yang dilunasi di luar shift kasir setelah shift ditutup.
+// Pembayaran batal tidak dihitung; baris tunai selalu ada karena memuat kas awal.
+func hitungRekapShift(s *models.ShiftKasir, pays []models.Pembayaran, kasDihitung *float64, setoran map[string]float64) ([]models.ShiftRekapMetode, float64) {
+	per := map[string]*models.ShiftRekapMetode{"cash": {Metode: "cash"}}
+	baris := func(p models.Pembayaran) *models.ShiftRekapMetode {
+		m := normalisasiMetode(p.Metode)
+		r, ok := per[m]
+		if !ok {
+			r = &models.ShiftRekapMetode{Metode: m}
+			per[m] = r
+		}
+		return r
+	}
+	for _, p := range pays {
+		switch {
+		case strings.EqualFold(p.Status, "batal"):
+		case !strings.EqualFold(p.Status, "selesai"):
+			if p.ShiftID == s.ID {
+				r := baris(p)
+				r.JumlahPending++
+				r.TotalPending += p.TotalBayar
+			}
+		case shiftPenerima(p) == s.ID:
+			r := baris(p)
+			r.Jumlah++
+			r.Total += p.TotalBayar
+		case p.ShiftID == s.ID && shiftPenerima(p) == "":
+			r := baris(p)
+			r.JumlahLuarShift++
+			r.TotalLuarShift += p.TotalBayar
+		}
+		// selain itu: dilunasi di shift lain dan dihitung di rekap shift tersebut
+	}
+	for m := range setoran {
+		m = normalisasiMetode(m)
+		if _, ok := per[m]; !ok {
+			per[m] = &models.ShiftRekapMetode{Metode: m}
+		}
+	}
+	aktual := map[string]float64{}
+	for m, v := range setoran {
+		aktual[normalisasiMetode(m)] = v
+	}
+	if kasDihitung != nil {
+		aktual["cash"] = *kasDihitung
+	}
+
+	rekap := make([]models.ShiftRekapMetode, 0, len(per))
+	var totalSelisih float64
+	for m, r := range per {
+		r.Diharapkan = r.Total
+		if m == "cash" {
+			r.Diharapkan += s.KasAwal
+		}
+		if v, ok := aktual[m]; ok {
+			selisih := v - r.Diharapkan
+			r.Aktual = &v
+			r.Selisih = &selisih
+			totalSelisih += selisih
+		}
+		rekap = append(rekap, *r)
+	}
+	sort.Slice(rekap, func(i, j int) bool {
+		if rekap[i].Metode == "cash" || rekap[j].Metode == "cash" {
+			return rekap[i].Metode == "cash"
+		}
+		return rekap[i].Metode < rekap[j].Metode
+	})
+	return rekap, totalSelisih
+}
+
+// shiftKasirAktifUntukPembayaran: role yang mengelola laci kas wajib punya shift terbuka
+// saat menerima pembayaran. Mengembalikan "" jika role tidak memakai shift kasir.
+func shiftKasirAktifUntukPembayaran(c *fiber.Ctx) (string, error) {
+	role, _ := c.Locals("userRole").(string)
+	wajib, err := repository.RoleHasPermission(role, models.PermShiftKasirKelola)
+	if err != nil || !wajib {
+		return "", err
+	}
+	userID, _ := c.Locals("userID").(string)
+	s, err := repository.GetShiftKasirAktif(userID)
+	if err != nil {
+		return "", err
+	}
+	return s.ID, nil
+}
+
+// POST /shift-kasir/buka (kasir): buka sesi laci kas dengan kas awal
+func BukaShiftKasir(c *fiber.Ctx) error {
+	var in models.BukaShiftInput
+	if err := c.BodyParser(&in); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Request tidak valid", "error": err.Error()})
+	}
+	if in.KasAwal < 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": "kas_awal tidak boleh negatif"})
+	}
+	userID, _ := c.Locals("userID").(string)
+	userNama, _ := c.Locals("userNama").(string)
+	if aktif, err := repository.GetShiftKasirAktif(userID); err == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "Masih ada shift yang belum ditutup", "shift_id": aktif.ID})
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal cek shift aktif", "error": err.Error()})
+	}
+
+	id, err := repository.GenerateID("shift_kasir")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal generate ID", "error": err.Error()})
+	}
+	s := models.ShiftKasir{
+		ID:          id,
+		KasirID:     userID,
+		KasirNama:   userNama,
+		Status:      "buka",
+		KasAwal:     in.KasAwal,
+		DibukaAt:    time.Now(),
+		CatatanBuka: strings.TrimSpace(in.Catatan),
+	}
+	if err := repository.CreateShiftKasir(&s); err != nil {
+		if isDuplicateKey(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "Masih ada shift yang belum ditutup"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal membuka shift", "error": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Shift dibuka", "id": s.ID, "data": s})
+}
+
+// GET /shift-kasir/aktif (kasir): shift yang sedang buka beserta rekap berjalan
+func GetShiftKasirAktif(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	s, err := repository.GetShiftKasirAktif(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Tidak ada shift yang sedang buka"})
+	}
+	return detailShiftKasir(c, s)
+}
+
+// POST /shift-kasir/tutup (kasir): tutup shift dengan hasil hitung kas, laporkan selisih per metode
+func TutupShiftKasir(c *fiber.Ctx) error {
+	var in models.TutupShiftInput
+	if err := c.BodyParser(&in); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Request tidak valid", "error": err.Error()})
+	}
+	if in.KasDihitung == nil || *in.KasDihitung < 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": "kas_dihitung wajib diisi dan tidak boleh negatif"})
+	}
+	for m, v := range in.Setoran {
+		if v < 0 {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": "setoran " + m + " tidak boleh negatif"})
+		}
+	}
+	userID, _ := c.Locals("userID").(string)
+	s, err := repository.GetShiftKasirAktif(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Tidak ada shift yang sedang buka"})
+	}
+	// Tutup dulu (bersyarat masih buka) agar tidak ada pembayaran baru, baru kemudian rekap
+	// dihitung dari data shift & pembayaran yang dibaca ulang
+	ok, err := repository.TutupShiftKasir(s.ID, *in.KasDihitung, in.Setoran, strings.TrimSpace(in.Catatan), time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal menutup shift", "error": err.Error()})
+	}
+	if !ok {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "Shift sudah ditutup"})
+	}
+	s, err = rekapShiftTertutup(s.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Shift ditutup, tetapi gagal menyimpan rekap", "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"message":       "Shift ditutup",
+		"id":            s.ID,
+		"kas_awal":      s.KasAwal,
+		"dibuka_at":     s.DibukaAt,
+		"ditutup_at":    s.DitutupAt,
+		"rekap":         s.Rekap,
+		"total_selisih": s.TotalSelisih,
+	})
+}
+
+// rekapShiftTertutup menghitung ulang rekap shift yang sudah ditutup dari pembayaran terbaru dan
+// menyimpannya dengan cek versi. Dipanggil saat tutup dan oleh pembayaran yang tersimpan
+// bersamaan dengan penutupan, sehingga rekap terakhir selalu memuat semua pembayaran shift.
+func rekapShiftTertutup(id string) (*models.ShiftKasir, error) {
+	for i := 0; i < 5; i++ {
+		s, err := repository.GetShiftKasirByID(id)
+		if err != nil {
+			return nil, err
+		}
+		pays, err := repository.GetPembayaranShift(s.ID)
+		if err != nil {
+			return nil, err
+		}
+		s.Rekap, s.TotalSelisih = hitungRekapShift(s, pays, s.KasDihitung, s.Setoran)
+		ok, err := repository.SimpanRekapShiftKasir(s.ID, s.RekapVersi, s.Rekap, s.TotalSelisih)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return s, nil
+		}
+	}
+	return nil, errors.New("rekap shift terus berubah, coba lagi")
+}
+
+// shiftPenerimaPelunasan menentukan shift laci kas yang menerima pelunasan: shift terbuka milik
+// user yang melunasi, lalu shift asal pembayaran jika masih buka. "" = di luar shift kasir.
+func shiftPenerimaPelunasan(c *fiber.Ctx, p *models.Pembayaran) string {
+	userID, _ := c.Locals("userID").(string)
+	if s, err := repository.GetShiftKasirAktif(userID); err == nil {
+		return s.ID
+	}
+	if p.ShiftID != "" {
+		if s, err := repository.GetShiftKasirByID(p.ShiftID); err == nil && s.Status == "buka" {
+			return s.ID
+		}
+	}
+	return ""
+}
+
+// sinkronShiftAsal menghitung ulang rekap shift asal pembayaran yang statusnya berubah
+// setelah shift tersebut ditutup (mis. pending dilunasi atau dibatalkan belakangan)
+func sinkronShiftAsal(p *models.Pembayaran) {
+	if p.ShiftID == "" {
+		return
+	}
+	s, err := repository.GetShiftKasirByID(p.ShiftID)
+	if err != nil || s.Status != "tutup" {
+		return
+	}
+	if _, err := rekapShiftTertutup(s.ID); err != nil {
+		log.Printf("⚠️ Gagal menghitung ulang rekap shift %s: %v", s.ID, err)
+	}
+}
+
+// GET /shift-kasir (admin semua; kasir hanya miliknya)
+func GetAllShiftKasir(c *fiber.Ctx) error {
+	filter, err := scopeAkses(c, policy.ShiftKasir)
+	if err != nil {
+		return tolakAkses(c, err)
+	}
+	if kasirID := c.Query("kasir_id"); kasirID != "" && filter["kasir_id"] == nil {
+		filter["kasir_id"] = kasirID
+	}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	dateFilter, err := buildCreatedAtFilterFromQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if v, ok := dateFilter["created_at"]; ok {
+		filter["dibuka_at"] = v
+	}
+	list, err := repository.ListShiftKasir(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal ambil data shift", "error": err.Error()})
+	}
+	return c.JSON(list)
+}
+
+// GET /shift-kasir/:id (admin semua; kasir hanya miliknya)
+func GetShiftKasirByID(c *fiber.Ctx) error {
+	s, err := repository.GetShiftKasirByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Shift tidak ditemukan"})
+	}
+	if ok, err := izinkanAkses(c, policy.ShiftKasir, policy.Lihat, s); !ok {
+		return tolakAkses(c, err)
+	}
+	return detailShiftKasir(c, s)
+}
+
+// detailShiftKasir: shift + daftar pembayarannya; rekap dihitung ulang selama shift masih buka
+func detailShiftKasir(c *fiber.Ctx, s *models.ShiftKasir) error {
+	pays, err := repository.GetPembayaranShift(s.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal ambil pembayaran shift", "error": err.Error()})
+	}
+	if pays == nil {
+		pays = []models.Pembayaran{}
+	}
+	if s.Status == "buka" {
+		s.Rekap, _ = hitungRekapShift(s, pays, nil, nil)
+	}
+	return c.JSON(fiber.Map{"shift": s, "pembayaran": pays})
+}
+
+// GET /shift-kasir/laporan (admin): rekap shift yang sudah ditutup per kasir dalam periode
+func LaporanShiftKasir(c *fiber.Ctx) error {
+	filter := bson.M{"status": "tutup"}
+	if kasirID := c.Query("kasir_id"); kasirID != "" {
+		filter["kasir_id"] = kasirID
+	}
+	dateFilter, err := buildCreatedAtFilterFromQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if v, ok := dateFilter["created_at"]; ok {
+		filter["dibuka_at"] = v
+	}
+	list, err := repository.ListShiftKasir(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal ambil data shift", "error": err.Error()})
+	}
+
+	type rekapKasir struct {
+		KasirID      string                              `json:"kasir_id"`
+		KasirNama    string                              `json:"kasir_nama"`
+		JumlahShift  int                                 `json:"jumlah_shift"`
+		ShiftSelisih int                                 `json:"shift_selisih"` // shift dengan selisih != 0
+		TotalSelisih float64                             `json:"total_selisih"`
+		PerMetode    map[string]*models.ShiftRekapMetode `json:"per_metode"`
+	}
+	perKasir := map[string]*rekapKasir{}
+	urutan := []string{}
+	var totalSelisih float64
+	for _, s := range list {
+		r, ok := perKasir[s.KasirID]
+		if !ok {
+			r = &rekapKasir{KasirID: s.KasirID, KasirNama: s.KasirNama, PerMetode: map[string]*models.ShiftRekapMetode{}}
+			perKasir[s.KasirID] = r
+			urutan = append(urutan, s.KasirID)
+		}
+		r.JumlahShift++
+		if s.TotalSelisih != 0 {
+			r.ShiftSelisih++
+		}
+		r.TotalSelisih += s.TotalSelisih
+		totalSelisih += s.TotalSelisih
+		for _, m := range s.Rekap {
+			agg, ok := r.PerMetode[m.Metode]
+			if !ok {
+				agg = &models.ShiftRekapMetode{Metode: m.Metode}
+				r.PerMetode[m.Metode] = agg
+			}
+			agg.Jumlah += m.Jumlah
+			agg.Total += m.Total
+			agg.JumlahPending += m.JumlahPending
+			agg.TotalPending += m.TotalPending
+			agg.JumlahLuarShift += m.JumlahLuarShift
+			agg.TotalLuarShift += m.TotalLuarShift
+			agg.Diharapkan += m.Diharapkan
+			if m.Aktual != nil {
+				v := *m.Aktual
+				if agg.Aktual != nil {
+					v += *agg.Aktual
+				}
+				agg.Aktual = &v
+			}
+			if m.Selisih != nil {
+				v := *m.Selisih
+				if agg.Selisih != nil {
+					v += *agg.Selisih
+				}
+				agg.Selisih = &v
+			}
+		}
+	}
+	hasil := make([]*rekapKasir, 0, len(urutan))
+	for _, id := range urutan {
+		hasil = append(hasil, perKasir[id])
+	}
+	return c.JSON(fiber.Map{
+		"per_kasir":     hasil,
+		"jumlah_shift":  len(list),
+		"total_selisih": totalSelisih,
+		"shift":         list,
+	})
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"backend/models"
+	"testing"
+	"time"
+)
+
+func TestRekapShiftPelunasan(t *testing.T) {
+	lunas := time.Date(2026, 3, 14, 20, 0, 0, 0, time.Local)
+	shift := &models.ShiftKasir{ID: "SK1", KasAwal: 100000}
+	pays := []models.Pembayaran{
+		{ID: "P1", ShiftID: "SK1", Metode: "cash", TotalBayar: 50000, Status: "Selesai"},                                            // data lama
+		{ID: "P2", ShiftID: "SK1", Metode: "Tunai", TotalBayar: 20000, Status: "Selesai", ShiftSelesaiID: "SK1", SelesaiAt: &lunas}, // dilunasi di shift ini
+		{ID: "P3", ShiftID: "SK1", Metode: "qris", TotalBayar: 30000, Status: "pending"},                                            // belum lunas
+		{ID: "P4", ShiftID: "SK1", Metode: "cash", TotalBayar: 40000, Status: "Selesai", ShiftSelesaiID: "SK2", SelesaiAt: &lunas},  // masuk laci shift lain
+		{ID: "P5", ShiftID: "SK1", Metode: "cash", TotalBayar: 15000, Status: "Selesai", SelesaiAt: &lunas},                         // di luar shift kasir
+		{ID: "P6", ShiftID: "SK0", Metode: "cash", TotalBayar: 25000, Status: "Selesai", ShiftSelesaiID: "SK1", SelesaiAt: &lunas},  // pending shift lama, lunas di sini
+		{ID: "P7", ShiftID: "SK1", Metode: "cash", TotalBayar: 99000, Status: "Batal"},
+	}
+	kas := 195000.0
+	rekap, selisih := hitungRekapShift(shift, pays, &kas, nil)
+
+	per := map[string]models.ShiftRekapMetode{}
+	for _, r := range rekap {
+		per[r.Metode] = r
+	}
+	cash := per["cash"]
+	if cash.Jumlah != 3 || cash.Total != 95000 || cash.Diharapkan != 195000 {
+		t.Errorf("cash jumlah/total/diharapkan = %d/%v/%v, want 3/95000/195000", cash.Jumlah, cash.Total, cash.Diharapkan)
+	}
+	if cash.JumlahLuarShift != 1 || cash.TotalLuarShift != 15000 {
+		t.Errorf("cash luar shift = %d/%v, want 1/15000", cash.JumlahLuarShift, cash.TotalLuarShift)
+	}
+	if selisih != 0 {
+		t.Errorf("selisih = %v, want 0", selisih)
+	}
+	qris := per["qris"]
+	if qris.Jumlah != 0 || qris.JumlahPending != 1 || qris.TotalPending != 30000 || qris.Diharapkan != 0 {
+		t.Errorf("qris = %+v, want hanya 1 pending 30000", qris)
+	}
+}
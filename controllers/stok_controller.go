@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"backend/middleware"
 	"backend/models"
 	"backend/repository"
 	"strconv"
@@ -102,6 +103,19 @@ func CreateMutasi(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": "jumlah harus lebih dari 0 untuk mutasi masuk/keluar"})
 	}
 
+	if m.HargaBeli < 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"message": "harga_beli tidak boleh negatif"})
+	}
+	if m.Jenis != "masuk" {
+		m.HargaBeli = 0
+	}
+
+	if m.Jenis == "masuk" && m.HargaBeli > 0 {
+		if _, err := repository.GetProdukByID(m.ProdukID); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Produk tidak ditemukan"})
+		}
+	}
+
 	// Jika keluar (manual), validasi stok mencukupi lebih dulu
 	if m.Jenis == "keluar" {
 		saldo, err := repository.GetSaldoProduk(m.ProdukID)
@@ -138,5 +152,21 @@ func CreateMutasi(c *fiber.Ctx) error {
 	if _, err := repository.CreateMutasi(&m); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
 	}
+	// Penerimaan barang ber-harga: harga beli terakhir & rata-rata bergerak dihitung ulang dari
+	// ledger (termasuk mutasi ini) sehingga penerimaan bersamaan tidak saling menimpa
+	if m.Jenis == "masuk" && m.HargaBeli > 0 {
+		err := ubahDenganAudit(c, "produk", m.ProdukID, func() error {
+			return repository.SinkronHargaPokokProduk(m.ProdukID)
+		})
+		if err != nil {
+			// Respons gagal tidak dicatat middleware Audit, padahal mutasinya sudah tersimpan
+			middleware.CatatAudit(c, "stok", m.ID, "buat", nil)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Mutasi tercatat, tetapi gagal memperbarui harga pokok produk; harga akan disinkronkan pada penerimaan berikutnya",
+				"id":      m.ID,
+				"error":   err.Error(),
+			})
+		}
+	}
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Mutasi stok berhasil dibuat", "id": m.ID})
 }
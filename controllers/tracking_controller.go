@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"backend/policy"
+	"backend/repository"
+	"backend/utils"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Link tracking publik untuk dibagikan ke pelanggan (TRACKING_BASE_URL opsional, mis. domain frontend)
+func trackingLink(nonce string) fiber.Map {
+	if nonce == "" {
+		return fiber.Map{"tracking_token": "", "tracking_url": ""}
+	}
+	token, err := utils.TrackingTokenFromNonce(nonce)
+	if err != nil {
+		return fiber.Map{"tracking_token": "", "tracking_url": ""}
+	}
+	base := strings.TrimRight(os.Getenv("TRACKING_BASE_URL"), "/")
+	return fiber.Map{"tracking_token": token, "tracking_url": base + "/track/" + token}
+}
+
+// GET /track/:token (publik, tanpa JWT)
+// Hanya mengembalikan status, nama depan driver dan timeline status pengiriman;
+// ID internal, harga dan data aktor tidak ditampilkan.
+func TrackPengiriman(c *fiber.Ctx) error {
+	nonce, err := utils.VerifyTrackingToken(c.Params("token"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Pengiriman tidak ditemukan"})
+	}
+	p, err := repository.GetPengirimanByTrackingNonce(nonce)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Pengiriman tidak ditemukan"})
+	}
+
+	driver := ""
+	if u, err := repository.GetKaryawanByID(p.DriverID); err == nil && u != nil {
+		if parts := strings.Fields(u.Nama); len(parts) > 0 {
+			driver = parts[0]
+		}
+	}
+	timeline := make([]fiber.Map, 0, len(p.RiwayatStatus))
+	for _, l := range p.RiwayatStatus {
+		if l.Entitas != "pengiriman" {
+			continue
+		}
+		timeline = append(timeline, fiber.Map{"status": l.Ke, "waktu": l.Waktu})
+	}
+	return c.JSON(fiber.Map{
+		"status":    p.Status,
+		"driver":    driver,
+		"kendaraan": p.Jenis,
+		"dibuat_at": p.CreatedAt,
+		"timeline":  timeline,
+	})
+}
+
+// POST /pengiriman/:id/tracking (kasir pemilik transaksi): buat ulang token, token lama tidak berlaku
+func RegenerateTrackingPengiriman(c *fiber.Ctx) error {
+	p, err := repository.GetPengirimanByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Data tidak ditemukan"})
+	}
+	// IMPORTANT: kasir hanya boleh mengelola pengiriman untuk transaksi miliknya sendiri
+	if ok, err := izinkanAkses(c, policy.Pengiriman, policy.Ubah, p); !ok {
+		return tolakAkses(c, err)
+	}
+	nonce, err := utils.GenerateTrackingNonce()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal membuat token tracking"})
+	}
+	if err := repository.SetPengirimanTrackingNonce(p.ID, nonce); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal menyimpan token tracking", "error": err.Error()})
+	}
+	return c.JSON(trackingLink(nonce))
+}
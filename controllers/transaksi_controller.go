@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"backend/models"
+	"backend/policy"
 	"backend/repository"
 	"fmt"
 	"strings"
@@ -13,13 +14,10 @@ import (
 
 // GET /transaksi (admin semua; kasir hanya miliknya)
 func ListTransaksi(c *fiber.Ctx) error {
-	role, _ := c.Locals("userRole").(string)
-	userID, _ := c.Locals("userID").(string)
-	filter := bson.M{}
-	if role != "admin" {
-		// IMPORTANT: kasir hanya boleh melihat transaksi miliknya sendiri
-		// (field DB: kasir_id  diperlakukan sebagai created_by)
-		filter["kasir_id"] = userID
+	// IMPORTANT: kasir hanya boleh melihat transaksi miliknya sendiri (lihat package policy)
+	filter, err := scopeAkses(c, policy.Transaksi)
+	if err != nil {
+		return tolakAkses(c, err)
 	}
 	list, err := repository.ListTransaksi(filter, 0, 0)
 	if err != nil {
@@ -31,31 +29,20 @@ func ListTransaksi(c *fiber.Ctx) error {
 // GET /transaksi/:id (admin; kasir hanya jika miliknya)
 func GetTransaksiByID(c *fiber.Ctx) error {
 	id := c.Params("id")
-	role, _ := c.Locals("userRole").(string)
-	userID, _ := c.Locals("userID").(string)
 	t, err := repository.GetTransaksiByID(id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Transaksi tidak ditemukan"})
 	}
 	// IMPORTANT: kasir hanya boleh melihat transaksi miliknya sendiri
-	if role == "kasir" && t.KasirID != userID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses ditolak"})
-	}
-	// FINAL RULE: selain admin/kasir tidak boleh akses transaksi
-	if role != "admin" && role != "kasir" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses ditolak"})
+	if ok, err := izinkanAkses(c, policy.Transaksi, policy.Lihat, t); !ok {
+		return tolakAkses(c, err)
 	}
 	return c.JSON(t)
 }
 
 // POST /transaksi (admin+kasir)
 func CreateTransaksi(c *fiber.Ctx) error {
-	role, _ := c.Locals("userRole").(string)
 	userID, _ := c.Locals("userID").(string)
-	if role != "kasir" {
-		// Admin/gudang/driver ditolak untuk create transaksi
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses ditolak"})
-	}
 
 	var body struct {
 		PelangganID string `json:"pelanggan_id"`
@@ -111,14 +98,22 @@ func CreateTransaksi(c *fiber.Ctx) error {
 		Items:       []models.TransaksiItem{},
 	}
 
+	// HPP per unit di-snapshot saat penjualan sesuai metode di pengaturan laporan
+	pengaturan, err := repository.GetPengaturanLaporan()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal membaca pengaturan laporan"})
+	}
+
 	var totalProduk int
 	var totalHarga float64
 	for _, it := range body.Items {
 		p := produkCache[it.ProdukID]
 		harga := float64(0)
+		hargaBeli := float64(0)
 		nama := ""
 		if p != nil {
 			harga = p.HargaJual
+			hargaBeli = p.HargaPokok(pengaturan.MetodeHPP)
 			nama = p.NamaProduk
 		}
 		qty := it.Jumlah
@@ -127,6 +122,7 @@ func CreateTransaksi(c *fiber.Ctx) error {
 			NamaProduk: nama,
 			Jumlah:     qty,
 			Harga:      harga,
+			HargaBeli:  hargaBeli,
 		}
 		t.Items = append(t.Items, item)
 		totalProduk += qty
@@ -169,7 +165,7 @@ func CreateTransaksi(c *fiber.Ctx) error {
 				CreatedAt:  time.Now(),
 			}
 			// Abaikan error agar transaksi tetap sukses. Log di repository jika perlu.
-			_, _ = repository.CreateMutasi(m)
+			_ = buatMutasiDenganAudit(c, m)
 		}
 	}
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Transaksi berhasil dibuat", "id": t.ID})
@@ -178,18 +174,13 @@ func CreateTransaksi(c *fiber.Ctx) error {
 // PUT /transaksi/:id (admin+kasir; kasir hanya miliknya)
 func UpdateTransaksi(c *fiber.Ctx) error {
 	id := c.Params("id")
-	role, _ := c.Locals("userRole").(string)
-	userID, _ := c.Locals("userID").(string)
-	if role != "kasir" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses ditolak"})
-	}
 	t, err := repository.GetTransaksiByID(id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Transaksi tidak ditemukan"})
 	}
-	if t.KasirID != userID {
-		// IMPORTANT: kasir tidak boleh update transaksi kasir lain
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses ditolak"})
+	// IMPORTANT: kasir tidak boleh update transaksi kasir lain
+	if ok, err := izinkanAkses(c, policy.Transaksi, policy.Ubah, t); !ok {
+		return tolakAkses(c, err)
 	}
 	var body struct {
 		Status string `json:"status"`
@@ -221,7 +212,7 @@ func UpdateTransaksi(c *fiber.Ctx) error {
 				Keterangan: "batal",
 				CreatedAt:  time.Now(),
 			}
-			_, _ = repository.CreateMutasi(m)
+			_ = buatMutasiDenganAudit(c, m)
 		}
 	}
 	return c.JSON(fiber.Map{"message": "Transaksi berhasil diupdate"})
@@ -230,18 +221,13 @@ func UpdateTransaksi(c *fiber.Ctx) error {
 // DELETE /transaksi/:id (admin+kasir; kasir hanya miliknya)
 func DeleteTransaksi(c *fiber.Ctx) error {
 	id := c.Params("id")
-	role, _ := c.Locals("userRole").(string)
-	userID, _ := c.Locals("userID").(string)
-	if role != "kasir" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses ditolak"})
-	}
 	t, err := repository.GetTransaksiByID(id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Transaksi tidak ditemukan"})
 	}
-	if t.KasirID != userID {
-		// IMPORTANT: kasir tidak boleh hapus transaksi kasir lain
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses ditolak"})
+	// IMPORTANT: kasir tidak boleh hapus transaksi kasir lain
+	if ok, err := izinkanAkses(c, policy.Transaksi, policy.Hapus, t); !ok {
+		return tolakAkses(c, err)
 	}
 	if _, err := repository.DeleteTransaksi(id); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal hapus transaksi"})
@@ -264,7 +250,7 @@ func DeleteTransaksi(c *fiber.Ctx) error {
 				Keterangan: "hapus",
 				CreatedAt:  time.Now(),
 			}
-			_, _ = repository.CreateMutasi(m)
+			_ = buatMutasiDenganAudit(c, m)
 		}
 	}
 	return c.JSON(fiber.Map{"message": "Transaksi berhasil dihapus"})
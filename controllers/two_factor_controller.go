@@ -0,0 +1,377 @@
+package controllers
+
+import (
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	loginChallengeTTL  = 5 * time.Minute
+	loginChallengeMaks = 5 // percobaan kode per interim token
+	jumlahRecoveryCode = 10
+)
+
+// Nama penerbit yang tampil di aplikasi authenticator (env TOTP_ISSUER), default "Backend MBG"
+func totpIssuer() string {
+	if v := os.Getenv("TOTP_ISSUER"); v != "" {
+		return v
+	}
+	return "Backend MBG"
+}
+
+// buatLoginChallenge menerbitkan interim token setelah password benar pada akun ber-2FA
+func buatLoginChallenge(user *models.User) (fiber.Map, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	ch := models.LoginChallenge{
+		ID:        utils.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: now.Add(loginChallengeTTL),
+		CreatedAt: now,
+	}
+	if err := repository.CreateLoginChallenge(&ch); err != nil {
+		return nil, err
+	}
+	return fiber.Map{
+		"message":       "Masukkan kode 2FA",
+		"2fa_required":  true,
+		"interim_token": token,
+		"expires_in":    int(loginChallengeTTL.Seconds()),
+	}, nil
+}
+
+// verifikasiFaktorKedua menerima kode TOTP atau kode pemulihan (sekali pakai)
+func verifikasiFaktorKedua(user *models.User, kode, recoveryCode string) (bool, error) {
+	if kode != "" {
+		step, ok := utils.VerifyTOTP(user.TOTPSecret, kode, time.Now())
+		if !ok {
+			return false, nil
+		}
+		// Kode yang sama tidak boleh dipakai dua kali dalam periodenya
+		return repository.PakaiTOTPStep(user.ID, step)
+	}
+	if recoveryCode != "" {
+		return repository.PakaiRecoveryCode(user.ID, utils.HashToken(utils.NormalizeRecoveryCode(recoveryCode)))
+	}
+	return false, nil
+}
+
+// buatRecoveryCodes mengembalikan kode plaintext (ditampilkan sekali) dan hash-nya untuk disimpan
+func buatRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(jumlahRecoveryCode)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashToken(code)
+	}
+	return codes, hashes, nil
+}
+
+// Login2FA godoc
+//
+//	@Summary		Login langkah kedua (2FA)
+//	@Description	Tukar interim token + kode TOTP (atau recovery_code) dengan pasangan token
+//	@Tags			Authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		object{interim_token=string,kode=string,recovery_code=string}	true	"Interim token & kode"
+//	@Success		200		{object}	map[string]interface{}
+//	@Failure		401		{object}	map[string]interface{}	"Kode salah / interim token tidak berlaku"
+//	@Router			/auth/login/2fa [post]
+func Login2FA(c *fiber.Ctx) error {
+	var body struct {
+		InterimToken string `json:"interim_token"`
+		Kode         string `json:"kode"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.InterimToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "interim_token wajib"})
+	}
+	hash := utils.HashToken(body.InterimToken)
+	ch, err := repository.HitLoginChallenge(hash, loginChallengeMaks)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Sesi login 2FA tidak berlaku, silakan login ulang"})
+	}
+	user, err := repository.FindUserByID(ch.UserID)
+	if err != nil || user.Status != "aktif" || !user.TOTPAktif {
+		_ = repository.DeleteLoginChallenge(hash)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Sesi login 2FA tidak berlaku, silakan login ulang"})
+	}
+	if user.TerkunciSampai != nil && time.Now().Before(*user.TerkunciSampai) {
+		_ = repository.DeleteLoginChallenge(hash)
+		catatLoginAttempt(c, user.Email, user.ID, false, "terkunci")
+		return c.Status(fiber.StatusLocked).JSON(fiber.Map{
+			"error":           "Akun terkunci sementara karena terlalu banyak login gagal",
+			"terkunci_sampai": user.TerkunciSampai,
+		})
+	}
+
+	ok, err := verifikasiFaktorKedua(user, body.Kode, body.RecoveryCode)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal verifikasi kode"})
+	}
+	if !ok {
+		// Kode salah dihitung sama seperti password salah (backoff & lockout)
+		catatLoginGagal(c.IP(), user.Email)
+		if n, err := repository.IncrementUserGagalLogin(user.ID); err == nil && n >= loginMaxGagal() {
+			_ = repository.LockUser(user.ID, time.Now().Add(loginLockDurasi()))
+		}
+		catatLoginAttempt(c, user.Email, user.ID, false, "2fa_salah")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Kode 2FA salah"})
+	}
+
+	_ = repository.DeleteLoginChallenge(hash)
+	return selesaikanLogin(c, user, user.Email)
+}
+
+// Get2FAStatus godoc
+//
+//	@Summary		Status 2FA
+//	@Tags			2FA
+//	@Security		BearerAuth
+//	@Produce		json
+//	@Success		200	{object}	map[string]interface{}
+//	@Router			/auth/2fa [get]
+func Get2FAStatus(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	user, err := repository.FindUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "User tidak ditemukan"})
+	}
+	wajib := false
+	if user.Role == "admin" {
+		if p, err := repository.GetPengaturanKeamanan(); err == nil {
+			wajib = p.Wajib2FAAdmin
+		}
+	}
+	return c.JSON(fiber.Map{
+		"aktif":               user.TOTPAktif,
+		"wajib":               wajib,
+		"sisa_recovery_codes": len(user.RecoveryCodes),
+	})
+}
+
+// Setup2FA godoc
+//
+//	@Summary		Mulai pendaftaran 2FA
+//	@Description	Membuat secret TOTP baru (belum aktif) dan URI otpauth:// untuk QR code
+//	@Tags			2FA
+//	@Security		BearerAuth
+//	@Produce		json
+//	@Success		200	{object}	map[string]interface{}
+//	@Failure		409	{object}	map[string]interface{}	"2FA sudah aktif"
+//	@Router			/auth/2fa/setup [post]
+func Setup2FA(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	user, err := repository.FindUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "User tidak ditemukan"})
+	}
+	if user.TOTPAktif {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "2FA sudah aktif"})
+	}
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal membuat secret"})
+	}
+	if err := repository.SetTOTPPending(userID, secret); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal menyimpan secret"})
+	}
+	return c.JSON(fiber.Map{
+		"secret":      secret,
+		"otpauth_uri": utils.TOTPProvisioningURI(totpIssuer(), user.Email, secret),
+		"message":     "Pindai QR lalu konfirmasi dengan kode pertama di POST /auth/2fa/aktifkan",
+	})
+}
+
+// Aktifkan2FA godoc
+//
+//	@Summary		Aktifkan 2FA
+//	@Description	Konfirmasi secret dari /auth/2fa/setup dengan kode TOTP; mengembalikan recovery code (ditampilkan sekali)
+//	@Tags			2FA
+//	@Security		BearerAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		object{kode=string}	true	"Kode TOTP"
+//	@Success		200		{object}	map[string]interface{}
+//	@Failure		400		{object}	map[string]interface{}
+//	@Router			/auth/2fa/aktifkan [post]
+func Aktifkan2FA(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	var body struct {
+		Kode string `json:"kode"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Kode == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "kode wajib"})
+	}
+	user, err := repository.FindUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "User tidak ditemukan"})
+	}
+	if user.TOTPAktif {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "2FA sudah aktif"})
+	}
+	if user.TOTPPending == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Jalankan /auth/2fa/setup terlebih dahulu"})
+	}
+	step, ok := utils.VerifyTOTP(user.TOTPPending, body.Kode, time.Now())
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Kode 2FA salah"})
+	}
+	codes, hashes, err := buatRecoveryCodes()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal membuat recovery code"})
+	}
+	if err := repository.AktifkanTOTP(userID, user.TOTPPending, step, hashes); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal mengaktifkan 2FA"})
+	}
+	return c.JSON(fiber.Map{
+		"message":        "2FA berhasil diaktifkan. Simpan recovery code di tempat aman.",
+		"recovery_codes": codes,
+	})
+}
+
+// Nonaktifkan2FA godoc
+//
+//	@Summary		Nonaktifkan 2FA
+//	@Description	Butuh password dan kode TOTP/recovery code. Ditolak untuk admin bila kebijakan mewajibkan 2FA.
+//	@Tags			2FA
+//	@Security		BearerAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		object{password=string,kode=string,recovery_code=string}	true	"Verifikasi"
+//	@Success		200		{object}	map[string]interface{}
+//	@Failure		401		{object}	map[string]interface{}
+//	@Failure		403		{object}	map[string]interface{}	"Diwajibkan kebijakan"
+//	@Failure		429		{object}	map[string]interface{}	"Terlalu banyak percobaan"
+//	@Router			/auth/2fa/nonaktifkan [post]
+func Nonaktifkan2FA(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	var body struct {
+		Password     string `json:"password"`
+		Kode         string `json:"kode"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Request tidak valid"})
+	}
+	user, err := repository.FindUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "User tidak ditemukan"})
+	}
+	if !user.TOTPAktif {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "2FA belum aktif"})
+	}
+	if user.Role == "admin" {
+		if p, err := repository.GetPengaturanKeamanan(); err == nil && p.Wajib2FAAdmin {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Kebijakan mewajibkan 2FA untuk admin"})
+		}
+	}
+	// Backoff & penghitung gagal sama seperti login agar kode tidak bisa ditebak berulang
+	if sisa := loginDibatasi(c.IP(), user.Email); sisa > 0 {
+		return tolakLoginDibatasi(c, sisa)
+	}
+	if !utils.CheckPasswordHash(body.Password, user.Password) {
+		catatLoginGagal(c.IP(), user.Email)
+		catatLoginAttempt(c, user.Email, user.ID, false, "password_salah")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Password salah"})
+	}
+	ok, err := verifikasiFaktorKedua(user, body.Kode, body.RecoveryCode)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal verifikasi kode"})
+	}
+	if !ok {
+		catatLoginGagal(c.IP(), user.Email)
+		catatLoginAttempt(c, user.Email, user.ID, false, "2fa_salah")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Kode 2FA salah"})
+	}
+	if err := repository.NonaktifkanTOTP(userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal menonaktifkan 2FA"})
+	}
+	return c.JSON(fiber.Map{"message": "2FA berhasil dinonaktifkan"})
+}
+
+// RegenerateRecoveryCodes godoc
+//
+//	@Summary		Buat ulang recovery code
+//	@Description	Mengganti semua recovery code lama. Butuh kode TOTP.
+//	@Tags			2FA
+//	@Security		BearerAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		object{kode=string}	true	"Kode TOTP"
+//	@Success		200		{object}	map[string]interface{}
+//	@Failure		429		{object}	map[string]interface{}	"Terlalu banyak percobaan"
+//	@Router			/auth/2fa/recovery-codes [post]
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	var body struct {
+		Kode string `json:"kode"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Kode == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "kode wajib"})
+	}
+	user, err := repository.FindUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "User tidak ditemukan"})
+	}
+	if !user.TOTPAktif {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "2FA belum aktif"})
+	}
+	if sisa := loginDibatasi(c.IP(), user.Email); sisa > 0 {
+		return tolakLoginDibatasi(c, sisa)
+	}
+	ok, err := verifikasiFaktorKedua(user, body.Kode, "")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal verifikasi kode"})
+	}
+	if !ok {
+		catatLoginGagal(c.IP(), user.Email)
+		catatLoginAttempt(c, user.Email, user.ID, false, "2fa_salah")
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Kode 2FA salah"})
+	}
+	codes, hashes, err := buatRecoveryCodes()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal membuat recovery code"})
+	}
+	if err := repository.SetRecoveryCodes(userID, hashes); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal menyimpan recovery code"})
+	}
+	return c.JSON(fiber.Map{"recovery_codes": codes})
+}
+
+// Reset2FAKaryawan godoc
+//
+//	@Summary		Reset 2FA karyawan
+//	@Description	Admin mematikan 2FA karyawan yang kehilangan perangkat & recovery code; semua sesinya dicabut
+//	@Tags			Karyawan
+//	@Security		BearerAuth
+//	@Produce		json
+//	@Param			id	path		string	true	"ID Karyawan"
+//	@Success		200	{object}	map[string]interface{}
+//	@Failure		403	{object}	map[string]interface{}	"Target di luar kasir/gudang/driver tanpa role:kelola"
+//	@Router			/users/karyawan/{id}/reset-2fa [post]
+func Reset2FAKaryawan(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if _, err := ambilKaryawanDikelola(c, id); err != nil {
+		return tolakKaryawan(c, err)
+	}
+	if id == c.Locals("userID") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Gunakan /auth/2fa/nonaktifkan untuk akun sendiri"})
+	}
+	if err := repository.NonaktifkanTOTP(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal reset 2FA"})
+	}
+	_ = repository.RevokeAllUserTokens(id)
+	return c.JSON(fiber.Map{"message": "2FA karyawan berhasil direset", "id": id})
+}
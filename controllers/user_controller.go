@@ -1,17 +1,59 @@
 package controllers
 
 import (
+	"backend/middleware"
 	"backend/models"
 	"backend/repository"
+	"backend/utils"
 	"errors"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// roleKaryawan adalah role yang dibuat & dikelola lewat endpoint karyawan
+func roleKaryawan(role string) bool {
+	return role == "kasir" || role == "gudang" || role == "driver"
+}
+
+// ambilKaryawanDikelola mengambil target perubahan karyawan. Akun di luar kasir/gudang/driver
+// (mis. admin) hanya boleh diubah pemegang role:kelola: karyawan:write bisa didelegasikan
+// lewat RBAC, dan reset password mengembalikan password sementara ke pemanggil.
+func ambilKaryawanDikelola(c *fiber.Ctx, id string) (*models.User, error) {
+	user, err := repository.GetKaryawanByID(id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Karyawan tidak ditemukan")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if roleKaryawan(user.Role) {
+		return user, nil
+	}
+	boleh, err := middleware.PunyaPermission(c, models.PermRoleKelola)
+	if err != nil {
+		return nil, err
+	}
+	if !boleh {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Mengelola akun "+user.Role+" butuh permission "+models.PermRoleKelola)
+	}
+	return user, nil
+}
+
+// tolakKaryawan menerjemahkan error ambilKaryawanDikelola ke respons
+func tolakKaryawan(c *fiber.Ctx, err error) error {
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		return c.Status(fe.Code).JSON(fiber.Map{"message": fe.Message})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal membaca data karyawan", "error": err.Error()})
+}
+
 // GetAllDrivers godoc
 //
 //	@Summary		Get all drivers
@@ -48,11 +90,6 @@ func GetAllDrivers(c *fiber.Ctx) error {
 //
 // CRUD Karyawan (admin only)
 func GetAllKaryawan(c *fiber.Ctx) error {
-	role, _ := c.Locals("userRole").(string)
-	// Allow admin and driver to view karyawan list (read-only)
-	if role != "admin" && role != "driver" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses hanya untuk admin atau driver"})
-	}
 	users, err := repository.GetAllKaryawan()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -77,9 +114,6 @@ func GetAllKaryawan(c *fiber.Ctx) error {
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/users/karyawan/{id} [get]
 func GetKaryawanByID(c *fiber.Ctx) error {
-	if c.Locals("userRole") != "admin" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses hanya untuk admin"})
-	}
 	id := c.Params("id")
 	user, err := repository.GetKaryawanByID(id)
 	if err != nil {
@@ -103,9 +137,6 @@ func GetKaryawanByID(c *fiber.Ctx) error {
 //	@Failure		500		{object}	map[string]interface{}	"Internal Server Error"
 //	@Router			/users/karyawan [post]
 func CreateKaryawan(c *fiber.Ctx) error {
-	if c.Locals("userRole") != "admin" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses hanya untuk admin"})
-	}
 	var user models.User
 	if err := c.BodyParser(&user); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Request tidak valid"})
@@ -133,7 +164,7 @@ func CreateKaryawan(c *fiber.Ctx) error {
 	}
 
 	// Validasi role yang bisa dibuat: kasir, gudang, driver
-	if user.Role != "kasir" && user.Role != "gudang" && user.Role != "driver" {
+	if !roleKaryawan(user.Role) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Role harus kasir, gudang, atau driver"})
 	}
 
@@ -153,6 +184,9 @@ func CreateKaryawan(c *fiber.Ctx) error {
 	}
 	// Hash password
 	if user.Password != "" {
+		if err := utils.ValidasiPassword(user.Password); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+		}
 		hashed, err := bcrypt.GenerateFromPassword([]byte(user.Password), 12)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal hash password"})
@@ -172,7 +206,7 @@ func CreateKaryawan(c *fiber.Ctx) error {
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal menambah karyawan"})
 	}
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Karyawan berhasil ditambah"})
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Karyawan berhasil ditambah", "id": user.ID})
 }
 
 // UpdateKaryawan godoc
@@ -191,10 +225,11 @@ func CreateKaryawan(c *fiber.Ctx) error {
 //	@Failure		500		{object}	map[string]interface{}	"Internal Server Error"
 //	@Router			/users/karyawan/{id} [put]
 func UpdateKaryawan(c *fiber.Ctx) error {
-	if c.Locals("userRole") != "admin" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses hanya untuk admin"})
-	}
 	id := c.Params("id")
+	existing, err := ambilKaryawanDikelola(c, id)
+	if err != nil {
+		return tolakKaryawan(c, err)
+	}
 	var user models.User
 	if err := c.BodyParser(&user); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Request tidak valid"})
@@ -202,14 +237,39 @@ func UpdateKaryawan(c *fiber.Ctx) error {
 
 	user.Email = strings.TrimSpace(user.Email)
 	user.Nama = strings.TrimSpace(user.Nama)
-	if user.Email != "" {
+	// Field kosong berarti tidak diubah (tidak menimpa data lama dengan "")
+	if user.Role == "" {
+		user.Role = existing.Role
+	}
+	if user.Status == "" {
+		user.Status = existing.Status
+	}
+	if user.Role != existing.Role {
+		// Perubahan role hanya ke kasir/gudang/driver dan butuh role:kelola, agar karyawan:write
+		// tidak bisa menaikkan akun (termasuk dirinya sendiri) menjadi admin
+		if !roleKaryawan(user.Role) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Role harus kasir, gudang, atau driver"})
+		}
+		boleh, err := middleware.PunyaPermission(c, models.PermRoleKelola)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal memeriksa hak akses"})
+		}
+		if !boleh {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Mengubah role karyawan butuh permission " + models.PermRoleKelola})
+		}
+	}
+	if user.Email == "" {
+		user.Email = existing.Email
+	} else {
 		if exists, err := repository.ExistsUserByEmail(user.Email, id); err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal validasi email"})
 		} else if exists {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Email sudah digunakan"})
 		}
 	}
-	if user.Nama != "" {
+	if user.Nama == "" {
+		user.Nama = existing.Nama
+	} else {
 		if exists, err := repository.ExistsUserByNama(user.Nama, id); err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal validasi nama"})
 		} else if exists {
@@ -218,13 +278,16 @@ func UpdateKaryawan(c *fiber.Ctx) error {
 	}
 	// Hash password jika diupdate
 	if user.Password != "" {
+		if err := utils.ValidasiPassword(user.Password); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+		}
 		hashed, err := bcrypt.GenerateFromPassword([]byte(user.Password), 12)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal hash password"})
 		}
 		user.Password = string(hashed)
 	}
-	_, err := repository.UpdateKaryawan(id, user)
+	_, err = repository.UpdateKaryawan(id, user)
 	if err != nil {
 		var we mongo.WriteException
 		if errors.As(err, &we) {
@@ -236,6 +299,12 @@ func UpdateKaryawan(c *fiber.Ctx) error {
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal update karyawan"})
 	}
+	// Role berubah atau akun dinonaktifkan: token lama langsung tidak berlaku
+	if user.Role != existing.Role || (user.Status != "aktif" && existing.Status == "aktif") {
+		if err := repository.RevokeAllUserTokens(id); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Karyawan diupdate, tetapi gagal mencabut token", "error": err.Error()})
+		}
+	}
 	return c.JSON(fiber.Map{"message": "Karyawan berhasil diupdate"})
 }
 
@@ -266,7 +335,7 @@ func RegisterKaryawan(c *fiber.Ctx) error {
 	}
 
 	// Validasi role yang bisa dibuat: kasir, gudang, driver
-	if user.Role != "kasir" && user.Role != "gudang" && user.Role != "driver" {
+	if !roleKaryawan(user.Role) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Role harus kasir, gudang, atau driver"})
 	}
 
@@ -284,6 +353,9 @@ func RegisterKaryawan(c *fiber.Ctx) error {
 	user.Status = "aktif"
 	// Hash password
 	if user.Password != "" {
+		if err := utils.ValidasiPassword(user.Password); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+		}
 		hashed, err := bcrypt.GenerateFromPassword([]byte(user.Password), 12)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal hash password"})
@@ -303,7 +375,7 @@ func RegisterKaryawan(c *fiber.Ctx) error {
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal register karyawan"})
 	}
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Register karyawan berhasil"})
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Register karyawan berhasil", "id": user.ID})
 }
 
 // DeleteKaryawan godoc
@@ -319,14 +391,16 @@ func RegisterKaryawan(c *fiber.Ctx) error {
 //	@Failure		500	{object}	map[string]interface{}	"Internal Server Error"
 //	@Router			/users/karyawan/{id} [delete]
 func DeleteKaryawan(c *fiber.Ctx) error {
-	if c.Locals("userRole") != "admin" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses hanya untuk admin"})
-	}
 	id := c.Params("id")
+	if _, err := ambilKaryawanDikelola(c, id); err != nil {
+		return tolakKaryawan(c, err)
+	}
 	_, err := repository.DeleteKaryawan(id)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal hapus karyawan"})
 	}
+	// Sesi user yang dihapus ikut dicabut
+	_ = repository.RevokeRefreshTokensByUser(id)
 	return c.JSON(fiber.Map{"message": "Karyawan berhasil dihapus"})
 }
 
@@ -347,9 +421,6 @@ func DeleteKaryawan(c *fiber.Ctx) error {
 //	@Failure		500		{object}	map[string]interface{}	"Internal Server Error"
 //	@Router			/users/karyawan/{id}/status [patch]
 func UpdateKaryawanStatus(c *fiber.Ctx) error {
-	if c.Locals("userRole") != "admin" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Akses hanya untuk admin"})
-	}
 
 	id := c.Params("id")
 	if id == "" {
@@ -368,10 +439,9 @@ func UpdateKaryawanStatus(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Status harus 'aktif' atau 'nonaktif'"})
 	}
 
-	// Cek apakah karyawan ada terlebih dahulu
-	_, err := repository.GetKaryawanByID(id)
-	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Karyawan tidak ditemukan"})
+	// Cek apakah karyawan ada & boleh dikelola terlebih dahulu
+	if _, err := ambilKaryawanDikelola(c, id); err != nil {
+		return tolakKaryawan(c, err)
 	}
 
 	// Update status
@@ -382,6 +452,16 @@ func UpdateKaryawanStatus(c *fiber.Ctx) error {
 		})
 	}
 
+	// Nonaktif: cabut semua token agar akses langsung terputus
+	if body.Status == "nonaktif" {
+		if err := repository.RevokeAllUserTokens(id); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Status diupdate, tetapi gagal mencabut token",
+				"error":   err.Error(),
+			})
+		}
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "Status karyawan berhasil diupdate",
 		"id":      id,
@@ -407,3 +487,143 @@ func GetActiveKaryawan(c *fiber.Ctx) error {
 	}
 	return c.JSON(users)
 }
+
+// UnlockKaryawan godoc
+//
+//	@Summary		Buka kunci akun karyawan
+//	@Description	Menghapus lockout login & hitungan login gagal (admin only)
+//	@Tags			Karyawan
+//	@Security		BearerAuth
+//	@Produce		json
+//	@Param			id	path		string	true	"ID Karyawan"
+//	@Success		200	{object}	map[string]interface{}
+//	@Failure		404	{object}	map[string]interface{}	"Karyawan tidak ditemukan"
+//	@Failure		403	{object}	map[string]interface{}	"Target di luar kasir/gudang/driver tanpa role:kelola"
+//	@Router			/users/karyawan/{id}/unlock [post]
+func UnlockKaryawan(c *fiber.Ctx) error {
+	id := c.Params("id")
+	user, err := ambilKaryawanDikelola(c, id)
+	if err != nil {
+		return tolakKaryawan(c, err)
+	}
+	if err := repository.UnlockUser(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal membuka kunci akun", "error": err.Error()})
+	}
+	// Jeda backoff per email ikut dihapus agar karyawan bisa langsung login
+	_ = repository.DeleteLoginThrottle(loginKeyEmail(user.Email))
+	return c.JSON(fiber.Map{"message": "Akun karyawan berhasil dibuka", "id": id})
+}
+
+// GetLoginAttempts godoc
+//
+//	@Summary		Log percobaan login
+//	@Description	Daftar percobaan login (berhasil/gagal) terbaru dulu (admin only)
+//	@Tags			Karyawan
+//	@Security		BearerAuth
+//	@Produce		json
+//	@Param			email		query		string	false	"Email"
+//	@Param			user_id		query		string	false	"ID user"
+//	@Param			ip			query		string	false	"Alamat IP"
+//	@Param			berhasil	query		bool	false	"true/false"
+//	@Param			start		query		string	false	"Tanggal mulai (YYYY-MM-DD)"
+//	@Param			end			query		string	false	"Tanggal akhir (YYYY-MM-DD)"
+//	@Param			page		query		int		false	"Halaman (default 1)"
+//	@Param			page_size	query		int		false	"Jumlah per halaman (default 50, maks 200)"
+//	@Success		200			{object}	map[string]interface{}
+//	@Router			/users/login-attempts [get]
+func GetLoginAttempts(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if v := strings.ToLower(strings.TrimSpace(c.Query("email"))); v != "" {
+		filter["email"] = v
+	}
+	if v := c.Query("user_id"); v != "" {
+		filter["user_id"] = v
+	}
+	if v := c.Query("ip"); v != "" {
+		filter["ip"] = v
+	}
+	if v := c.Query("berhasil"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "berhasil harus true/false"})
+		}
+		filter["berhasil"] = b
+	}
+	dateFilter, err := buildCreatedAtFilterFromQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	if v, ok := dateFilter["created_at"]; ok {
+		filter["waktu"] = v
+	}
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 50
+	}
+	list, total, err := repository.ListLoginAttempts(filter, page, pageSize)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal mengambil log login"})
+	}
+	return c.JSON(fiber.Map{
+		"data":      list,
+		"page":      page,
+		"page_size": pageSize,
+		"total":     total,
+	})
+}
+
+// ResetPasswordKaryawan godoc
+//
+//	@Summary		Reset password karyawan
+//	@Description	Admin menetapkan password sementara; karyawan wajib menggantinya saat login berikutnya dan semua sesinya dicabut. Jika password kosong, dibuatkan acak.
+//	@Tags			Karyawan
+//	@Security		BearerAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string					true	"ID Karyawan"
+//	@Param			body	body		object{password=string}	false	"Password sementara (opsional)"
+//	@Success		200		{object}	map[string]interface{}
+//	@Failure		404		{object}	map[string]interface{}	"Karyawan tidak ditemukan"
+//	@Failure		403	{object}	map[string]interface{}	"Target di luar kasir/gudang/driver tanpa role:kelola"
+//	@Router			/users/karyawan/{id}/reset-password [post]
+func ResetPasswordKaryawan(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if _, err := ambilKaryawanDikelola(c, id); err != nil {
+		return tolakKaryawan(c, err)
+	}
+	var body struct {
+		Password string `json:"password"`
+	}
+	_ = c.BodyParser(&body)
+	sementara := body.Password
+	if sementara == "" {
+		acak, err := utils.PasswordSementara()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal membuat password sementara"})
+		}
+		sementara = acak
+	}
+	if err := utils.ValidasiPassword(sementara); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+	}
+	hashed, err := utils.HashPassword(sementara)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal hash password"})
+	}
+	if err := repository.UpdatePassword(id, hashed, true); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Gagal reset password"})
+	}
+	if err := repository.RevokeAllUserTokens(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": "Password direset, tetapi gagal mencabut token", "error": err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"message":            "Password karyawan berhasil direset",
+		"id":                 id,
+		"password_sementara": sementara,
+	})
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"backend/config"
+	"backend/controllers"
 	_ "backend/docs" // Import docs for swagger
 	"backend/middleware"
 	"backend/repository"
 	"backend/routes"
+	"backend/utils"
+	"flag"
 	"log"
 	"os"
 	"strings"
@@ -52,6 +55,10 @@ import (
 //	@description				Type "Bearer" followed by a space and JWT token.
 
 func main() {
+	// -bootstrap-admin: buat admin pertama (dari env BOOTSTRAP_ADMIN_*) lalu keluar
+	bootstrapOnly := flag.Bool("bootstrap-admin", false, "buat admin pertama dari env BOOTSTRAP_ADMIN_* jika belum ada user, lalu keluar")
+	flag.Parse()
+
 	// Load file .env (tidak fatal jika gagal, agar bisa jalan di Railway)
 	_ = godotenv.Load()
 
@@ -65,6 +72,11 @@ func main() {
 		log.Println("⚠️ JWT_SECRET tidak diset, menggunakan default untuk development")
 	}
 
+	// Tanpa SMTP di production, lupa password dinonaktifkan (token tidak boleh masuk log)
+	if err := utils.CekKonfigurasiMailer(); err != nil {
+		log.Printf("⚠️ %v: lupa password dinonaktifkan", err)
+	}
+
 	// Koneksi ke MongoDB
 	config.ConnectDB()
 
@@ -75,6 +87,25 @@ func main() {
 		log.Println("✅ Counters berhasil diinisialisasi")
 	}
 
+	// Bootstrap admin pertama: via flag -bootstrap-admin atau otomatis jika BOOTSTRAP_ADMIN_EMAIL diset
+	if *bootstrapOnly || os.Getenv("BOOTSTRAP_ADMIN_EMAIL") != "" {
+		created, err := bootstrapAdmin()
+		switch {
+		case err != nil:
+			log.Printf("⚠️ Bootstrap admin gagal: %v", err)
+		case created:
+			log.Println("✅ Admin pertama berhasil dibuat")
+		default:
+			log.Println("ℹ️ Bootstrap admin dilewati: koleksi user sudah berisi data")
+		}
+		if *bootstrapOnly {
+			if err != nil {
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	// Pastikan index kategori (unique nama)
 	if err := repository.EnsureKategoriIndexes(); err != nil {
 		log.Printf("⚠️ Gagal membuat index kategori: %v", err)
@@ -105,27 +136,101 @@ func main() {
 		log.Printf("⚠️ Gagal membuat index pengiriman: %v", err)
 	}
 
+	// Pastikan index perjalanan
+	if err := repository.EnsurePerjalananIndexes(); err != nil {
+		log.Printf("⚠️ Gagal membuat index perjalanan: %v", err)
+	}
+
+	// Pastikan index kendaraan & shift driver
+	if err := repository.EnsureKendaraanIndexes(); err != nil {
+		log.Printf("⚠️ Gagal membuat index kendaraan: %v", err)
+	}
+	if err := repository.EnsureShiftDriverIndexes(); err != nil {
+		log.Printf("⚠️ Gagal membuat index shift driver: %v", err)
+	}
+
+	// Pastikan index token (refresh & daftar cabut)
+	if err := repository.EnsureTokenIndexes(); err != nil {
+		log.Printf("⚠️ Gagal membuat index token: %v", err)
+	}
+
+	// Pastikan index log login & throttle (TTL)
+	if err := repository.EnsureLoginIndexes(); err != nil {
+		log.Printf("⚠️ Gagal membuat index login: %v", err)
+	}
+
+	// Pastikan index interim token 2FA (TTL)
+	if err := repository.EnsureTwoFactorIndexes(); err != nil {
+		log.Printf("⚠️ Gagal membuat index 2FA: %v", err)
+	}
+
+	// Isi permission default untuk role yang belum ada (RBAC)
+	if err := repository.EnsureRolePermissions(); err != nil {
+		log.Printf("⚠️ Gagal menyiapkan permission role: %v", err)
+	}
+
+	// Pastikan index shift kasir (satu shift buka per kasir)
+	if err := repository.EnsureShiftKasirIndexes(); err != nil {
+		log.Printf("⚠️ Gagal membuat index shift kasir: %v", err)
+	}
+
+	// Pastikan index audit log
+	if err := repository.EnsureAuditIndexes(); err != nil {
+		log.Printf("⚠️ Gagal membuat index audit: %v", err)
+	}
+
+	// Pastikan index API key (unique prefix)
+	if err := repository.EnsureApiKeyIndexes(); err != nil {
+		log.Printf("⚠️ Gagal membuat index API key: %v", err)
+	}
+
+	if err := repository.EnsureLaporanJobIndexes(); err != nil {
+		log.Printf("⚠️ Gagal membuat index job laporan: %v", err)
+	}
+
 	// Pastikan index user (unique email & nama)
 	if err := repository.EnsureUserIndexes(); err != nil {
 		log.Printf("⚠️ Gagal membuat index user: %v", err)
 	}
 
-	// Inisialisasi Fiber
-	app := fiber.New()
+	// Inisialisasi Fiber. Di belakang reverse proxy (mis. Railway) IP klien diambil dari
+	// PROXY_HEADER (default X-Forwarded-For), hanya untuk request dari TRUSTED_PROXIES
+	// (IP/CIDR dipisah koma). Tanpa ini semua klien terlihat ber-IP proxy dan backoff
+	// login per IP berlaku untuk semua user.
+	fiberCfg := fiber.Config{}
+	if proxies := strings.TrimSpace(os.Getenv("TRUSTED_PROXIES")); proxies != "" {
+		fiberCfg.ProxyHeader = os.Getenv("PROXY_HEADER")
+		if fiberCfg.ProxyHeader == "" {
+			fiberCfg.ProxyHeader = fiber.HeaderXForwardedFor
+		}
+		fiberCfg.EnableTrustedProxyCheck = true
+		fiberCfg.EnableIPValidation = true
+		for _, p := range strings.Split(proxies, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				fiberCfg.TrustedProxies = append(fiberCfg.TrustedProxies, p)
+			}
+		}
+	} else if appEnv == "production" {
+		log.Println("⚠️ TRUSTED_PROXIES tidak diset: IP klien dibaca dari koneksi langsung (di belakang proxy semua klien berbagi IP)")
+	}
+	app := fiber.New(fiberCfg)
 
 	// Middleware global
 	app.Use(middleware.LoggerMiddleware())
 	app.Use(middleware.CorsMiddleware())
 
-	// JWTMiddleware global, kecuali untuk /auth/login dan /auth/register
+	// JWTMiddleware global, kecuali untuk /auth/login, /auth/refresh, lupa password dan tracking publik /track/:token
 	app.Use(func(c *fiber.Ctx) error {
 		path := c.Path()
-		// NOTE: export endpoints are opened via window.open and may pass token via query.
-		// They are protected at route-level via JWTMiddlewareForExport + RoleGuard.
-		if path == "/laporan/export/excel" {
+		// Unduhan export dibuka via window.open: diautentikasi link sekali pakai, bukan JWT di URL
+		if strings.HasPrefix(path, "/laporan/export/") && c.Query("link") != "" {
+			return middleware.ExportLinkMiddleware(c)
+		}
+		switch path {
+		case "/auth/login", "/auth/login/2fa", "/auth/refresh", "/auth/forgot", "/auth/reset":
 			return c.Next()
 		}
-		if path == "/auth/login" || path == "/auth/register" || strings.HasPrefix(path, "/swagger") {
+		if strings.HasPrefix(path, "/swagger") || strings.HasPrefix(path, "/track/") {
 			return c.Next()
 		}
 		return middleware.JWTMiddleware(c)
@@ -134,9 +239,12 @@ func main() {
 	// Swagger route
 	app.Get("/swagger/*", fiberSwagger.WrapHandler)
 
-	// Semua route (termasuk auth/login/register)
+	// Semua route (termasuk auth/login)
 	routes.SetupRoutes(app)
 
+	// Worker job laporan (export besar di latar belakang)
+	controllers.StartLaporanWorker()
+
 	// Port server (default ke 5000 agar konsisten dengan frontend & docs)
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"backend/models"
+	"backend/repository"
+	"backend/utils"
+	"crypto/subtle"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// apiKeyAuth mengautentikasi request integrasi lewat header X-API-Key.
+// Request diperlakukan sebagai role "integrasi" dengan permission = scope key.
+func apiKeyAuth(c *fiber.Ctx, key string) error {
+	prefix, ok := utils.ApiKeyPrefix(key)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "API key tidak valid"})
+	}
+	k, err := repository.GetApiKeyByPrefix(prefix)
+	if err != nil || subtle.ConstantTimeCompare([]byte(k.Hash), []byte(utils.HashToken(key))) != 1 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "API key tidak valid"})
+	}
+	if k.RevokedAt != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "API key sudah dicabut"})
+	}
+	if k.ExpiresAt != nil && !time.Now().Before(*k.ExpiresAt) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "API key sudah kadaluarsa"})
+	}
+	if err := repository.TouchApiKey(k.ID, c.IP()); err != nil {
+		log.Printf("⚠️ Gagal mencatat pemakaian API key %s: %v", k.ID, err)
+	}
+
+	c.Locals("userID", k.ID)
+	c.Locals("userRole", models.RoleIntegrasi)
+	c.Locals("userNama", k.Nama)
+	c.Locals("apiKeyScopes", k.Scopes)
+
+	return c.Next()
+}
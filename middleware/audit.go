@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"backend/models"
+	"backend/repository"
+	"encoding/json"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Field rahasia tetap dicatat berubah, tetapi nilainya tidak ikut disimpan
+var auditDisamarkan = map[string]bool{
+	"password":       true,
+	"totp_secret":    true,
+	"totp_pending":   true,
+	"recovery_codes": true,
+	"hash":           true,
+}
+
+const auditNilaiSamar = "[disembunyikan]"
+
+// Audit mencatat operasi tulis pada entitas (nama entitas = nama koleksi) ke audit_log.
+// Dipasang per route setelah RequirePermission; ID dokumen diambil dari param :id, atau
+// dari respons (field "id" / "data") untuk operasi buat.
+func Audit(entitas string) fiber.Handler {
+	return audit(entitas, func(c *fiber.Ctx) string { return c.Params("id") })
+}
+
+// AuditAkunSendiri mencatat perubahan akun milik user yang sedang login (/users/me, 2FA)
+func AuditAkunSendiri() fiber.Handler {
+	return audit("user", func(c *fiber.Ctx) string {
+		id, _ := c.Locals("userID").(string)
+		return id
+	})
+}
+
+func audit(entitas string, refID func(*fiber.Ctx) string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := refID(c)
+		var sebelum bson.M
+		if id != "" {
+			sebelum = AuditSebelum(entitas, id)
+		}
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+		status := c.Response().StatusCode()
+		if status < 200 || status >= 300 {
+			return nil
+		}
+
+		aksi := "ubah"
+		if c.Method() == fiber.MethodDelete {
+			aksi = "hapus"
+		} else if id == "" {
+			aksi = "buat"
+			id = idDariRespons(c.Response().Body())
+		}
+		CatatAudit(c, entitas, id, aksi, sebelum)
+		return nil
+	}
+}
+
+// AuditSebelum membaca dokumen sebelum ditulis sebagai efek samping handler (lihat CatatAudit)
+func AuditSebelum(entitas, id string) bson.M {
+	doc, err := repository.GetDokumenAudit(entitas, id)
+	if err != nil {
+		log.Printf("⚠️ Audit: gagal membaca %s %s: %v", entitas, id, err)
+	}
+	return doc
+}
+
+// CatatAudit mencatat satu operasi tulis (buat / ubah / hapus) atas nama request saat ini.
+// Dipakai middleware Audit dan langsung oleh handler untuk tulisan di luar entitas route-nya,
+// mis. mutasi stok saat transaksi dibuat atau status pembayaran saat pengiriman selesai.
+func CatatAudit(c *fiber.Ctx, entitas, id, aksi string, sebelum bson.M) {
+	var sesudah bson.M
+	if aksi != "hapus" && id != "" {
+		sesudah = AuditSebelum(entitas, id)
+	}
+
+	aktorID, _ := c.Locals("userID").(string)
+	aktorNama, _ := c.Locals("userNama").(string)
+	role, _ := c.Locals("userRole").(string)
+	entri := &models.AuditLog{
+		AktorID:   aktorID,
+		AktorNama: aktorNama,
+		Role:      role,
+		Entitas:   entitas,
+		RefID:     id,
+		Aksi:      aksi,
+		Endpoint:  c.Method() + " " + c.Route().Path,
+		Perubahan: diffAudit(sebelum, sesudah),
+		IP:        c.IP(),
+		Waktu:     time.Now(),
+	}
+	// Kegagalan audit tidak membatalkan operasi yang sudah berhasil
+	if err := repository.CreateAuditLog(entri); err != nil {
+		log.Printf("⚠️ Audit: gagal menyimpan log %s %s: %v", entitas, id, err)
+	}
+}
+
+// idDariRespons mengambil ID dokumen baru dari respons JSON handler buat
+func idDariRespons(body []byte) string {
+	var resp map[string]interface{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return ""
+	}
+	if id, ok := resp["id"].(string); ok {
+		return id
+	}
+	switch d := resp["data"].(type) {
+	case string:
+		return d
+	case map[string]interface{}:
+		if id, ok := d["id"].(string); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// diffAudit membandingkan field tingkat atas; hanya field yang berubah yang dicatat
+func diffAudit(sebelum, sesudah bson.M) map[string]models.AuditPerubahan {
+	hasil := map[string]models.AuditPerubahan{}
+	for k, v := range sebelum {
+		if k == "_id" {
+			continue
+		}
+		baru, ada := sesudah[k]
+		if ada && reflect.DeepEqual(v, baru) {
+			continue
+		}
+		p := models.AuditPerubahan{Dari: v}
+		if ada {
+			p.Ke = baru
+		}
+		hasil[k] = p
+	}
+	for k, v := range sesudah {
+		if _, ada := sebelum[k]; ada || k == "_id" {
+			continue
+		}
+		hasil[k] = models.AuditPerubahan{Ke: v}
+	}
+	for k, p := range hasil {
+		if auditDisamarkan[k] {
+			if p.Dari != nil {
+				p.Dari = auditNilaiSamar
+			}
+			if p.Ke != nil {
+				p.Ke = auditNilaiSamar
+			}
+			hasil[k] = p
+		}
+	}
+	if len(hasil) == 0 {
+		return nil
+	}
+	return hasil
+}
@@ -15,7 +15,7 @@ func CorsMiddleware() fiber.Handler {
 
 	return cors.New(cors.Config{
 		AllowOrigins:     allowedOrigins, // Frontend tetap + Railway domain untuk Swagger
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-Requested-With",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-Requested-With, X-API-Key",
 		AllowMethods:     "GET,POST,PUT,DELETE,PATCH,OPTIONS",
 		AllowCredentials: true, // Kembali ke true untuk frontend
 	})
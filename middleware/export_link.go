@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"backend/repository"
+	"backend/utils"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// queryTanpaLink mengambil query request dalam bentuk kanonik, tanpa parameter link
+func queryTanpaLink(c *fiber.Ctx) string {
+	q := url.Values{}
+	c.Context().QueryArgs().VisitAll(func(k, v []byte) {
+		if string(k) != "link" {
+			q.Add(string(k), string(v))
+		}
+	})
+	return utils.KanonikQuery(q)
+}
+
+// ExportLinkMiddleware mengautentikasi unduhan export lewat ?link= hasil
+// POST /laporan/export/links. Link hanya berlaku sekali, singkat, dan untuk path +
+// filter yang sama persis dengan saat dibuat; JWT utama tidak pernah muncul di URL.
+func ExportLinkMiddleware(c *fiber.Ctx) error {
+	token := c.Query("link")
+	if token == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Link export tidak ditemukan",
+		})
+	}
+	query := queryTanpaLink(c)
+	nonce, err := utils.VerifyExportLinkToken(token, c.Path(), query, time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Link export tidak valid atau kadaluarsa",
+		})
+	}
+
+	// IMPORTANT: tandai terpakai sebelum file dibuat agar link tidak bisa dipakai ulang
+	link, err := repository.ConsumeExportLink(utils.HashToken(nonce))
+	if err != nil || link.Path != c.Path() || link.Query != query {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Link export tidak valid atau sudah dipakai",
+		})
+	}
+
+	user, err := repository.FindUserByID(link.UserID)
+	if err != nil || user.Status != "aktif" || user.TokenVersion != link.TokenVersion {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Link export tidak berlaku",
+		})
+	}
+	if user.WajibGantiPwd || wajib2FA(user) {
+		return tolakPengaturanWajib(c, user)
+	}
+
+	c.Locals("userID", user.ID)
+	c.Locals("userRole", user.Role)
+	c.Locals("userNama", user.Nama)
+
+	return c.Next()
+}
@@ -1,13 +1,93 @@
 package middleware
 
 import (
+	"backend/models"
+	"backend/repository"
 	"backend/utils"
+	"errors"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// checkTokenAktif menolak token yang sudah dicabut: jti ada di daftar cabut (logout),
+// user tidak ada / nonaktif, atau token_version user sudah naik sejak token dibuat.
+func checkTokenAktif(claims *utils.JWTClaims) (*models.User, error) {
+	if jti := claims.RegisteredClaims.ID; jti != "" {
+		revoked, err := repository.IsAccessTokenRevoked(jti)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, errors.New("token sudah dicabut")
+		}
+	}
+	user, err := repository.FindUserByID(claims.ID)
+	if err != nil {
+		return nil, errors.New("user tidak ditemukan")
+	}
+	if user.Status != "aktif" {
+		return nil, errors.New("akun nonaktif")
+	}
+	if user.TokenVersion != claims.Ver {
+		return nil, errors.New("token sudah dicabut")
+	}
+	return user, nil
+}
+
+// Endpoint yang tetap boleh diakses selama user wajib menyelesaikan pengaturan akun
+// (ganti password setelah reset admin / aktivasi 2FA yang diwajibkan kebijakan)
+func bolehSaatPengaturanWajib(c *fiber.Ctx) bool {
+	path := strings.TrimSuffix(c.Path(), "/")
+	switch {
+	case path == "/users/me" && c.Method() == fiber.MethodGet:
+		return true
+	case path == "/users/me/password", path == "/auth/logout", strings.HasPrefix(path, "/auth/2fa"):
+		return true
+	}
+	return false
+}
+
+// wajib2FA: kebijakan mewajibkan TOTP untuk admin, tetapi admin ini belum mengaktifkannya
+func wajib2FA(user *models.User) bool {
+	if user.Role != "admin" || user.TOTPAktif {
+		return false
+	}
+	p, err := repository.GetPengaturanKeamanan()
+	return err == nil && p.Wajib2FAAdmin
+}
+
+// tolakPengaturanWajib membalas 403 selama password belum diganti / 2FA wajib belum aktif
+func tolakPengaturanWajib(c *fiber.Ctx, user *models.User) error {
+	if user.WajibGantiPwd {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":                "Password wajib diganti sebelum melanjutkan",
+			"wajib_ganti_password": true,
+		})
+	}
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error":     "Akun admin wajib mengaktifkan 2FA sebelum melanjutkan",
+		"wajib_2fa": true,
+	})
+}
+
+// setClaimsLocals menyimpan claims ke context agar bisa dipakai di handler berikutnya
+func setClaimsLocals(c *fiber.Ctx, claims *utils.JWTClaims) {
+	c.Locals("userID", claims.ID)
+	c.Locals("userRole", claims.Role)
+	c.Locals("userNama", claims.Nama)
+	c.Locals("tokenID", claims.RegisteredClaims.ID)
+	if claims.ExpiresAt != nil {
+		c.Locals("tokenExp", claims.ExpiresAt.Time)
+	}
+}
+
 func JWTMiddleware(c *fiber.Ctx) error {
+	// Integrasi mesin-ke-mesin memakai API key sebagai alternatif Bearer JWT
+	if key := c.Get("X-API-Key"); key != "" {
+		return apiKeyAuth(c, key)
+	}
+
 	authHeader := c.Get("Authorization")
 
 	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
@@ -25,10 +105,17 @@ func JWTMiddleware(c *fiber.Ctx) error {
 		})
 	}
 
-	// Simpan claims ke context agar bisa dipakai di handler berikutnya
-	c.Locals("userID", claims.ID)
-	c.Locals("userRole", claims.Role)
-	c.Locals("userNama", claims.Nama)
+	user, err := checkTokenAktif(claims)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Token tidak berlaku: " + err.Error(),
+		})
+	}
+	if (user.WajibGantiPwd || wajib2FA(user)) && !bolehSaatPengaturanWajib(c) {
+		return tolakPengaturanWajib(c, user)
+	}
+
+	setClaimsLocals(c, claims)
 
 	return c.Next()
 }
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"backend/repository"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func punyaScope(scopes []string, perm string) bool {
+	for _, s := range scopes {
+		if s == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// PunyaPermission memeriksa satu permission untuk request saat ini (scope API key atau
+// permission role), untuk pengecekan tambahan di dalam handler.
+func PunyaPermission(c *fiber.Ctx, perm string) (bool, error) {
+	if scopes, ok := c.Locals("apiKeyScopes").([]string); ok {
+		return punyaScope(scopes, perm), nil
+	}
+	userRole, _ := c.Locals("userRole").(string)
+	if userRole == "" {
+		return false, nil
+	}
+	return repository.RoleHasPermission(userRole, perm)
+}
+
+// RequirePermission mengizinkan request jika role user memiliki SEMUA permission yang diminta.
+// Pemetaan role → permission disimpan di Mongo (koleksi role_permission); untuk API key
+// dipakai scope key tersebut.
+func RequirePermission(perms ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userRole, ok := c.Locals("userRole").(string)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid"})
+		}
+		// Request ber-API key dibatasi scope key, bukan permission role
+		if scopes, ok := c.Locals("apiKeyScopes").([]string); ok {
+			for _, p := range perms {
+				if !punyaScope(scopes, p) {
+					return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Scope API key tidak mencukupi", "permission": p})
+				}
+			}
+			return c.Next()
+		}
+		for _, p := range perms {
+			allowed, err := repository.RoleHasPermission(userRole, p)
+			if err != nil {
+				log.Printf("⚠️ Gagal membaca permission role: %v", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa hak akses"})
+			}
+			if !allowed {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Akses ditolak", "permission": p})
+			}
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// ApiKey adalah kredensial integrasi mesin-ke-mesin (sinkronisasi akuntansi, konektor
+// marketplace). Key asli hanya ditampilkan sekali saat dibuat; yang disimpan hanya hash
+// SHA-256 dan prefix untuk identifikasi. Hak akses dibatasi Scopes (kode permission).
+type ApiKey struct {
+	ID         string     `json:"id" bson:"_id"`
+	Nama       string     `json:"nama" bson:"nama"`
+	Prefix     string     `json:"prefix" bson:"prefix"`
+	Hash       string     `json:"-" bson:"hash"`
+	Scopes     []string   `json:"scopes" bson:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty" bson:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	CreatedBy  string     `json:"created_by" bson:"created_by"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+}
+
+// ApiKeyInput adalah body pembuatan API key
+type ApiKeyInput struct {
+	Nama        string   `json:"nama"`
+	Scopes      []string `json:"scopes"`
+	BerlakuHari int      `json:"berlaku_hari"` // 0 = tanpa kadaluarsa
+}
+
+// RoleIntegrasi adalah role yang dipasang di context untuk request ber-API key
+const RoleIntegrasi = "integrasi"
+
+// PermissionBolehApiKey: permission pengelolaan akses/akun tidak boleh diberikan ke API key
+func PermissionBolehApiKey(p string) bool {
+	switch p {
+	case PermRoleKelola, PermKaryawanWrite, PermPengaturanKelola:
+		return false
+	}
+	return PermissionDikenal(p)
+}
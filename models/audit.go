@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// AuditPerubahan adalah nilai satu field sebelum dan sesudah operasi tulis
+type AuditPerubahan struct {
+	Dari interface{} `json:"dari,omitempty" bson:"dari,omitempty"`
+	Ke   interface{} `json:"ke,omitempty" bson:"ke,omitempty"`
+}
+
+// AuditLog mencatat satu operasi tulis (buat/ubah/hapus) terhadap entitas: siapa, apa,
+// field apa saja yang berubah, dari mana dan kapan. ID memakai counter "log" (LOG###).
+type AuditLog struct {
+	ID        string                    `json:"id" bson:"_id"`
+	AktorID   string                    `json:"aktor_id" bson:"aktor_id"`
+	AktorNama string                    `json:"aktor_nama,omitempty" bson:"aktor_nama,omitempty"`
+	Role      string                    `json:"role" bson:"role"`
+	Entitas   string                    `json:"entitas" bson:"entitas"` // produk / kategori / pelanggan / transaksi / pembayaran / pengiriman / stok / user
+	RefID     string                    `json:"ref_id,omitempty" bson:"ref_id,omitempty"`
+	Aksi      string                    `json:"aksi" bson:"aksi"` // buat / ubah / hapus
+	Endpoint  string                    `json:"endpoint" bson:"endpoint"`
+	Perubahan map[string]AuditPerubahan `json:"perubahan,omitempty" bson:"perubahan,omitempty"`
+	IP        string                    `json:"ip" bson:"ip"`
+	Waktu     time.Time                 `json:"waktu" bson:"waktu"`
+}
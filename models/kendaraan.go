@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Kendaraan adalah armada pengiriman (mobil/motor)
+type Kendaraan struct {
+	ID        string    `json:"id" bson:"_id"`
+	PlatNomor string    `json:"plat_nomor" bson:"plat_nomor"`
+	Jenis     string    `json:"jenis" bson:"jenis"`         // mobil / motor
+	Kapasitas int       `json:"kapasitas" bson:"kapasitas"` // maksimal pengiriman aktif sekaligus
+	DriverID  string    `json:"driver_id,omitempty" bson:"driver_id,omitempty"`
+	Aktif     bool      `json:"aktif" bson:"aktif"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// ShiftDriver adalah jadwal kerja driver beserta kendaraan yang dipakai
+type ShiftDriver struct {
+	ID          string    `json:"id" bson:"_id"`
+	DriverID    string    `json:"driver_id" bson:"driver_id"`
+	KendaraanID string    `json:"kendaraan_id,omitempty" bson:"kendaraan_id,omitempty"`
+	Mulai       time.Time `json:"mulai" bson:"mulai"`
+	Selesai     time.Time `json:"selesai" bson:"selesai"`
+	Status      string    `json:"status" bson:"status"` // tersedia / libur
+	Catatan     string    `json:"catatan,omitempty" bson:"catatan,omitempty"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Status job laporan
+const (
+	JobAntri   = "antri"
+	JobProses  = "proses"
+	JobSelesai = "selesai"
+	JobGagal   = "gagal"
+)
+
+// LaporanJob adalah permintaan export yang dikerjakan worker di latar belakang. File hasil
+// disimpan di penyimpanan lokal dan dihapus bersama job setelah ExpiresAt.
+type LaporanJob struct {
+	ID          string            `json:"id" bson:"_id"`
+	Export      string            `json:"export" bson:"export"` // jenis export, mis. "excel"
+	Filter      map[string]string `json:"filter,omitempty" bson:"filter,omitempty"`
+	Status      string            `json:"status" bson:"status"`                   // antri / proses / selesai / gagal
+	Pesan       string            `json:"pesan,omitempty" bson:"pesan,omitempty"` // alasan gagal
+	JumlahBaris int               `json:"jumlah_baris,omitempty" bson:"jumlah_baris,omitempty"`
+	NamaFile    string            `json:"nama_file,omitempty" bson:"nama_file,omitempty"`
+	Path        string            `json:"-" bson:"path,omitempty"`
+	Ukuran      int64             `json:"ukuran,omitempty" bson:"ukuran,omitempty"` // byte
+	DibuatOleh  string            `json:"dibuat_oleh" bson:"dibuat_oleh"`
+	DibuatNama  string            `json:"dibuat_nama,omitempty" bson:"dibuat_nama,omitempty"`
+	CreatedAt   time.Time         `json:"created_at" bson:"created_at"`
+	MulaiAt     *time.Time        `json:"mulai_at,omitempty" bson:"mulai_at,omitempty"`
+	SelesaiAt   *time.Time        `json:"selesai_at,omitempty" bson:"selesai_at,omitempty"`
+	ExpiresAt   time.Time         `json:"expires_at" bson:"expires_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// LoginAttempt mencatat setiap percobaan login (berhasil maupun gagal)
+type LoginAttempt struct {
+	ID        string    `json:"id" bson:"_id"`
+	Email     string    `json:"email" bson:"email"`
+	UserID    string    `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	IP        string    `json:"ip" bson:"ip"`
+	UserAgent string    `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	Berhasil  bool      `json:"berhasil" bson:"berhasil"`
+	Alasan    string    `json:"alasan,omitempty" bson:"alasan,omitempty"` // password_salah / user_tidak_ada / nonaktif / terkunci / dibatasi
+	Waktu     time.Time `json:"waktu" bson:"waktu"`
+}
+
+// LoginThrottle menghitung login gagal per kunci ("ip:<ip>" atau "email:<email>")
+// untuk menghitung jeda backoff eksponensial. Dokumen terhapus otomatis lewat TTL.
+type LoginThrottle struct {
+	Key           string    `json:"key" bson:"_id"`
+	Gagal         int       `json:"gagal" bson:"gagal"`
+	TerakhirGagal time.Time `json:"terakhir_gagal" bson:"terakhir_gagal"`
+	BlokirSampai  time.Time `json:"blokir_sampai" bson:"blokir_sampai"`
+	ExpiresAt     time.Time `json:"expires_at" bson:"expires_at"`
+}
@@ -6,4 +6,7 @@ type Pelanggan struct {
 	Email  string `json:"email" bson:"email" validate:"required,email"`
 	NoHP   string `json:"no_hp" bson:"no_hp" validate:"required"`
 	Alamat string `json:"alamat" bson:"alamat" validate:"required"`
+	// Koordinat opsional untuk perencanaan rute driver
+	Latitude  *float64 `json:"latitude,omitempty" bson:"latitude,omitempty" validate:"omitempty,latitude"`
+	Longitude *float64 `json:"longitude,omitempty" bson:"longitude,omitempty" validate:"omitempty,longitude"`
 }
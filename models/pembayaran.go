@@ -2,12 +2,20 @@ package models
 
 import "time"
 
+// StatusPembayaran adalah daftar status pembayaran yang dikenal (huruf kecil)
+var StatusPembayaran = []string{"pending", "selesai", "batal"}
+
 type Pembayaran struct {
-	ID          string    `json:"id" bson:"_id"`
-	TransaksiID string    `json:"transaksi_id" bson:"transaksi_id"`
-	KasirID     string    `json:"kasir_id" bson:"kasir_id"`
-	Metode      string    `json:"metode" bson:"metode"`
-	TotalBayar  float64   `json:"total_bayar" bson:"total_bayar"`
-	Status      string    `json:"status" bson:"status"`
-	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+	ID          string  `json:"id" bson:"_id"`
+	TransaksiID string  `json:"transaksi_id" bson:"transaksi_id"`
+	KasirID     string  `json:"kasir_id" bson:"kasir_id"`
+	Metode      string  `json:"metode" bson:"metode"`
+	TotalBayar  float64 `json:"total_bayar" bson:"total_bayar"`
+	Status      string  `json:"status" bson:"status"`
+	ShiftID     string  `json:"shift_id,omitempty" bson:"shift_id,omitempty"` // sesi kasir saat pembayaran dibuat
+	// Sesi kasir yang menerima pelunasan (bisa berbeda dari ShiftID jika dilunasi setelah
+	// shift asal ditutup); kosong dengan SelesaiAt terisi = dilunasi di luar shift kasir
+	ShiftSelesaiID string     `json:"shift_selesai_id,omitempty" bson:"shift_selesai_id,omitempty"`
+	SelesaiAt      *time.Time `json:"selesai_at,omitempty" bson:"selesai_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" bson:"created_at"`
 }
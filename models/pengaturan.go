@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// PengaturanKeamanan adalah kebijakan keamanan yang dapat diubah admin (dokumen tunggal _id "keamanan")
+type PengaturanKeamanan struct {
+	ID            string    `json:"-" bson:"_id"`
+	Wajib2FAAdmin bool      `json:"wajib_2fa_admin" bson:"wajib_2fa_admin"` // admin wajib mengaktifkan TOTP
+	UpdatedBy     string    `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// Metode perhitungan HPP (harga pokok penjualan) untuk laporan laba
+const (
+	MetodeHPPTerakhir = "terakhir"  // harga beli penerimaan terakhir
+	MetodeHPPRataRata = "rata_rata" // rata-rata bergerak dari penerimaan barang
+)
+
+// PengaturanLaporan adalah pengaturan laporan yang dapat diubah admin (dokumen tunggal _id "laporan")
+type PengaturanLaporan struct {
+	ID        string `json:"-" bson:"_id"`
+	MetodeHPP string `json:"metode_hpp" bson:"metode_hpp"` // terakhir / rata_rata
+	// Export Excel: status pembayaran bila query status kosong, dan ID pembayaran yang
+	// tidak pernah ikut diekspor (mis. data uji). Pengecualian disimpan tanpa omitempty agar
+	// daftar kosong tidak tertukar dengan "belum pernah diatur".
+	ExportStatus            []string  `json:"export_status" bson:"export_status,omitempty"`
+	ExportKecualiPembayaran []string  `json:"export_kecuali_pembayaran" bson:"export_kecuali_pembayaran"`
+	UpdatedBy               string    `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	UpdatedAt               time.Time `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
@@ -3,12 +3,31 @@ package models
 import "time"
 
 type Pengiriman struct {
-	ID          string    `json:"id" bson:"_id"`
-	TransaksiID string    `json:"transaksi_id" bson:"transaksi_id"`
-	DriverID    string    `json:"driver_id" bson:"driver_id"`
-	Jenis       string    `json:"jenis" bson:"jenis"`
-	Ongkir      float64   `json:"ongkir" bson:"ongkir"`
-	Status      string    `json:"status" bson:"status"`
-	AlasanBatal string    `json:"alasan_batal,omitempty" bson:"alasan_batal,omitempty"`
-	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+	ID            string      `json:"id" bson:"_id"`
+	TransaksiID   string      `json:"transaksi_id" bson:"transaksi_id"`
+	DriverID      string      `json:"driver_id" bson:"driver_id"`
+	Jenis         string      `json:"jenis" bson:"jenis"`
+	KendaraanID   string      `json:"kendaraan_id,omitempty" bson:"kendaraan_id,omitempty"`
+	Ongkir        float64     `json:"ongkir" bson:"ongkir"`
+	Status        string      `json:"status" bson:"status"`
+	AlasanBatal   string      `json:"alasan_batal,omitempty" bson:"alasan_batal,omitempty"`
+	PerjalananID  string      `json:"perjalanan_id,omitempty" bson:"perjalanan_id,omitempty"` // trip driver (jika sudah dikelompokkan)
+	RiwayatStatus []StatusLog `json:"riwayat_status,omitempty" bson:"riwayat_status,omitempty"`
+	TrackingNonce string      `json:"-" bson:"tracking_nonce,omitempty"` // dasar token tracking publik
+	CreatedAt     time.Time   `json:"created_at" bson:"created_at"`
+}
+
+// StatusLog mencatat satu perubahan status beserta pelakunya.
+// Entitas membedakan perubahan pada pengiriman itu sendiri dan efek sampingnya
+// ke transaksi/pembayaran terkait.
+type StatusLog struct {
+	Entitas   string    `json:"entitas" bson:"entitas"` // pengiriman / transaksi / pembayaran
+	RefID     string    `json:"ref_id" bson:"ref_id"`
+	Dari      string    `json:"dari,omitempty" bson:"dari,omitempty"`
+	Ke        string    `json:"ke" bson:"ke"`
+	AktorID   string    `json:"aktor_id" bson:"aktor_id"`
+	AktorNama string    `json:"aktor_nama,omitempty" bson:"aktor_nama,omitempty"`
+	Role      string    `json:"role" bson:"role"`
+	Catatan   string    `json:"catatan,omitempty" bson:"catatan,omitempty"`
+	Waktu     time.Time `json:"waktu" bson:"waktu"`
 }
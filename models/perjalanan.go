@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// PerjalananStop adalah satu titik antar dalam perjalanan driver (satu pengiriman)
+type PerjalananStop struct {
+	Urutan       int        `json:"urutan" bson:"urutan"`
+	PengirimanID string     `json:"pengiriman_id" bson:"pengiriman_id"`
+	TransaksiID  string     `json:"transaksi_id" bson:"transaksi_id"`
+	PelangganID  string     `json:"pelanggan_id,omitempty" bson:"pelanggan_id,omitempty"`
+	Latitude     *float64   `json:"latitude,omitempty" bson:"latitude,omitempty"`
+	Longitude    *float64   `json:"longitude,omitempty" bson:"longitude,omitempty"`
+	Status       string     `json:"status" bson:"status"` // menunggu / tiba / selesai / batal
+	TibaAt       *time.Time `json:"tiba_at,omitempty" bson:"tiba_at,omitempty"`
+}
+
+// Perjalanan mengelompokkan beberapa pengiriman milik satu driver dalam satu trip
+type Perjalanan struct {
+	ID          string           `json:"id" bson:"_id"`
+	DriverID    string           `json:"driver_id" bson:"driver_id"`
+	Kendaraan   string           `json:"kendaraan" bson:"kendaraan"` // mobil / motor
+	KendaraanID string           `json:"kendaraan_id,omitempty" bson:"kendaraan_id,omitempty"`
+	Tanggal     time.Time        `json:"tanggal" bson:"tanggal"`
+	Status      string           `json:"status" bson:"status"` // direncanakan / berjalan / selesai / batal
+	Stops       []PerjalananStop `json:"stops" bson:"stops"`
+	MulaiAt     *time.Time       `json:"mulai_at,omitempty" bson:"mulai_at,omitempty"`
+	SelesaiAt   *time.Time       `json:"selesai_at,omitempty" bson:"selesai_at,omitempty"`
+	Catatan     string           `json:"catatan,omitempty" bson:"catatan,omitempty"`
+	CreatedBy   string           `json:"created_by" bson:"created_by"`
+	CreatedAt   time.Time        `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,140 @@
+package models
+
+import "time"
+
+// Daftar permission. Format "<modul>:<aksi>"; role dipetakan ke kumpulan permission
+// yang disimpan di koleksi role_permission dan bisa diubah admin.
+const (
+	PermProdukRead        = "produk:read"
+	PermProdukWrite       = "produk:write"
+	PermKategoriRead      = "kategori:read"
+	PermKategoriWrite     = "kategori:write"
+	PermStokRead          = "stok:read"
+	PermStokWrite         = "stok:write"
+	PermPelangganRead     = "pelanggan:read"
+	PermPelangganWrite    = "pelanggan:write"
+	PermTransaksiRead     = "transaksi:read"
+	PermTransaksiWrite    = "transaksi:write"
+	PermPembayaranRead    = "pembayaran:read"
+	PermPembayaranWrite   = "pembayaran:write"
+	PermPengirimanRead    = "pengiriman:read"
+	PermPengirimanWrite   = "pengiriman:write"
+	PermPengirimanStatus  = "pengiriman:status"
+	PermPerjalananRead    = "perjalanan:read"
+	PermPerjalananWrite   = "perjalanan:write"
+	PermPerjalananJalan   = "perjalanan:jalankan"
+	PermKendaraanRead     = "kendaraan:read"
+	PermKendaraanWrite    = "kendaraan:write"
+	PermShiftRead         = "shift:read"
+	PermShiftWrite        = "shift:write"
+	PermDriverRead        = "driver:read"
+	PermKaryawanRead      = "karyawan:read"
+	PermKaryawanWrite     = "karyawan:write"
+	PermRiwayatRead       = "riwayat:read"
+	PermLaporanRead       = "laporan:read"
+	PermLaporanExport     = "laporan:export"
+	PermLaporanKinerja    = "laporan:kinerja"
+	PermLaporanLaba       = "laporan:laba"
+	PermLaporanStok       = "laporan:stok"
+	PermLaporanPersediaan = "laporan:persediaan"
+	PermPengaturanKelola  = "pengaturan:kelola"
+	PermRoleKelola        = "role:kelola"
+	PermAuditRead         = "audit:read"
+	PermShiftKasirKelola  = "shift_kasir:kelola"
+	PermShiftKasirRead    = "shift_kasir:read"
+	PermShiftKasirLapor   = "shift_kasir:laporan"
+)
+
+// KatalogPermission berisi semua permission yang dikenal beserta keterangannya
+var KatalogPermission = []PermissionInfo{
+	{PermProdukRead, "Melihat produk"},
+	{PermProdukWrite, "Tambah/ubah/hapus produk"},
+	{PermKategoriRead, "Melihat kategori"},
+	{PermKategoriWrite, "Tambah/ubah/hapus kategori"},
+	{PermStokRead, "Melihat saldo & mutasi stok"},
+	{PermStokWrite, "Mencatat mutasi stok manual"},
+	{PermPelangganRead, "Melihat pelanggan"},
+	{PermPelangganWrite, "Tambah/ubah/hapus pelanggan"},
+	{PermTransaksiRead, "Melihat transaksi"},
+	{PermTransaksiWrite, "Membuat/ubah/hapus transaksi"},
+	{PermPembayaranRead, "Melihat pembayaran"},
+	{PermPembayaranWrite, "Membuat & menyelesaikan pembayaran"},
+	{PermPengirimanRead, "Melihat pengiriman"},
+	{PermPengirimanWrite, "Membuat/hapus pengiriman & link tracking"},
+	{PermPengirimanStatus, "Mengubah status pengiriman"},
+	{PermPerjalananRead, "Melihat perjalanan driver"},
+	{PermPerjalananWrite, "Merencanakan perjalanan driver"},
+	{PermPerjalananJalan, "Menjalankan perjalanan (driver)"},
+	{PermKendaraanRead, "Melihat armada"},
+	{PermKendaraanWrite, "Kelola armada"},
+	{PermShiftRead, "Melihat jadwal shift driver"},
+	{PermShiftWrite, "Kelola jadwal shift driver"},
+	{PermDriverRead, "Melihat daftar & ketersediaan driver"},
+	{PermKaryawanRead, "Melihat karyawan & log login"},
+	{PermKaryawanWrite, "Kelola karyawan (buat, ubah, reset, buka kunci)"},
+	{PermRiwayatRead, "Melihat riwayat pembayaran"},
+	{PermLaporanRead, "Melihat laporan dashboard"},
+	{PermLaporanExport, "Export laporan"},
+	{PermLaporanKinerja, "Laporan kinerja kasir & driver"},
+	{PermLaporanLaba, "Laporan laba kotor & margin"},
+	{PermLaporanStok, "Laporan kartu stok (termasuk export Excel)"},
+	{PermLaporanPersediaan, "Laporan nilai persediaan per tanggal"},
+	{PermPengaturanKelola, "Kelola pengaturan sistem"},
+	{PermRoleKelola, "Kelola permission role & API key integrasi"},
+	{PermAuditRead, "Melihat audit log perubahan data"},
+	{PermShiftKasirKelola, "Buka & tutup shift kasir sendiri (wajib shift saat menerima pembayaran)"},
+	{PermShiftKasirRead, "Melihat shift kasir"},
+	{PermShiftKasirLapor, "Laporan rekonsiliasi shift kasir"},
+}
+
+// DefaultRolePermissions adalah pemetaan awal (setara aturan akses sebelumnya);
+// hanya dipakai untuk mengisi role yang belum ada di database.
+var DefaultRolePermissions = map[string][]string{
+	"admin": {
+		PermProdukRead, PermKategoriRead, PermStokRead, PermPelangganRead, PermTransaksiRead,
+		PermPembayaranRead, PermPengirimanRead, PermPerjalananRead, PermKendaraanRead, PermKendaraanWrite,
+		PermShiftRead, PermShiftWrite, PermDriverRead, PermKaryawanRead, PermKaryawanWrite, PermRiwayatRead,
+		PermLaporanRead, PermLaporanExport, PermLaporanKinerja, PermLaporanLaba, PermPengaturanKelola, PermRoleKelola,
+		PermAuditRead, PermShiftKasirRead, PermShiftKasirLapor, PermLaporanStok, PermLaporanPersediaan,
+	},
+	"kasir": {
+		PermProdukRead, PermKategoriRead, PermStokRead, PermPelangganRead, PermPelangganWrite,
+		PermTransaksiRead, PermTransaksiWrite, PermPembayaranRead, PermPembayaranWrite,
+		PermPengirimanRead, PermPengirimanWrite, PermPengirimanStatus, PermPerjalananRead, PermPerjalananWrite,
+		PermKendaraanRead, PermShiftRead, PermDriverRead, PermRiwayatRead, PermLaporanRead,
+		PermShiftKasirKelola, PermShiftKasirRead,
+	},
+	"gudang": {
+		PermProdukRead, PermProdukWrite, PermKategoriRead, PermKategoriWrite, PermStokRead, PermStokWrite,
+		PermLaporanRead, PermLaporanStok,
+	},
+	"driver": {
+		PermProdukRead, PermKategoriRead, PermPengirimanRead, PermPengirimanStatus, PermPerjalananRead,
+		PermPerjalananJalan, PermShiftRead, PermRiwayatRead,
+	},
+}
+
+type PermissionInfo struct {
+	Kode       string `json:"kode"`
+	Keterangan string `json:"keterangan"`
+}
+
+// RolePermission adalah kumpulan permission milik satu role (_id = nama role)
+type RolePermission struct {
+	Role        string    `json:"role" bson:"_id"`
+	Permissions []string  `json:"permissions" bson:"permissions"`
+	UpdatedBy   string    `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+	// Permission default yang sudah pernah dipasang saat startup
+	DefaultTerpasang []string `json:"-" bson:"default_terpasang,omitempty"`
+}
+
+// PermissionDikenal memeriksa apakah kode ada di katalog
+func PermissionDikenal(kode string) bool {
+	for _, p := range KatalogPermission {
+		if p.Kode == kode {
+			return true
+		}
+	}
+	return false
+}
@@ -14,6 +14,19 @@ type Produk struct {
 	Stok       int       `json:"stok" bson:"stok"`
 	Aktif      bool      `json:"aktif" bson:"aktif"`
 	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+	// Rata-rata bergerak harga beli dari penerimaan barang (stok masuk ber-harga)
+	HargaPokokRata float64 `json:"harga_pokok_rata,omitempty" bson:"harga_pokok_rata,omitempty"`
+	// Naik setiap harga pokok dihitung ulang; dipakai sebagai cek versi antar penerimaan bersamaan
+	HPPVersi int `json:"-" bson:"hpp_versi,omitempty"`
+}
+
+// HargaPokok mengembalikan HPP per unit sesuai metode: harga beli terakhir (HargaBeli)
+// atau rata-rata bergerak (fallback ke HargaBeli jika belum ada penerimaan ber-harga).
+func (p *Produk) HargaPokok(metode string) float64 {
+	if metode == MetodeHPPRataRata && p.HargaPokokRata > 0 {
+		return p.HargaPokokRata
+	}
+	return p.HargaBeli
 }
 
 // ProdukSwagger adalah struct khusus untuk dokumentasi Swagger response
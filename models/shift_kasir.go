@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// ShiftKasir adalah sesi laci kas kasir: dibuka dengan kas awal, semua pembayaran selama
+// sesi terhubung lewat ShiftID, lalu ditutup dengan hasil hitung fisik kasir.
+type ShiftKasir struct {
+	ID           string             `json:"id" bson:"_id"`
+	KasirID      string             `json:"kasir_id" bson:"kasir_id"`
+	KasirNama    string             `json:"kasir_nama,omitempty" bson:"kasir_nama,omitempty"`
+	Status       string             `json:"status" bson:"status"` // buka / tutup
+	KasAwal      float64            `json:"kas_awal" bson:"kas_awal"`
+	DibukaAt     time.Time          `json:"dibuka_at" bson:"dibuka_at"`
+	DitutupAt    *time.Time         `json:"ditutup_at,omitempty" bson:"ditutup_at,omitempty"`
+	Rekap        []ShiftRekapMetode `json:"rekap,omitempty" bson:"rekap,omitempty"` // diisi saat tutup
+	TotalSelisih float64            `json:"total_selisih" bson:"total_selisih"`
+	// Hasil hitung kasir saat tutup; disimpan agar rekap bisa dihitung ulang jika ada
+	// pembayaran yang masuk bersamaan dengan penutupan
+	KasDihitung  *float64           `json:"kas_dihitung,omitempty" bson:"kas_dihitung,omitempty"`
+	Setoran      map[string]float64 `json:"setoran,omitempty" bson:"setoran,omitempty"`
+	RekapVersi   int                `json:"-" bson:"rekap_versi,omitempty"`
+	CatatanBuka  string             `json:"catatan_buka,omitempty" bson:"catatan_buka,omitempty"`
+	CatatanTutup string             `json:"catatan_tutup,omitempty" bson:"catatan_tutup,omitempty"`
+}
+
+// ShiftRekapMetode membandingkan nilai seharusnya (sistem) dengan hasil hitung per metode bayar.
+// Untuk tunai, Diharapkan sudah termasuk kas awal. Hanya pembayaran selesai yang dihitung;
+// pembayaran pending dilaporkan terpisah.
+type ShiftRekapMetode struct {
+	Metode        string  `json:"metode" bson:"metode"`
+	Jumlah        int     `json:"jumlah" bson:"jumlah"` // jumlah pembayaran selesai
+	Total         float64 `json:"total" bson:"total"`   // total pembayaran selesai metode ini
+	JumlahPending int     `json:"jumlah_pending,omitempty" bson:"jumlah_pending,omitempty"`
+	TotalPending  float64 `json:"total_pending,omitempty" bson:"total_pending,omitempty"` // belum selesai; tidak masuk Diharapkan
+	// Pembayaran shift ini yang dilunasi setelah shift ditutup tanpa shift kasir penerima:
+	// uangnya tidak masuk laci mana pun sehingga perlu ditelusuri admin
+	JumlahLuarShift int      `json:"jumlah_luar_shift,omitempty" bson:"jumlah_luar_shift,omitempty"`
+	TotalLuarShift  float64  `json:"total_luar_shift,omitempty" bson:"total_luar_shift,omitempty"`
+	Diharapkan      float64  `json:"diharapkan" bson:"diharapkan"`
+	Aktual          *float64 `json:"aktual,omitempty" bson:"aktual,omitempty"`
+	Selisih         *float64 `json:"selisih,omitempty" bson:"selisih,omitempty"` // aktual - diharapkan
+}
+
+// BukaShiftInput adalah body pembukaan shift kasir
+type BukaShiftInput struct {
+	KasAwal float64 `json:"kas_awal"`
+	Catatan string  `json:"catatan"`
+}
+
+// TutupShiftInput adalah body penutupan shift: KasDihitung = uang tunai di laci,
+// Setoran = nilai hitung/settlement metode non-tunai (opsional, mis. {"qris": 150000}).
+type TutupShiftInput struct {
+	KasDihitung *float64           `json:"kas_dihitung"`
+	Setoran     map[string]float64 `json:"setoran"`
+	Catatan     string             `json:"catatan"`
+}
@@ -11,6 +11,7 @@ type StokMutasi struct {
 	RefID      string    `json:"ref_id,omitempty" bson:"ref_id,omitempty"`
 	RefType    string    `json:"ref_type,omitempty" bson:"ref_type,omitempty"` // contoh: transaksi, manual
 	Keterangan string    `json:"keterangan,omitempty" bson:"keterangan,omitempty"`
+	HargaBeli  float64   `json:"harga_beli,omitempty" bson:"harga_beli,omitempty"` // harga beli per unit (penerimaan barang, jenis masuk)
 	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
 }
 
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// RefreshToken disimpan server-side (hash SHA-256), dirotasi setiap kali dipakai.
+// Semua token hasil rotasi dari satu login berbagi FamilyID sehingga pemakaian ulang
+// token lama dapat mencabut seluruh keluarga token.
+type RefreshToken struct {
+	ID           string     `json:"id" bson:"_id"` // hash token
+	UserID       string     `json:"user_id" bson:"user_id"`
+	FamilyID     string     `json:"family_id" bson:"family_id"`
+	TokenVersion int        `json:"token_version" bson:"token_version"`
+	UserAgent    string     `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	IP           string     `json:"ip,omitempty" bson:"ip,omitempty"`
+	ExpiresAt    time.Time  `json:"expires_at" bson:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	ReplacedBy   string     `json:"replaced_by,omitempty" bson:"replaced_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at" bson:"created_at"`
+}
+
+// RevokedToken adalah daftar jti access token yang dicabut sebelum kadaluarsa (logout)
+type RevokedToken struct {
+	JTI       string    `json:"jti" bson:"_id"`
+	UserID    string    `json:"user_id" bson:"user_id"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+// PasswordResetToken adalah token lupa password sekali pakai (disimpan sebagai hash SHA-256)
+type PasswordResetToken struct {
+	ID        string     `json:"id" bson:"_id"` // hash token
+	UserID    string     `json:"user_id" bson:"user_id"`
+	IP        string     `json:"ip,omitempty" bson:"ip,omitempty"`
+	ExpiresAt time.Time  `json:"expires_at" bson:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty" bson:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+}
+
+// LoginChallenge adalah token sementara antara langkah password dan langkah kode 2FA
+type LoginChallenge struct {
+	ID        string    `json:"id" bson:"_id"` // hash interim token
+	UserID    string    `json:"user_id" bson:"user_id"`
+	Percobaan int       `json:"percobaan" bson:"percobaan"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// ExportLink adalah link unduhan export sekali pakai. Link terikat pada satu path export
+// dan query filternya sehingga JWT utama tidak perlu ditaruh di URL.
+type ExportLink struct {
+	ID           string     `json:"id" bson:"_id"` // hash nonce link
+	UserID       string     `json:"user_id" bson:"user_id"`
+	TokenVersion int        `json:"token_version" bson:"token_version"`
+	Path         string     `json:"path" bson:"path"`
+	Query        string     `json:"query" bson:"query"` // query filter kanonik (urut key)
+	ExpiresAt    time.Time  `json:"expires_at" bson:"expires_at"`
+	UsedAt       *time.Time `json:"used_at,omitempty" bson:"used_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at" bson:"created_at"`
+}
@@ -7,6 +7,7 @@ type TransaksiItem struct {
 	NamaProduk string  `json:"nama_produk,omitempty" bson:"nama_produk,omitempty"`
 	Jumlah     int     `json:"jumlah" bson:"jumlah"`
 	Harga      float64 `json:"harga" bson:"harga"`
+	HargaBeli  float64 `json:"harga_beli,omitempty" bson:"harga_beli,omitempty"` // HPP per unit saat terjual (snapshot)
 }
 
 type Transaksi struct {
@@ -3,18 +3,40 @@ package models
 import "time"
 
 type User struct {
-	ID        string    `json:"id" bson:"_id"`
-	Nama      string    `json:"nama" bson:"nama"`
-	Email     string    `json:"email" bson:"email"`
-	Password  string    `json:"password,omitempty" bson:"password"`
-	Role      string    `json:"role" bson:"role"`
-	NoHP      string    `json:"no_hp,omitempty" bson:"no_hp,omitempty"`
-	Alamat    string    `json:"alamat,omitempty" bson:"alamat,omitempty"`
-	Status    string    `json:"status" bson:"status"` // aktif/nonaktif
-	CreatedAt time.Time `json:"created_at,omitempty" bson:"created_at"`
+	ID             string     `json:"id" bson:"_id"`
+	Nama           string     `json:"nama" bson:"nama"`
+	Email          string     `json:"email" bson:"email"`
+	Password       string     `json:"password,omitempty" bson:"password"`
+	Role           string     `json:"role" bson:"role"`
+	NoHP           string     `json:"no_hp,omitempty" bson:"no_hp,omitempty"`
+	Alamat         string     `json:"alamat,omitempty" bson:"alamat,omitempty"`
+	Status         string     `json:"status" bson:"status"`                                                 // aktif/nonaktif
+	TokenVersion   int        `json:"-" bson:"token_version"`                                               // naik saat semua token user dicabut
+	GagalLogin     int        `json:"gagal_login,omitempty" bson:"gagal_login,omitempty"`                   // login gagal berturut-turut
+	TerkunciSampai *time.Time `json:"terkunci_sampai,omitempty" bson:"terkunci_sampai,omitempty"`           // lockout sementara
+	WajibGantiPwd  bool       `json:"wajib_ganti_password,omitempty" bson:"wajib_ganti_password,omitempty"` // setelah reset oleh admin
+	TOTPAktif      bool       `json:"totp_aktif,omitempty" bson:"totp_aktif,omitempty"`
+	TOTPSecret     string     `json:"-" bson:"totp_secret,omitempty"`
+	TOTPPending    string     `json:"-" bson:"totp_pending,omitempty"`   // secret yang belum dikonfirmasi
+	TOTPLastStep   int64      `json:"-" bson:"totp_last_step,omitempty"` // cegah kode yang sama dipakai ulang
+	RecoveryCodes  []string   `json:"-" bson:"recovery_codes,omitempty"` // hash kode pemulihan yang belum dipakai
+	CreatedAt      time.Time  `json:"created_at,omitempty" bson:"created_at"`
 }
 
 type LoginInput struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
+
+// ProfilInput adalah field profil yang boleh diubah sendiri oleh user (PUT /users/me)
+type ProfilInput struct {
+	Nama   *string `json:"nama"`
+	NoHP   *string `json:"no_hp"`
+	Alamat *string `json:"alamat"`
+}
+
+// GantiPasswordInput untuk POST /users/me/password
+type GantiPasswordInput struct {
+	PasswordLama string `json:"password_lama"`
+	PasswordBaru string `json:"password_baru"`
+}
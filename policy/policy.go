@@ -0,0 +1,188 @@
+// Package policy memusatkan aturan akses tingkat baris (kepemilikan data):
+// kasir hanya melihat transaksinya sendiri, driver hanya pengiriman yang ditugaskan
+// kepadanya, dan seterusnya. Controller memakai Scope untuk filter query list dan
+// Izinkan untuk keputusan per dokumen, sehingga aturan tidak lagi ditulis ulang di
+// setiap handler. Hak akses per endpoint (permission) tetap diatur middleware RBAC.
+package policy
+
+import (
+	"backend/models"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Sumber adalah jenis resource yang dilindungi aturan kepemilikan
+type Sumber string
+
+const (
+	Transaksi   Sumber = "transaksi"
+	Pembayaran  Sumber = "pembayaran"
+	Pengiriman  Sumber = "pengiriman"
+	Perjalanan  Sumber = "perjalanan"
+	ShiftDriver Sumber = "shift_driver"
+	ShiftKasir  Sumber = "shift_kasir"
+	Riwayat     Sumber = "riwayat"
+)
+
+// Aksi terhadap satu dokumen
+type Aksi string
+
+const (
+	Lihat Aksi = "lihat"
+	Ubah  Aksi = "ubah"
+	Hapus Aksi = "hapus"
+)
+
+// ErrDitolak dikembalikan Scope jika role sama sekali tidak boleh melihat resource
+var ErrDitolak = errors.New("akses ditolak")
+
+// Subjek adalah pemanggil, diambil dari claims JWT
+type Subjek struct {
+	UserID string
+	Role   string
+}
+
+// Resolver menyediakan data lintas koleksi yang dibutuhkan aturan
+// (pengiriman dimiliki kasir lewat transaksi terkait).
+type Resolver interface {
+	KasirTransaksi(transaksiID string) (string, error)
+	TransaksiMilikKasir(kasirID string) ([]string, error)
+}
+
+type cakupan int
+
+const (
+	tidak        cakupan = iota
+	semua                // seluruh dokumen
+	milikKasir           // kasir pemilik transaksi
+	milikDriver          // driver yang ditugaskan
+	milikPembuat         // user yang membuat dokumen
+)
+
+// aturanLihat: cakupan per role saat membaca (juga dipakai Scope). Role "integrasi"
+// adalah request ber-API key; transaksi yang dibuatnya tercatat dengan kasir_id = ID key.
+var aturanLihat = map[Sumber]map[string]cakupan{
+	Transaksi:   {"admin": semua, "kasir": milikKasir, "integrasi": semua},
+	Pembayaran:  {"admin": semua, "kasir": milikKasir, "integrasi": semua},
+	Pengiriman:  {"admin": semua, "kasir": milikKasir, "driver": milikDriver},
+	Perjalanan:  {"admin": semua, "kasir": semua, "driver": milikDriver},
+	ShiftDriver: {"admin": semua, "kasir": semua, "driver": milikDriver},
+	ShiftKasir:  {"admin": semua, "kasir": milikKasir},
+	// Riwayat pembayaran: driver melihat pembayaran yang pengirimannya ditugaskan kepadanya
+	Riwayat: {"admin": semua, "kasir": milikKasir, "driver": milikDriver},
+}
+
+// aturanTulis: cakupan per role untuk Ubah/Hapus. Admin tidak tercantum karena
+// data operasional hanya diubah oleh pemiliknya (admin read-only).
+var aturanTulis = map[Sumber]map[Aksi]map[string]cakupan{
+	Transaksi: {
+		Ubah:  {"kasir": milikKasir, "integrasi": milikKasir},
+		Hapus: {"kasir": milikKasir, "integrasi": milikKasir},
+	},
+	Pembayaran: {
+		Ubah: {"kasir": milikKasir},
+	},
+	Pengiriman: {
+		Ubah:  {"kasir": milikKasir, "driver": milikDriver},
+		Hapus: {"kasir": milikKasir},
+	},
+	Perjalanan: {
+		Ubah:  {"kasir": milikPembuat, "driver": milikDriver},
+		Hapus: {"kasir": milikPembuat},
+	},
+}
+
+// Policy mengevaluasi aturan kepemilikan
+type Policy struct {
+	r Resolver
+}
+
+func New(r Resolver) *Policy {
+	return &Policy{r: r}
+}
+
+func (p *Policy) cakupan(s Subjek, src Sumber, a Aksi) cakupan {
+	if a == Lihat {
+		return aturanLihat[src][s.Role]
+	}
+	return aturanTulis[src][a][s.Role]
+}
+
+// Scope menghasilkan filter Mongo yang harus digabung (AND) ke query list.
+// Filter kosong berarti seluruh dokumen; ErrDitolak berarti role tidak boleh melihat.
+func (p *Policy) Scope(s Subjek, src Sumber) (bson.M, error) {
+	switch p.cakupan(s, src, Lihat) {
+	case semua:
+		return bson.M{}, nil
+	case milikKasir:
+		if src == Pengiriman {
+			ids, err := p.r.TransaksiMilikKasir(s.UserID)
+			if err != nil {
+				return nil, err
+			}
+			if ids == nil {
+				ids = []string{}
+			}
+			return bson.M{"transaksi_id": bson.M{"$in": ids}}, nil
+		}
+		return bson.M{"kasir_id": s.UserID}, nil
+	case milikDriver:
+		return bson.M{"driver_id": s.UserID}, nil
+	case milikPembuat:
+		return bson.M{"created_by": s.UserID}, nil
+	}
+	return nil, ErrDitolak
+}
+
+// Izinkan memutuskan akses subjek terhadap satu dokumen. doc berupa pointer model
+// (*models.Transaksi, *models.Pembayaran, *models.Pengiriman, *models.Perjalanan,
+// *models.ShiftDriver, *models.ShiftKasir); tipe lain selalu ditolak.
+func (p *Policy) Izinkan(s Subjek, src Sumber, a Aksi, doc interface{}) (bool, error) {
+	c := p.cakupan(s, src, a)
+	switch c {
+	case tidak:
+		return false, nil
+	case semua:
+		return true, nil
+	}
+
+	var kasirID, driverID, pembuatID string
+	switch d := doc.(type) {
+	case *models.Transaksi:
+		kasirID = d.KasirID
+	case *models.Pembayaran:
+		kasirID = d.KasirID
+	case *models.Pengiriman:
+		driverID = d.DriverID
+		if c == milikKasir {
+			k, err := p.r.KasirTransaksi(d.TransaksiID)
+			if err != nil {
+				return false, err
+			}
+			kasirID = k
+		}
+	case *models.Perjalanan:
+		driverID = d.DriverID
+		pembuatID = d.CreatedBy
+	case *models.ShiftDriver:
+		driverID = d.DriverID
+	case *models.ShiftKasir:
+		kasirID = d.KasirID
+	default:
+		return false, nil
+	}
+
+	if s.UserID == "" {
+		return false, nil
+	}
+	switch c {
+	case milikKasir:
+		return kasirID == s.UserID, nil
+	case milikDriver:
+		return driverID == s.UserID, nil
+	case milikPembuat:
+		return pembuatID == s.UserID, nil
+	}
+	return false, nil
+}
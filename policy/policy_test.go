@@ -0,0 +1,193 @@
+package policy
+
+import (
+	"backend/models"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// stubResolver: transaksi TRX1 & TRX2 milik kasir K1, TRX3 milik K2
+type stubResolver struct {
+	err error
+}
+
+var kasirTrx = map[string]string{"TRX1": "K1", "TRX2": "K1", "TRX3": "K2"}
+
+func (r stubResolver) KasirTransaksi(transaksiID string) (string, error) {
+	if r.err != nil {
+		return "", r.err
+	}
+	return kasirTrx[transaksiID], nil
+}
+
+func (r stubResolver) TransaksiMilikKasir(kasirID string) ([]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	var ids []string
+	for _, id := range []string{"TRX1", "TRX2", "TRX3"} {
+		if kasirTrx[id] == kasirID {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+var (
+	admin   = Subjek{UserID: "A1", Role: "admin"}
+	kasir1  = Subjek{UserID: "K1", Role: "kasir"}
+	kasir2  = Subjek{UserID: "K2", Role: "kasir"}
+	driver1 = Subjek{UserID: "D1", Role: "driver"}
+	driver2 = Subjek{UserID: "D2", Role: "driver"}
+	gudang  = Subjek{UserID: "G1", Role: "gudang"}
+	apiKey  = Subjek{UserID: "KEY001", Role: "integrasi"}
+)
+
+func TestScope(t *testing.T) {
+	tests := []struct {
+		nama    string
+		s       Subjek
+		src     Sumber
+		want    bson.M
+		wantErr error
+	}{
+		{"admin transaksi", admin, Transaksi, bson.M{}, nil},
+		{"kasir transaksi", kasir1, Transaksi, bson.M{"kasir_id": "K1"}, nil},
+		{"driver transaksi", driver1, Transaksi, nil, ErrDitolak},
+		{"gudang transaksi", gudang, Transaksi, nil, ErrDitolak},
+		{"admin pembayaran", admin, Pembayaran, bson.M{}, nil},
+		{"kasir pembayaran", kasir2, Pembayaran, bson.M{"kasir_id": "K2"}, nil},
+		{"driver pembayaran", driver1, Pembayaran, nil, ErrDitolak},
+		{"admin pengiriman", admin, Pengiriman, bson.M{}, nil},
+		{"kasir pengiriman", kasir1, Pengiriman, bson.M{"transaksi_id": bson.M{"$in": []string{"TRX1", "TRX2"}}}, nil},
+		{"kasir tanpa transaksi", Subjek{UserID: "K9", Role: "kasir"}, Pengiriman, bson.M{"transaksi_id": bson.M{"$in": []string{}}}, nil},
+		{"driver pengiriman", driver1, Pengiriman, bson.M{"driver_id": "D1"}, nil},
+		{"gudang pengiriman", gudang, Pengiriman, nil, ErrDitolak},
+		{"kasir perjalanan", kasir1, Perjalanan, bson.M{}, nil},
+		{"driver perjalanan", driver2, Perjalanan, bson.M{"driver_id": "D2"}, nil},
+		{"driver shift", driver1, ShiftDriver, bson.M{"driver_id": "D1"}, nil},
+		{"admin shift", admin, ShiftDriver, bson.M{}, nil},
+		{"kasir shift kasir", kasir1, ShiftKasir, bson.M{"kasir_id": "K1"}, nil},
+		{"driver shift kasir", driver1, ShiftKasir, nil, ErrDitolak},
+		{"admin riwayat", admin, Riwayat, bson.M{}, nil},
+		{"kasir riwayat", kasir1, Riwayat, bson.M{"kasir_id": "K1"}, nil},
+		{"driver riwayat", driver2, Riwayat, bson.M{"driver_id": "D2"}, nil},
+		{"gudang riwayat", gudang, Riwayat, nil, ErrDitolak},
+		{"api key riwayat", apiKey, Riwayat, nil, ErrDitolak},
+		{"api key transaksi", apiKey, Transaksi, bson.M{}, nil},
+		{"api key pengiriman", apiKey, Pengiriman, nil, ErrDitolak},
+		{"role kosong", Subjek{UserID: "X"}, Transaksi, nil, ErrDitolak},
+	}
+	p := New(stubResolver{})
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			got, err := p.Scope(tt.s, tt.src)
+			if err != tt.wantErr {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("filter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIzinkan(t *testing.T) {
+	trxK1 := &models.Transaksi{ID: "TRX1", KasirID: "K1"}
+	payK1 := &models.Pembayaran{ID: "PAY1", KasirID: "K1"}
+	krmD1 := &models.Pengiriman{ID: "KRM1", TransaksiID: "TRX1", DriverID: "D1"}
+	krmTanpaTrx := &models.Pengiriman{ID: "KRM2", TransaksiID: "TRX404", DriverID: "D1"}
+	pjlD1 := &models.Perjalanan{ID: "PJL1", DriverID: "D1", CreatedBy: "K1"}
+	shiftD1 := &models.ShiftDriver{ID: "SHF1", DriverID: "D1"}
+
+	tests := []struct {
+		nama string
+		s    Subjek
+		src  Sumber
+		aksi Aksi
+		doc  interface{}
+		want bool
+	}{
+		// Transaksi
+		{"admin lihat transaksi", admin, Transaksi, Lihat, trxK1, true},
+		{"admin ubah transaksi", admin, Transaksi, Ubah, trxK1, false},
+		{"kasir pemilik lihat transaksi", kasir1, Transaksi, Lihat, trxK1, true},
+		{"kasir lain lihat transaksi", kasir2, Transaksi, Lihat, trxK1, false},
+		{"kasir pemilik ubah transaksi", kasir1, Transaksi, Ubah, trxK1, true},
+		{"kasir lain hapus transaksi", kasir2, Transaksi, Hapus, trxK1, false},
+		{"driver lihat transaksi", driver1, Transaksi, Lihat, trxK1, false},
+		{"gudang lihat transaksi", gudang, Transaksi, Lihat, trxK1, false},
+		{"api key lihat transaksi", apiKey, Transaksi, Lihat, trxK1, true},
+		{"api key ubah transaksi kasir", apiKey, Transaksi, Ubah, trxK1, false},
+		{"api key ubah transaksi sendiri", apiKey, Transaksi, Ubah, &models.Transaksi{KasirID: "KEY001"}, true},
+
+		// Pembayaran
+		{"kasir pemilik lihat pembayaran", kasir1, Pembayaran, Lihat, payK1, true},
+		{"kasir lain ubah pembayaran", kasir2, Pembayaran, Ubah, payK1, false},
+		{"kasir hapus pembayaran", kasir1, Pembayaran, Hapus, payK1, false},
+
+		// Pengiriman
+		{"admin lihat pengiriman", admin, Pengiriman, Lihat, krmD1, true},
+		{"kasir pemilik transaksi lihat pengiriman", kasir1, Pengiriman, Lihat, krmD1, true},
+		{"kasir lain lihat pengiriman", kasir2, Pengiriman, Lihat, krmD1, false},
+		{"kasir pemilik hapus pengiriman", kasir1, Pengiriman, Hapus, krmD1, true},
+		{"kasir transaksi hilang", kasir1, Pengiriman, Ubah, krmTanpaTrx, false},
+		{"driver ditugaskan lihat pengiriman", driver1, Pengiriman, Lihat, krmD1, true},
+		{"driver ditugaskan ubah pengiriman", driver1, Pengiriman, Ubah, krmD1, true},
+		{"driver lain ubah pengiriman", driver2, Pengiriman, Ubah, krmD1, false},
+		{"driver hapus pengiriman", driver1, Pengiriman, Hapus, krmD1, false},
+		{"gudang ubah pengiriman", gudang, Pengiriman, Ubah, krmD1, false},
+
+		// Perjalanan
+		{"kasir lihat perjalanan", kasir2, Perjalanan, Lihat, pjlD1, true},
+		{"kasir pembuat ubah perjalanan", kasir1, Perjalanan, Ubah, pjlD1, true},
+		{"kasir lain ubah perjalanan", kasir2, Perjalanan, Ubah, pjlD1, false},
+		{"kasir pembuat hapus perjalanan", kasir1, Perjalanan, Hapus, pjlD1, true},
+		{"driver ditugaskan ubah perjalanan", driver1, Perjalanan, Ubah, pjlD1, true},
+		{"driver lain lihat perjalanan", driver2, Perjalanan, Lihat, pjlD1, false},
+		{"driver hapus perjalanan", driver1, Perjalanan, Hapus, pjlD1, false},
+
+		// Shift driver
+		{"driver lihat shift sendiri", driver1, ShiftDriver, Lihat, shiftD1, true},
+		{"driver lihat shift lain", driver2, ShiftDriver, Lihat, shiftD1, false},
+		{"kasir ubah shift", kasir1, ShiftDriver, Ubah, shiftD1, false},
+		{"kasir lihat shift kasir sendiri", kasir1, ShiftKasir, Lihat, &models.ShiftKasir{KasirID: "K1"}, true},
+		{"kasir lihat shift kasir lain", kasir2, ShiftKasir, Lihat, &models.ShiftKasir{KasirID: "K1"}, false},
+
+		// Dokumen tidak dikenal / subjek tanpa user ID
+		{"tipe dokumen salah", kasir1, Transaksi, Lihat, models.Transaksi{KasirID: "K1"}, false},
+		{"user id kosong", Subjek{Role: "kasir"}, Transaksi, Lihat, &models.Transaksi{}, false},
+	}
+	p := New(stubResolver{})
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			got, err := p.Izinkan(tt.s, tt.src, tt.aksi, tt.doc)
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("izinkan = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolverGagal(t *testing.T) {
+	errDB := errors.New("db mati")
+	p := New(stubResolver{err: errDB})
+
+	if _, err := p.Scope(kasir1, Pengiriman); err != errDB {
+		t.Fatalf("Scope err = %v, want %v", err, errDB)
+	}
+	ok, err := p.Izinkan(kasir1, Pengiriman, Lihat, &models.Pengiriman{TransaksiID: "TRX1"})
+	if ok || err != errDB {
+		t.Fatalf("Izinkan = %v, %v; want false, %v", ok, err, errDB)
+	}
+	// Driver tidak butuh resolver sehingga tetap bisa diputuskan
+	if ok, err := p.Izinkan(driver1, Pengiriman, Lihat, &models.Pengiriman{DriverID: "D1"}); !ok || err != nil {
+		t.Fatalf("Izinkan driver = %v, %v; want true, nil", ok, err)
+	}
+}
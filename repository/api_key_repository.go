@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"backend/config"
+	"backend/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func apiKeyCol() *mongo.Collection { return config.DB.Collection("api_key") }
+
+// last_used_at cukup diperbarui paling sering sekali per menit agar tidak menulis di setiap request
+const apiKeyTouchInterval = time.Minute
+
+func EnsureApiKeyIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := apiKeyCol().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "prefix", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func CreateApiKey(k *models.ApiKey) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := apiKeyCol().InsertOne(ctx, k)
+	return err
+}
+
+func ListApiKeys() ([]models.ApiKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cur, err := apiKeyCol().Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	list := []models.ApiKey{}
+	if err := cur.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func GetApiKeyByID(id string) (*models.ApiKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var k models.ApiKey
+	if err := apiKeyCol().FindOne(ctx, bson.M{"_id": id}).Decode(&k); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+func GetApiKeyByPrefix(prefix string) (*models.ApiKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var k models.ApiKey
+	if err := apiKeyCol().FindOne(ctx, bson.M{"prefix": prefix}).Decode(&k); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// RevokeApiKey mencabut key; key yang sudah dicabut tidak berubah
+func RevokeApiKey(id string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	res, err := apiKeyCol().UpdateOne(ctx,
+		bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// TouchApiKey mencatat waktu & IP pemakaian terakhir (dibatasi apiKeyTouchInterval)
+func TouchApiKey(id, ip string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	now := time.Now()
+	_, err := apiKeyCol().UpdateOne(ctx,
+		bson.M{"_id": id, "$or": bson.A{
+			bson.M{"last_used_at": bson.M{"$exists": false}},
+			bson.M{"last_used_at": bson.M{"$lt": now.Add(-apiKeyTouchInterval)}},
+		}},
+		bson.M{"$set": bson.M{"last_used_at": now, "last_used_ip": ip}},
+	)
+	return err
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"backend/config"
+	"backend/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func auditCol() *mongo.Collection { return config.DB.Collection("audit_log") }
+
+func EnsureAuditIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := auditCol().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "entitas", Value: 1}, {Key: "ref_id", Value: 1}, {Key: "waktu", Value: -1}}},
+		{Keys: bson.D{{Key: "aktor_id", Value: 1}, {Key: "waktu", Value: -1}}},
+		{Keys: bson.D{{Key: "waktu", Value: -1}}},
+	})
+	return err
+}
+
+// GetDokumenAudit mengambil dokumen mentah dari koleksi entitas untuk snapshot audit.
+// Mengembalikan nil tanpa error jika dokumen tidak ada.
+func GetDokumenAudit(koleksi, id string) (bson.M, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var doc bson.M
+	err := config.DB.Collection(koleksi).FindOne(ctx, bson.M{"_id": id}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func CreateAuditLog(a *models.AuditLog) error {
+	id, err := GenerateID("log")
+	if err != nil {
+		return err
+	}
+	a.ID = id
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err = auditCol().InsertOne(ctx, a)
+	return err
+}
+
+func ListAuditLog(filter bson.M, page, pageSize int) ([]models.AuditLog, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	total, err := auditCol().CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "waktu", Value: -1}}).
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+	cur, err := auditCol().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+	list := []models.AuditLog{}
+	if err := cur.All(ctx, &list); err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
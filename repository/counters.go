@@ -27,8 +27,14 @@ func InitializeCounters() error {
 		{"_id": "transaksi", "prefix": "TRX", "sequence_value": 1},
 		{"_id": "pembayaran", "prefix": "BYR", "sequence_value": 1},
 		{"_id": "pengiriman", "prefix": "KRM", "sequence_value": 1},
+		{"_id": "perjalanan", "prefix": "TRP", "sequence_value": 1},
+		{"_id": "kendaraan", "prefix": "KND", "sequence_value": 1},
+		{"_id": "shift_driver", "prefix": "SFT", "sequence_value": 1},
 		{"_id": "stok", "prefix": "STK", "sequence_value": 2},
 		{"_id": "log", "prefix": "LOG", "sequence_value": 1},
+		{"_id": "api_key", "prefix": "KEY", "sequence_value": 1},
+		{"_id": "shift_kasir", "prefix": "SKS", "sequence_value": 1},
+		{"_id": "laporan_job", "prefix": "JOB", "sequence_value": 1},
 		// Tambahkan counter untuk role gudang agar pembuatan ID karyawan gudang berhasil
 		{"_id": "gudang", "prefix": "GDG", "sequence_value": 1},
 	}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BarisKartuStok adalah satu mutasi pada kartu stok beserta saldo berjalannya
+type BarisKartuStok struct {
+	ID         string    `json:"id"`
+	Tanggal    time.Time `json:"tanggal"`
+	Jenis      string    `json:"jenis"`
+	RefType    string    `json:"ref_type,omitempty"`
+	RefID      string    `json:"ref_id,omitempty"`
+	Keterangan string    `json:"keterangan,omitempty"`
+	UserID     string    `json:"user_id"`
+	Masuk      int       `json:"masuk"`
+	Keluar     int       `json:"keluar"`
+	Saldo      int       `json:"saldo"`
+}
+
+// KartuStok adalah kartu stok satu produk dalam periode [start, end)
+type KartuStok struct {
+	ProdukID   string           `json:"produk_id"`
+	NamaProduk string           `json:"nama_produk"`
+	SaldoAwal  int              `json:"saldo_awal"`
+	Masuk      int              `json:"masuk"`
+	Keluar     int              `json:"keluar"`
+	SaldoAkhir int              `json:"saldo_akhir"`
+	Mutasi     []BarisKartuStok `json:"mutasi"`
+}
+
+// saldoSebelum menghitung saldo (masuk - keluar) per produk dari semua mutasi sebelum waktu t
+func saldoSebelum(ctx context.Context, t time.Time, produkID string) (map[string]int, error) {
+	match := bson.M{"created_at": bson.M{"$lt": t}}
+	if produkID != "" {
+		match["produk_id"] = produkID
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$produk_id"},
+			{Key: "saldo", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$switch", Value: bson.D{
+				{Key: "branches", Value: bson.A{
+					bson.D{{Key: "case", Value: bson.D{{Key: "$eq", Value: bson.A{"$jenis", "masuk"}}}}, {Key: "then", Value: "$jumlah"}},
+					bson.D{{Key: "case", Value: bson.D{{Key: "$eq", Value: bson.A{"$jenis", "keluar"}}}}, {Key: "then", Value: bson.D{{Key: "$multiply", Value: bson.A{"$jumlah", -1}}}}},
+				}},
+				{Key: "default", Value: 0},
+			}}}}}},
+		}}},
+	}
+	cur, err := stokCol().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	saldo := map[string]int{}
+	for cur.Next(ctx) {
+		var row struct {
+			ProdukID string `bson:"_id"`
+			Saldo    int    `bson:"saldo"`
+		}
+		if err := cur.Decode(&row); err != nil {
+			return nil, err
+		}
+		saldo[row.ProdukID] = row.Saldo
+	}
+	return saldo, cur.Err()
+}
+
+// GetKartuStok menyusun kartu stok per produk untuk periode [start, end): saldo awal dari
+// seluruh mutasi sebelum start, mutasi dalam periode urut waktu dengan saldo berjalan, dan
+// saldo akhir. produkID kosong = semua produk yang punya saldo awal atau mutasi di periode.
+func GetKartuStok(produkID string, start, end time.Time) ([]KartuStok, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	awal, err := saldoSebelum(ctx, start, produkID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"created_at": bson.M{"$gte": start, "$lt": end}}
+	if produkID != "" {
+		filter["produk_id"] = produkID
+	}
+	// _id sebagai pengurut kedua agar mutasi di detik yang sama tetap stabil
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := stokCol().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	susun := newPenyusunKartuStok(produkID, awal)
+	for cur.Next(ctx) {
+		var m models.StokMutasi
+		if err := cur.Decode(&m); err != nil {
+			return nil, err
+		}
+		susun.tambah(m)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	produkList, err := GetAllProduk()
+	if err != nil {
+		return nil, err
+	}
+	nama := make(map[string]string, len(produkList))
+	for _, p := range produkList {
+		nama[p.ID] = p.NamaProduk
+	}
+	return susun.hasil(nama), nil
+}
+
+// penyusunKartuStok menyusun kartu stok dari saldo awal per produk dan mutasi periode yang
+// diberikan urut waktu; tidak menyentuh database sehingga bisa diuji langsung
+type penyusunKartuStok struct {
+	awal  map[string]int
+	kartu map[string]*KartuStok
+}
+
+// newPenyusunKartuStok menyiapkan kartu untuk produkID (jika diisi) dan setiap produk yang
+// saldo awalnya tidak nol, walaupun tidak ada mutasi dalam periode
+func newPenyusunKartuStok(produkID string, awal map[string]int) *penyusunKartuStok {
+	s := &penyusunKartuStok{awal: awal, kartu: map[string]*KartuStok{}}
+	if produkID != "" {
+		s.ambil(produkID)
+	}
+	for id, saldo := range awal {
+		if saldo != 0 {
+			s.ambil(id)
+		}
+	}
+	return s
+}
+
+func (s *penyusunKartuStok) ambil(id string) *KartuStok {
+	k, ok := s.kartu[id]
+	if !ok {
+		k = &KartuStok{ProdukID: id, SaldoAwal: s.awal[id], SaldoAkhir: s.awal[id], Mutasi: []BarisKartuStok{}}
+		s.kartu[id] = k
+	}
+	return k
+}
+
+// tambah mencatat satu mutasi periode dengan saldo berjalan melanjutkan saldo sebelumnya
+func (s *penyusunKartuStok) tambah(m models.StokMutasi) {
+	k := s.ambil(m.ProdukID)
+	b := BarisKartuStok{
+		ID:         m.ID,
+		Tanggal:    m.CreatedAt,
+		Jenis:      m.Jenis,
+		RefType:    m.RefType,
+		RefID:      m.RefID,
+		Keterangan: m.Keterangan,
+		UserID:     m.UserID,
+	}
+	switch m.Jenis {
+	case "masuk":
+		b.Masuk = m.Jumlah
+	case "keluar":
+		b.Keluar = m.Jumlah
+	}
+	k.Masuk += b.Masuk
+	k.Keluar += b.Keluar
+	k.SaldoAkhir += b.Masuk - b.Keluar
+	b.Saldo = k.SaldoAkhir
+	k.Mutasi = append(k.Mutasi, b)
+}
+
+// hasil mengembalikan kartu urut nama produk (lalu ID) dengan nama dari peta nama
+func (s *penyusunKartuStok) hasil(nama map[string]string) []KartuStok {
+	list := make([]KartuStok, 0, len(s.kartu))
+	for id, k := range s.kartu {
+		k.NamaProduk = nama[id]
+		list = append(list, *k)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].NamaProduk != list[j].NamaProduk {
+			return list[i].NamaProduk < list[j].NamaProduk
+		}
+		return list[i].ProdukID < list[j].ProdukID
+	})
+	return list
+}
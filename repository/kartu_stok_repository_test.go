@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"backend/models"
+	"testing"
+	"time"
+)
+
+func TestKartuStokMelanjutkanSaldoAwal(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+	mutasi := func(id, produk, jenis string, jumlah int, menit int) models.StokMutasi {
+		return models.StokMutasi{ID: id, ProdukID: produk, Jenis: jenis, Jumlah: jumlah, CreatedAt: start.Add(time.Duration(menit) * time.Minute)}
+	}
+	// Saldo awal hasil mutasi sebelum start: P1 10, P2 habis (0), P3 5 tanpa mutasi di periode
+	awal := map[string]int{"P1": 10, "P2": 0, "P3": 5}
+	susun := newPenyusunKartuStok("", awal)
+	for _, m := range []models.StokMutasi{
+		mutasi("S1", "P1", "keluar", 3, 0), // tepat di batas start
+		mutasi("S2", "P1", "masuk", 5, 10),
+		mutasi("S3", "P4", "masuk", 2, 20), // produk baru di periode
+		mutasi("S4", "P1", "keluar", 12, 30),
+	} {
+		susun.tambah(m)
+	}
+	kartu := susun.hasil(map[string]string{"P1": "Beras", "P3": "Gula", "P4": "Minyak"})
+
+	type harap struct {
+		awal, masuk, keluar, akhir int
+		saldo                      []int
+	}
+	tests := map[string]harap{
+		"P1": {10, 5, 15, 0, []int{7, 12, 0}},
+		"P3": {5, 0, 0, 5, nil},
+		"P4": {0, 2, 0, 2, []int{2}},
+	}
+	if len(kartu) != len(tests) {
+		t.Fatalf("jumlah kartu = %d, want %d (produk bersaldo 0 tanpa mutasi tidak ikut)", len(kartu), len(tests))
+	}
+	for _, k := range kartu {
+		want, ok := tests[k.ProdukID]
+		if !ok {
+			t.Errorf("kartu %s tidak diharapkan", k.ProdukID)
+			continue
+		}
+		if k.SaldoAwal != want.awal || k.Masuk != want.masuk || k.Keluar != want.keluar || k.SaldoAkhir != want.akhir {
+			t.Errorf("%s: awal/masuk/keluar/akhir = %d/%d/%d/%d, want %d/%d/%d/%d", k.ProdukID,
+				k.SaldoAwal, k.Masuk, k.Keluar, k.SaldoAkhir, want.awal, want.masuk, want.keluar, want.akhir)
+		}
+		if len(k.Mutasi) != len(want.saldo) {
+			t.Errorf("%s: %d baris mutasi, want %d", k.ProdukID, len(k.Mutasi), len(want.saldo))
+			continue
+		}
+		for i, b := range k.Mutasi {
+			if b.Saldo != want.saldo[i] {
+				t.Errorf("%s baris %d: saldo %d, want %d", k.ProdukID, i, b.Saldo, want.saldo[i])
+			}
+		}
+	}
+	if kartu[0].NamaProduk != "Beras" || kartu[2].NamaProduk != "Minyak" {
+		t.Errorf("urutan kartu harus per nama produk: %s, %s, %s", kartu[0].NamaProduk, kartu[1].NamaProduk, kartu[2].NamaProduk)
+	}
+}
+
+func TestKartuStokProdukTanpaMutasi(t *testing.T) {
+	kartu := newPenyusunKartuStok("P9", map[string]int{}).hasil(nil)
+	if len(kartu) != 1 || kartu[0].ProdukID != "P9" || kartu[0].SaldoAkhir != 0 || kartu[0].Mutasi == nil {
+		t.Errorf("produk yang diminta harus tetap punya kartu kosong, got %+v", kartu)
+	}
+}
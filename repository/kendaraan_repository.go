@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"backend/config"
+	"backend/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func kendaraanCol() *mongo.Collection { return config.DB.Collection("kendaraan") }
+
+func EnsureKendaraanIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ci := &options.Collation{Locale: "en", Strength: 2} // case-insensitive
+	_, err := kendaraanCol().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "plat_nomor", Value: 1}},
+			Options: options.Index().SetName("uniq_plat_nomor_ci").SetUnique(true).SetCollation(ci),
+		},
+		{Keys: bson.D{{Key: "driver_id", Value: 1}}},
+	})
+	return err
+}
+
+func GetKendaraanFiltered(filter bson.M) ([]models.Kendaraan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cur, err := kendaraanCol().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var list []models.Kendaraan
+	for cur.Next(ctx) {
+		var k models.Kendaraan
+		if err := cur.Decode(&k); err != nil {
+			return nil, err
+		}
+		list = append(list, k)
+	}
+	return list, nil
+}
+
+func GetKendaraanByID(id string) (*models.Kendaraan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var k models.Kendaraan
+	if err := kendaraanCol().FindOne(ctx, bson.M{"_id": id}).Decode(&k); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+func CreateKendaraan(k *models.Kendaraan) (*mongo.InsertOneResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return kendaraanCol().InsertOne(ctx, k)
+}
+
+func UpdateKendaraan(id string, update bson.M) (*mongo.UpdateResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return kendaraanCol().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+}
+
+func DeleteKendaraan(id string) (*mongo.DeleteResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return kendaraanCol().DeleteOne(ctx, bson.M{"_id": id})
+}
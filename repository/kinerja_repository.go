@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// KinerjaKasir merangkum transaksi satu kasir dalam periode
+type KinerjaKasir struct {
+	KasirID           string  `json:"kasir_id" bson:"_id"`
+	KasirNama         string  `json:"kasir_nama" bson:"kasir_nama"`
+	JumlahTransaksi   int     `json:"jumlah_transaksi" bson:"jumlah_transaksi"` // tidak termasuk batal
+	Pendapatan        float64 `json:"pendapatan" bson:"pendapatan"`
+	RataRataKeranjang float64 `json:"rata_rata_keranjang" bson:"rata_rata_keranjang"`
+	Dibatalkan        int     `json:"dibatalkan" bson:"dibatalkan"`
+}
+
+// KinerjaDriver merangkum pengiriman satu driver dalam periode
+type KinerjaDriver struct {
+	DriverID        string  `json:"driver_id"`
+	DriverNama      string  `json:"driver_nama"`
+	TotalPengiriman int     `json:"total_pengiriman"`
+	Selesai         int     `json:"selesai"`
+	Dibatalkan      int     `json:"dibatalkan"`
+	RataRataMenit   float64 `json:"rata_rata_menit"` // rata-rata mulai antar → selesai
+	OngkirTerkumpul float64 `json:"ongkir_terkumpul"`
+}
+
+// GetKinerjaKasir mengelompokkan transaksi per kasir. match memakai field created_at transaksi.
+func GetKinerjaKasir(match bson.M) ([]KinerjaKasir, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	batal := bson.M{"$eq": bson.A{bson.M{"$toLower": "$status"}, "batal"}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":              "$kasir_id",
+			"jumlah_transaksi": bson.M{"$sum": bson.M{"$cond": bson.A{batal, 0, 1}}},
+			"pendapatan":       bson.M{"$sum": bson.M{"$cond": bson.A{batal, 0, "$total_harga"}}},
+			"dibatalkan":       bson.M{"$sum": bson.M{"$cond": bson.A{batal, 1, 0}}},
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"rata_rata_keranjang": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{"$jumlah_transaksi", 0}},
+				bson.M{"$divide": bson.A{"$pendapatan", "$jumlah_transaksi"}},
+				0,
+			}},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "user",
+			"localField":   "_id",
+			"foreignField": "_id",
+			"as":           "kasir",
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"kasir_nama": bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$kasir.nama", 0}}, "$_id"}},
+		}}},
+		{{Key: "$project", Value: bson.M{"kasir": 0}}},
+		{{Key: "$sort", Value: bson.M{"pendapatan": -1}}},
+	}
+	cur, err := transaksiCol().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	list := []KinerjaKasir{}
+	if err := cur.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// waktuAntar mengambil rentang mulai antar → selesai dari riwayat status pengiriman.
+// Jika status "dikirim" tidak tercatat, waktu pembuatan pengiriman dipakai sebagai awal.
+func waktuAntar(p models.Pengiriman) (time.Duration, bool) {
+	mulai := p.CreatedAt
+	var selesai time.Time
+	adaMulai := false
+	for _, l := range p.RiwayatStatus {
+		if l.Entitas != "" && l.Entitas != "pengiriman" {
+			continue
+		}
+		switch strings.ToLower(l.Ke) {
+		case "dikirim", "sedang diantarkan", "sedang di antar":
+			if !adaMulai {
+				mulai = l.Waktu
+				adaMulai = true
+			}
+		case "selesai":
+			selesai = l.Waktu
+		}
+	}
+	if selesai.IsZero() || selesai.Before(mulai) {
+		return 0, false
+	}
+	return selesai.Sub(mulai), true
+}
+
+// GetKinerjaDriver mengelompokkan pengiriman per driver. match memakai field created_at pengiriman.
+func GetKinerjaDriver(match bson.M) ([]KinerjaDriver, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetProjection(bson.M{
+		"driver_id": 1, "status": 1, "ongkir": 1, "riwayat_status": 1, "created_at": 1,
+	})
+	cur, err := pengirimanCol().Find(ctx, match, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	type akumulasi struct {
+		KinerjaDriver
+		totalDurasi  time.Duration
+		jumlahDurasi int
+	}
+	per := map[string]*akumulasi{}
+	for cur.Next(ctx) {
+		var p models.Pengiriman
+		if err := cur.Decode(&p); err != nil {
+			return nil, err
+		}
+		a, ok := per[p.DriverID]
+		if !ok {
+			a = &akumulasi{KinerjaDriver: KinerjaDriver{DriverID: p.DriverID, DriverNama: p.DriverID}}
+			per[p.DriverID] = a
+		}
+		a.TotalPengiriman++
+		switch strings.ToLower(p.Status) {
+		case "selesai":
+			a.Selesai++
+			a.OngkirTerkumpul += p.Ongkir
+			if d, ok := waktuAntar(p); ok {
+				a.totalDurasi += d
+				a.jumlahDurasi++
+			}
+		case "batal":
+			a.Dibatalkan++
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(per))
+	for id := range per {
+		ids = append(ids, id)
+	}
+	if len(ids) > 0 {
+		curUser, err := userCol().Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetProjection(bson.M{"nama": 1}))
+		if err != nil {
+			return nil, err
+		}
+		defer curUser.Close(ctx)
+		for curUser.Next(ctx) {
+			var u models.User
+			if err := curUser.Decode(&u); err == nil {
+				if a, ok := per[u.ID]; ok {
+					a.DriverNama = u.Nama
+				}
+			}
+		}
+	}
+
+	list := make([]KinerjaDriver, 0, len(per))
+	for _, a := range per {
+		if a.jumlahDurasi > 0 {
+			a.RataRataMenit = a.totalDurasi.Minutes() / float64(a.jumlahDurasi)
+		}
+		list = append(list, a.KinerjaDriver)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Selesai != list[j].Selesai {
+			return list[i].Selesai > list[j].Selesai
+		}
+		return list[i].DriverID < list[j].DriverID
+	})
+	return list, nil
+}
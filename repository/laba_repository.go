@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BarisLaba adalah laba kotor satu kelompok (produk, kategori, kasir, atau hari)
+type BarisLaba struct {
+	Kunci     string  `json:"kunci"`
+	Nama      string  `json:"nama"`
+	Jumlah    int     `json:"jumlah"` // unit terjual
+	Penjualan float64 `json:"penjualan"`
+	HPP       float64 `json:"hpp"`
+	LabaKotor float64 `json:"laba_kotor"`
+	Margin    float64 `json:"margin"` // persen laba kotor terhadap penjualan
+}
+
+// LaporanLaba merangkum laba kotor & margin dalam periode
+type LaporanLaba struct {
+	MetodeHPP string      `json:"metode_hpp"`
+	Total     BarisLaba   `json:"total"`
+	TanpaHPP  int         `json:"item_tanpa_hpp"` // item lama tanpa snapshot HPP; diestimasi dari harga pokok produk saat ini
+	Produk    []BarisLaba `json:"per_produk"`
+	Kategori  []BarisLaba `json:"per_kategori"`
+	Kasir     []BarisLaba `json:"per_kasir"`
+	Hari      []BarisLaba `json:"per_hari"`
+}
+
+func (b *BarisLaba) tambah(qty int, penjualan, hpp float64) {
+	b.Jumlah += qty
+	b.Penjualan += penjualan
+	b.HPP += hpp
+}
+
+func (b *BarisLaba) hitungMargin() {
+	b.LabaKotor = b.Penjualan - b.HPP
+	if b.Penjualan != 0 {
+		b.Margin = b.LabaKotor / b.Penjualan * 100
+	}
+}
+
+func urutkanLaba(per map[string]*BarisLaba, berdasarKunci bool) []BarisLaba {
+	list := make([]BarisLaba, 0, len(per))
+	for _, b := range per {
+		b.hitungMargin()
+		list = append(list, *b)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !berdasarKunci && list[i].LabaKotor != list[j].LabaKotor {
+			return list[i].LabaKotor > list[j].LabaKotor
+		}
+		return list[i].Kunci < list[j].Kunci
+	})
+	return list
+}
+
+// GetLaporanLaba menghitung laba kotor dari item transaksi (tidak termasuk batal).
+// match memakai field created_at transaksi. HPP diambil dari snapshot harga_beli item;
+// item tanpa snapshot diestimasi dengan harga pokok produk saat ini sesuai metode.
+func GetLaporanLaba(match bson.M, metode string) (*LaporanLaba, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	produkList, err := GetAllProduk()
+	if err != nil {
+		return nil, err
+	}
+	produk := make(map[string]models.Produk, len(produkList))
+	for _, p := range produkList {
+		produk[p.ID] = p
+	}
+	kategoriList, err := GetAllKategori()
+	if err != nil {
+		return nil, err
+	}
+	namaKategori := make(map[string]string, len(kategoriList))
+	for _, k := range kategoriList {
+		namaKategori[k.ID] = k.NamaKategori
+	}
+
+	filter := bson.M{}
+	for k, v := range match {
+		filter[k] = v
+	}
+	filter["status"] = bson.M{"$not": bson.M{"$regex": "^batal$", "$options": "i"}}
+	opts := options.Find().SetProjection(bson.M{"kasir_id": 1, "items": 1, "created_at": 1})
+	cur, err := transaksiCol().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	lap := &LaporanLaba{MetodeHPP: metode, Total: BarisLaba{Kunci: "total", Nama: "Total"}}
+	perProduk := map[string]*BarisLaba{}
+	perKategori := map[string]*BarisLaba{}
+	perKasir := map[string]*BarisLaba{}
+	perHari := map[string]*BarisLaba{}
+	ambil := func(per map[string]*BarisLaba, kunci, nama string) *BarisLaba {
+		b, ok := per[kunci]
+		if !ok {
+			b = &BarisLaba{Kunci: kunci, Nama: nama}
+			per[kunci] = b
+		}
+		return b
+	}
+
+	for cur.Next(ctx) {
+		var t models.Transaksi
+		if err := cur.Decode(&t); err != nil {
+			return nil, err
+		}
+		hari := t.CreatedAt.In(time.Local).Format("2006-01-02")
+		for _, it := range t.Items {
+			p, adaProduk := produk[it.ProdukID]
+			hargaBeli := it.HargaBeli
+			if hargaBeli == 0 {
+				lap.TanpaHPP++
+				if adaProduk {
+					hargaBeli = p.HargaPokok(metode)
+				}
+			}
+			penjualan := it.Harga * float64(it.Jumlah)
+			hpp := hargaBeli * float64(it.Jumlah)
+
+			nama := it.NamaProduk
+			if nama == "" {
+				nama = p.NamaProduk
+			}
+			kategoriID := p.KategoriID
+			if strings.TrimSpace(kategoriID) == "" {
+				kategoriID = "-"
+			}
+			kategoriNama := namaKategori[kategoriID]
+			if kategoriNama == "" {
+				kategoriNama = "Tanpa Kategori"
+			}
+
+			ambil(perProduk, it.ProdukID, nama).tambah(it.Jumlah, penjualan, hpp)
+			ambil(perKategori, kategoriID, kategoriNama).tambah(it.Jumlah, penjualan, hpp)
+			ambil(perKasir, t.KasirID, t.KasirID).tambah(it.Jumlah, penjualan, hpp)
+			ambil(perHari, hari, hari).tambah(it.Jumlah, penjualan, hpp)
+			lap.Total.tambah(it.Jumlah, penjualan, hpp)
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(perKasir))
+	for id := range perKasir {
+		ids = append(ids, id)
+	}
+	if len(ids) > 0 {
+		curUser, err := userCol().Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetProjection(bson.M{"nama": 1}))
+		if err != nil {
+			return nil, err
+		}
+		defer curUser.Close(ctx)
+		for curUser.Next(ctx) {
+			var u models.User
+			if err := curUser.Decode(&u); err == nil {
+				if b, ok := perKasir[u.ID]; ok {
+					b.Nama = u.Nama
+				}
+			}
+		}
+	}
+
+	lap.Total.hitungMargin()
+	lap.Produk = urutkanLaba(perProduk, false)
+	lap.Kategori = urutkanLaba(perKategori, false)
+	lap.Kasir = urutkanLaba(perKasir, false)
+	lap.Hari = urutkanLaba(perHari, true)
+	return lap, nil
+}
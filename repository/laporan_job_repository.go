@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"backend/config"
+	"backend/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func laporanJobCol() *mongo.Collection { return config.DB.Collection("laporan_job") }
+
+// EnsureLaporanJobIndexes membuat index daftar job per user, antrean, dan kadaluarsa.
+// Tidak memakai TTL index karena file hasil harus dihapus bersama dokumennya.
+func EnsureLaporanJobIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := laporanJobCol().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "dibuat_oleh", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}},
+	})
+	return err
+}
+
+func CreateLaporanJob(j *models.LaporanJob) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := laporanJobCol().InsertOne(ctx, j)
+	return err
+}
+
+func GetLaporanJobByID(id string) (*models.LaporanJob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var j models.LaporanJob
+	if err := laporanJobCol().FindOne(ctx, bson.M{"_id": id}).Decode(&j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// ListLaporanJob mengambil job terbaru sesuai filter (maksimal limit, 0 = semua)
+func ListLaporanJob(filter bson.M, limit int64) ([]models.LaporanJob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+	cur, err := laporanJobCol().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	list := []models.LaporanJob{}
+	if err := cur.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// AmbilLaporanJob menandai job antri menjadi proses secara atomik agar satu job hanya
+// dikerjakan satu worker. mongo.ErrNoDocuments jika job sudah diambil / tidak ada.
+func AmbilLaporanJob(id string) (*models.LaporanJob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	now := time.Now()
+	var j models.LaporanJob
+	err := laporanJobCol().FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "status": models.JobAntri},
+		bson.M{"$set": bson.M{"status": models.JobProses, "mulai_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&j)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// SelesaikanLaporanJob menyimpan hasil akhir job (status selesai/gagal beserta file atau pesan)
+func SelesaikanLaporanJob(id string, set bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	set["selesai_at"] = time.Now()
+	_, err := laporanJobCol().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	return err
+}
+
+// KembalikanLaporanJobProses mengembalikan job "proses" ke antrean (worker berhenti di
+// tengah jalan, mis. server restart)
+func KembalikanLaporanJobProses() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := laporanJobCol().UpdateMany(ctx,
+		bson.M{"status": models.JobProses},
+		bson.M{"$set": bson.M{"status": models.JobAntri}, "$unset": bson.M{"mulai_at": ""}},
+	)
+	return err
+}
+
+// GagalkanLaporanJobMacet menandai gagal job "proses" yang mulai sebelum batas (worker macet
+// atau mati tanpa menyimpan hasil) dan mengembalikan jumlah job yang ditandai
+func GagalkanLaporanJobMacet(batas time.Time, pesan string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	res, err := laporanJobCol().UpdateMany(ctx,
+		bson.M{"status": models.JobProses, "mulai_at": bson.M{"$lt": batas}},
+		bson.M{"$set": bson.M{"status": models.JobGagal, "pesan": pesan, "selesai_at": time.Now()}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+func DeleteLaporanJob(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := laporanJobCol().DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"backend/config"
+	"backend/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func loginAttemptCol() *mongo.Collection  { return config.DB.Collection("login_attempt") }
+func loginThrottleCol() *mongo.Collection { return config.DB.Collection("login_throttle") }
+
+// Catatan percobaan login disimpan 90 hari
+const loginAttemptRetensi = 90 * 24 * time.Hour
+
+// EnsureLoginIndexes membuat index pencarian log login & TTL untuk penghitung throttle
+func EnsureLoginIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := loginAttemptCol().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "email", Value: 1}, {Key: "waktu", Value: -1}}},
+		{Keys: bson.D{{Key: "ip", Value: 1}, {Key: "waktu", Value: -1}}},
+		{Keys: bson.D{{Key: "waktu", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(loginAttemptRetensi.Seconds()))},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = loginThrottleCol().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+func CreateLoginAttempt(a *models.LoginAttempt) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := loginAttemptCol().InsertOne(ctx, a)
+	return err
+}
+
+// ListLoginAttempts mengembalikan log login terbaru dulu beserta total untuk paginasi
+func ListLoginAttempts(filter bson.M, page, pageSize int) ([]models.LoginAttempt, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	total, err := loginAttemptCol().CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "waktu", Value: -1}}).
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+	cur, err := loginAttemptCol().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+	list := []models.LoginAttempt{}
+	if err := cur.All(ctx, &list); err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// GetLoginThrottles mengambil penghitung untuk beberapa kunci sekaligus (yang belum ada dilewati)
+func GetLoginThrottles(keys ...string) ([]models.LoginThrottle, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cur, err := loginThrottleCol().Find(ctx, bson.M{"_id": bson.M{"$in": keys}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var list []models.LoginThrottle
+	if err := cur.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// IncrementLoginThrottle menambah hitungan gagal untuk key dan mengembalikan nilai terbaru.
+// Penghitung kedaluwarsa setelah window tanpa kegagalan baru.
+func IncrementLoginThrottle(key string, window time.Duration) (*models.LoginThrottle, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	now := time.Now()
+	var t models.LoginThrottle
+	err := loginThrottleCol().FindOneAndUpdate(ctx,
+		bson.M{"_id": key},
+		bson.M{
+			"$inc": bson.M{"gagal": 1},
+			"$set": bson.M{"terakhir_gagal": now, "expires_at": now.Add(window)},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&t)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func SetLoginThrottleBlokir(key string, sampai time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := loginThrottleCol().UpdateOne(ctx, bson.M{"_id": key}, bson.M{"$set": bson.M{"blokir_sampai": sampai}})
+	return err
+}
+
+func DeleteLoginThrottle(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := loginThrottleCol().DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
+
+// IncrementUserGagalLogin menambah hitungan login gagal berturut-turut milik user
+func IncrementUserGagalLogin(id string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var u models.User
+	err := userCol().FindOneAndUpdate(ctx,
+		bson.M{"_id": id},
+		bson.M{"$inc": bson.M{"gagal_login": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&u)
+	if err != nil {
+		return 0, err
+	}
+	return u.GagalLogin, nil
+}
+
+// LockUser mengunci akun sampai waktu tertentu; hitungan gagal dimulai ulang setelah kunci berakhir
+func LockUser(id string, sampai time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := userCol().UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set":   bson.M{"terkunci_sampai": sampai},
+		"$unset": bson.M{"gagal_login": ""},
+	})
+	return err
+}
+
+// UnlockUser menghapus kunci akun dan hitungan login gagal
+func UnlockUser(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	res, err := userCol().UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$unset": bson.M{"gagal_login": "", "terkunci_sampai": ""},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -60,14 +60,18 @@ func UpdatePelanggan(id string, p models.Pelanggan) (*mongo.UpdateResult, error)
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	update := bson.M{
-		"$set": bson.M{
-			"nama":   p.Nama,
-			"email":  p.Email,
-			"no_hp":  p.NoHP,
-			"alamat": p.Alamat,
-		},
+	set := bson.M{
+		"nama":   p.Nama,
+		"email":  p.Email,
+		"no_hp":  p.NoHP,
+		"alamat": p.Alamat,
 	}
+	// Koordinat hanya diupdate jika dikirim
+	if p.Latitude != nil && p.Longitude != nil {
+		set["latitude"] = *p.Latitude
+		set["longitude"] = *p.Longitude
+	}
+	update := bson.M{"$set": set}
 
 	return pelangganCol().UpdateOne(ctx, bson.M{"_id": id}, update)
 }
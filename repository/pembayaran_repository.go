@@ -19,6 +19,8 @@ func EnsurePembayaranIndexes() error {
 	_, err := pembayaranCol().Indexes().CreateMany(ctx, []mongo.IndexModel{
 		{Keys: bson.D{{Key: "kasir_id", Value: 1}}},
 		{Keys: bson.D{{Key: "transaksi_id", Value: 1}}},
+		{Keys: bson.D{{Key: "shift_id", Value: 1}}},
+		{Keys: bson.D{{Key: "shift_selesai_id", Value: 1}}},
 		{Keys: bson.D{{Key: "created_at", Value: -1}}},
 	})
 	return err
@@ -72,6 +74,24 @@ func GetPembayaranFiltered(filter bson.M) ([]models.Pembayaran, error) {
 	return list, nil
 }
 
+// GetMetodePembayaran mengembalikan nilai metode apa adanya yang pernah tersimpan (termasuk
+// variasi penulisan lama seperti "Tunai" atau kosong)
+func GetMetodePembayaran() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	vals, err := pembayaranCol().Distinct(ctx, "metode", bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(vals))
+	for _, v := range vals {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
 // Ambil satu pembayaran berdasarkan ID
 func GetPembayaranByID(id string) (*models.Pembayaran, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -120,14 +140,24 @@ func DeletePembayaran(id string) (*mongo.DeleteResult, error) {
 	return pembayaranCol().DeleteOne(ctx, bson.M{"_id": id})
 }
 
-// Set status transaksi jadi "Selesai"
-func SelesaikanPembayaran(id string) error {
+// SelesaikanPembayaran menandai pembayaran selesai beserta shift kasir penerima pelunasan
+// (kosong jika dilunasi di luar shift kasir)
+func SelesaikanPembayaran(id, shiftSelesaiID string, waktu time.Time) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	filter := bson.M{"_id": id}
-	update := bson.M{"$set": bson.M{"status": "Selesai"}}
-
-	_, err := pembayaranCol().UpdateOne(ctx, filter, update)
+	set := bson.M{"status": "Selesai", "selesai_at": waktu}
+	if shiftSelesaiID != "" {
+		set["shift_selesai_id"] = shiftSelesaiID
+	}
+	_, err := pembayaranCol().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
 	return err
 }
+
+// GetPembayaranShift mengambil pembayaran yang dibuat di shift atau dilunasi di shift tersebut
+func GetPembayaranShift(shiftID string) ([]models.Pembayaran, error) {
+	return GetPembayaranFiltered(bson.M{"$or": bson.A{
+		bson.M{"shift_id": shiftID},
+		bson.M{"shift_selesai_id": shiftID},
+	}})
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"backend/config"
+	"backend/models"
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func pengaturanCol() *mongo.Collection { return config.DB.Collection("pengaturan") }
+
+const pengaturanKeamananID = "keamanan"
+
+// Pengaturan keamanan dibaca di setiap request (middleware), jadi di-cache sebentar
+var (
+	keamananMu     sync.Mutex
+	keamananCache  *models.PengaturanKeamanan
+	keamananDibaca time.Time
+)
+
+const keamananCacheTTL = 30 * time.Second
+
+// GetPengaturanKeamanan mengembalikan pengaturan keamanan (nilai default jika belum pernah disimpan)
+func GetPengaturanKeamanan() (*models.PengaturanKeamanan, error) {
+	keamananMu.Lock()
+	defer keamananMu.Unlock()
+	if keamananCache != nil && time.Since(keamananDibaca) < keamananCacheTTL {
+		p := *keamananCache
+		return &p, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	p := models.PengaturanKeamanan{ID: pengaturanKeamananID}
+	err := pengaturanCol().FindOne(ctx, bson.M{"_id": pengaturanKeamananID}).Decode(&p)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	keamananCache = &p
+	keamananDibaca = time.Now()
+	cp := p
+	return &cp, nil
+}
+
+func SavePengaturanKeamanan(p *models.PengaturanKeamanan) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	p.ID = pengaturanKeamananID
+	_, err := pengaturanCol().ReplaceOne(ctx, bson.M{"_id": p.ID}, p, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	keamananMu.Lock()
+	cp := *p
+	keamananCache = &cp
+	keamananDibaca = time.Now()
+	keamananMu.Unlock()
+	return nil
+}
+
+const pengaturanLaporanID = "laporan"
+
+// exportKecualiLama adalah ID pembayaran yang dulu dikecualikan tetap di kode export;
+// dipakai sampai admin menyimpan daftar pengecualian sendiri (termasuk daftar kosong)
+var exportKecualiLama = []string{"PMB001", "PMB002", "PMB003", "PMB004", "PMB005"}
+
+// GetPengaturanLaporan mengembalikan pengaturan laporan (default metode HPP: harga beli terakhir)
+func GetPengaturanLaporan() (*models.PengaturanLaporan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	p := models.PengaturanLaporan{ID: pengaturanLaporanID}
+	err := pengaturanCol().FindOne(ctx, bson.M{"_id": pengaturanLaporanID}).Decode(&p)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	if p.MetodeHPP == "" {
+		p.MetodeHPP = models.MetodeHPPTerakhir
+	}
+	if len(p.ExportStatus) == 0 {
+		p.ExportStatus = []string{"pending", "selesai"}
+	}
+	if p.ExportKecualiPembayaran == nil {
+		p.ExportKecualiPembayaran = append([]string{}, exportKecualiLama...)
+	}
+	return &p, nil
+}
+
+func SavePengaturanLaporan(p *models.PengaturanLaporan) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	p.ID = pengaturanLaporanID
+	_, err := pengaturanCol().ReplaceOne(ctx, bson.M{"_id": p.ID}, p, options.Replace().SetUpsert(true))
+	return err
+}
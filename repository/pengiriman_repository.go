@@ -19,7 +19,12 @@ func EnsurePengirimanIndexes() error {
 	_, err := pengirimanCol().Indexes().CreateMany(ctx, []mongo.IndexModel{
 		{Keys: bson.D{{Key: "driver_id", Value: 1}}},
 		{Keys: bson.D{{Key: "transaksi_id", Value: 1}}},
+		{Keys: bson.D{{Key: "kendaraan_id", Value: 1}}},
 		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "tracking_nonce", Value: 1}},
+			Options: options.Index().SetName("uniq_tracking_nonce").SetUnique(true).SetSparse(true),
+		},
 	})
 	return err
 }
@@ -85,3 +90,55 @@ func DeletePengiriman(id string) (*mongo.DeleteResult, error) {
 	defer cancel()
 	return pengirimanCol().DeleteOne(ctx, bson.M{"_id": id})
 }
+
+// Hitung pengiriman aktif (belum selesai/batal) milik driver
+func CountPengirimanAktifByDriver(driverID string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	filter := bson.M{
+		"driver_id": driverID,
+		"status":    bson.M{"$nin": []string{"selesai", "Selesai", "batal", "Batal"}},
+	}
+	return pengirimanCol().CountDocuments(ctx, filter)
+}
+
+// Hitung pengiriman aktif yang memakai kendaraan tertentu oleh driver selain driverID
+func CountPengirimanAktifKendaraanLain(kendaraanID, driverID string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	filter := bson.M{
+		"kendaraan_id": kendaraanID,
+		"driver_id":    bson.M{"$ne": driverID},
+		"status":       bson.M{"$nin": []string{"selesai", "Selesai", "batal", "Batal"}},
+	}
+	return pengirimanCol().CountDocuments(ctx, filter)
+}
+
+// Tambahkan entri riwayat status (append-only) ke pengiriman
+func AppendPengirimanRiwayat(id string, logs ...models.StatusLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	update := bson.M{"$push": bson.M{"riwayat_status": bson.M{"$each": logs}}}
+	_, err := pengirimanCol().UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func GetPengirimanByTrackingNonce(nonce string) (*models.Pengiriman, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var p models.Pengiriman
+	if err := pengirimanCol().FindOne(ctx, bson.M{"tracking_nonce": nonce}).Decode(&p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func SetPengirimanTrackingNonce(id, nonce string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := pengirimanCol().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"tracking_nonce": nonce}})
+	return err
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Interval pengelompokan ringkasan penjualan
+const (
+	IntervalHari   = "day"
+	IntervalMinggu = "week"
+	IntervalBulan  = "month"
+)
+
+// TitikPenjualan adalah ringkasan penjualan satu periode (hari, minggu ISO, atau bulan)
+type TitikPenjualan struct {
+	Periode         string  `json:"periode" bson:"_id"` // 2006-01-02, 2006-W01, atau 2006-01
+	Pendapatan      float64 `json:"pendapatan" bson:"pendapatan"`
+	JumlahTransaksi int     `json:"jumlah_transaksi" bson:"jumlah_transaksi"`
+	ItemTerjual     int     `json:"item_terjual" bson:"item_terjual"`
+	Ongkir          float64 `json:"ongkir" bson:"ongkir"`
+}
+
+// Tambah menjumlahkan titik lain ke titik ini (untuk total periode)
+func (t *TitikPenjualan) Tambah(o TitikPenjualan) {
+	t.Pendapatan += o.Pendapatan
+	t.JumlahTransaksi += o.JumlahTransaksi
+	t.ItemTerjual += o.ItemTerjual
+	t.Ongkir += o.Ongkir
+}
+
+func formatInterval(interval string) string {
+	switch interval {
+	case IntervalMinggu:
+		return "%G-W%V"
+	case IntervalBulan:
+		return "%Y-%m"
+	}
+	return "%Y-%m-%d"
+}
+
+// kunciPeriode menghasilkan kunci periode yang sama dengan $dateToString di agregasi
+func kunciPeriode(t time.Time, interval string) string {
+	t = t.In(time.Local)
+	switch interval {
+	case IntervalMinggu:
+		y, w := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", y, w)
+	case IntervalBulan:
+		return t.Format("2006-01")
+	}
+	return t.Format("2006-01-02")
+}
+
+// awalInterval membulatkan waktu ke awal periodenya (awal hari, Senin, atau tanggal 1)
+func awalInterval(t time.Time, interval string) time.Time {
+	t = t.In(time.Local)
+	hari := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	switch interval {
+	case IntervalMinggu:
+		return hari.AddDate(0, 0, -((int(hari.Weekday()) + 6) % 7))
+	case IntervalBulan:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+	}
+	return hari
+}
+
+func langkahInterval(t time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalMinggu:
+		return t.AddDate(0, 0, 7)
+	case IntervalBulan:
+		return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.Local)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// offset zona waktu server (mis. +07:00) agar pengelompokan mengikuti tanggal lokal
+func zonaLokal() string {
+	return time.Now().Format("-07:00")
+}
+
+// GetSeriPenjualan mengelompokkan transaksi (tidak termasuk batal) dan ongkir pengiriman
+// (tidak termasuk batal) milik transaksi tersebut dalam [start, end) per interval. Periode tanpa penjualan tetap
+// muncul dengan nilai 0. scope (hasil policy.Transaksi) digabung ke filter transaksi.
+func GetSeriPenjualan(start, end time.Time, interval string, scope bson.M) ([]TitikPenjualan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	periode := bson.M{"$dateToString": bson.M{
+		"format":   formatInterval(interval),
+		"date":     "$created_at",
+		"timezone": zonaLokal(),
+	}}
+	bukanBatal := bson.M{"$not": bson.M{"$regex": "^batal$", "$options": "i"}}
+
+	match := bson.M{"created_at": bson.M{"$gte": start, "$lt": end}, "status": bukanBatal}
+	for k, v := range scope {
+		match[k] = v
+	}
+	cur, err := transaksiCol().Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":              periode,
+			"pendapatan":       bson.M{"$sum": "$total_harga"},
+			"jumlah_transaksi": bson.M{"$sum": 1},
+			"item_terjual":     bson.M{"$sum": "$total_produk"},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var trx []TitikPenjualan
+	if err := cur.All(ctx, &trx); err != nil {
+		return nil, err
+	}
+
+	// Ongkir ikut periode transaksi induknya (bukan tanggal pengiriman dibuat) agar sejajar
+	// dengan pendapatan: pengiriman digabung ke transaksi lewat index transaksi_id.
+	curOngkir, err := transaksiCol().Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$project", Value: bson.M{"_id": 1, "created_at": 1}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "pengiriman",
+			"localField":   "_id",
+			"foreignField": "transaksi_id",
+			"as":           "krm",
+		}}},
+		{{Key: "$unwind", Value: "$krm"}},
+		{{Key: "$match", Value: bson.M{"krm.status": bukanBatal}}},
+		{{Key: "$group", Value: bson.M{"_id": periode, "ongkir": bson.M{"$sum": "$krm.ongkir"}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer curOngkir.Close(ctx)
+	var ongkir []TitikPenjualan
+	if err := curOngkir.All(ctx, &ongkir); err != nil {
+		return nil, err
+	}
+
+	per := map[string]*TitikPenjualan{}
+	list := []TitikPenjualan{}
+	for t := awalInterval(start, interval); t.Before(end); t = langkahInterval(t, interval) {
+		list = append(list, TitikPenjualan{Periode: kunciPeriode(t, interval)})
+	}
+	for i := range list {
+		per[list[i].Periode] = &list[i]
+	}
+	for _, sumber := range [][]TitikPenjualan{trx, ongkir} {
+		for _, p := range sumber {
+			if t, ok := per[p.Periode]; ok {
+				t.Tambah(p)
+			}
+		}
+	}
+	return list, nil
+}
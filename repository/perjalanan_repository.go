@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"backend/config"
+	"backend/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func perjalananCol() *mongo.Collection { return config.DB.Collection("perjalanan") }
+
+func EnsurePerjalananIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := perjalananCol().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "driver_id", Value: 1}, {Key: "tanggal", Value: -1}}},
+		{Keys: bson.D{{Key: "stops.pengiriman_id", Value: 1}}},
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+	})
+	return err
+}
+
+func CreatePerjalanan(p *models.Perjalanan) (*mongo.InsertOneResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return perjalananCol().InsertOne(ctx, p)
+}
+
+func GetPerjalananFiltered(filter bson.M) ([]models.Perjalanan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	opts := options.Find().SetSort(bson.D{{Key: "tanggal", Value: -1}, {Key: "created_at", Value: -1}})
+	cur, err := perjalananCol().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var list []models.Perjalanan
+	for cur.Next(ctx) {
+		var p models.Perjalanan
+		if err := cur.Decode(&p); err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	return list, nil
+}
+
+func GetPerjalananByID(id string) (*models.Perjalanan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var p models.Perjalanan
+	if err := perjalananCol().FindOne(ctx, bson.M{"_id": id}).Decode(&p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func UpdatePerjalanan(id string, update bson.M) (*mongo.UpdateResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return perjalananCol().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+}
+
+// Update status satu stop (berdasarkan pengiriman_id) di dalam perjalanan
+func UpdatePerjalananStopStatus(id, pengirimanID, status string, at time.Time) (*mongo.UpdateResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	filter := bson.M{"_id": id, "stops.pengiriman_id": pengirimanID}
+	update := bson.M{"$set": bson.M{"stops.$.status": status, "stops.$.tiba_at": at}}
+	return perjalananCol().UpdateOne(ctx, filter, update)
+}
+
+func DeletePerjalanan(id string) (*mongo.DeleteResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return perjalananCol().DeleteOne(ctx, bson.M{"_id": id})
+}
+
+// Set / lepas perjalanan_id pada sekumpulan pengiriman
+func SetPerjalananPengiriman(pengirimanIDs []string, perjalananID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	filter := bson.M{"_id": bson.M{"$in": pengirimanIDs}}
+	update := bson.M{"$set": bson.M{"perjalanan_id": perjalananID}}
+	if perjalananID == "" {
+		update = bson.M{"$unset": bson.M{"perjalanan_id": ""}}
+	}
+	_, err := pengirimanCol().UpdateMany(ctx, filter, update)
+	return err
+}
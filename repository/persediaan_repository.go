@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"backend/models"
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Sumber harga pokok pada laporan persediaan
+const (
+	SumberHargaMutasi = "mutasi" // dari penerimaan barang ber-harga di ledger stok
+	SumberHargaProduk = "produk" // belum ada penerimaan ber-harga; harga pokok produk saat ini
+)
+
+// BarisPersediaan adalah nilai persediaan satu produk
+type BarisPersediaan struct {
+	ProdukID    string  `json:"produk_id"`
+	NamaProduk  string  `json:"nama_produk"`
+	Jumlah      int     `json:"jumlah"`
+	HargaPokok  float64 `json:"harga_pokok"`
+	Nilai       float64 `json:"nilai"`
+	SumberHarga string  `json:"sumber_harga"`
+}
+
+// KategoriPersediaan merangkum nilai persediaan satu kategori
+type KategoriPersediaan struct {
+	KategoriID   string            `json:"kategori_id"`
+	NamaKategori string            `json:"nama_kategori"`
+	Jumlah       int               `json:"jumlah"`
+	Nilai        float64           `json:"nilai"`
+	Produk       []BarisPersediaan `json:"produk"`
+}
+
+// LaporanPersediaan adalah nilai persediaan per tanggal, dikelompokkan per kategori
+type LaporanPersediaan struct {
+	Tanggal         string               `json:"tanggal"`
+	MetodeHPP       string               `json:"metode_hpp"`
+	Jumlah          int                  `json:"jumlah"`
+	Nilai           float64              `json:"nilai"`
+	HargaDariProduk int                  `json:"harga_dari_produk"` // produk tanpa penerimaan ber-harga sampai tanggal ini
+	SaldoNegatif    int                  `json:"saldo_negatif"`     // produk bersaldo negatif; tidak dinilai
+	Kategori        []KategoriPersediaan `json:"per_kategori"`
+}
+
+// posisiStok adalah hasil pemutaran ulang ledger satu produk
+type posisiStok struct {
+	saldo    int
+	terakhir float64
+	rata     float64
+}
+
+// terapkan memutar satu mutasi ke posisi: penerimaan ber-harga memperbarui harga beli terakhir
+// dan rata-rata bergerak (saldo negatif dianggap nol sebagai bobot), lalu saldo disesuaikan.
+// Mutasi harus diputar urut created_at, _id.
+func (p *posisiStok) terapkan(m models.StokMutasi) {
+	switch m.Jenis {
+	case "masuk":
+		if m.HargaBeli > 0 {
+			lama := float64(p.saldo)
+			if lama < 0 {
+				lama = 0
+			}
+			if p.rata == 0 {
+				p.rata = m.HargaBeli
+			} else {
+				p.rata = (lama*p.rata + float64(m.Jumlah)*m.HargaBeli) / (lama + float64(m.Jumlah))
+			}
+			p.terakhir = m.HargaBeli
+		}
+		p.saldo += m.Jumlah
+	case "keluar":
+		p.saldo -= m.Jumlah
+	}
+}
+
+// posisiLedgerProduk memutar ulang seluruh ledger satu produk sampai saat ini
+func posisiLedgerProduk(produkID string) (*posisiStok, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"produk_id": 1, "jenis": 1, "jumlah": 1, "harga_beli": 1})
+	cur, err := stokCol().Find(ctx, bson.M{"produk_id": produkID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	pos := &posisiStok{}
+	for cur.Next(ctx) {
+		var m models.StokMutasi
+		if err := cur.Decode(&m); err != nil {
+			return nil, err
+		}
+		pos.terapkan(m)
+	}
+	return pos, cur.Err()
+}
+
+// GetNilaiPersediaan menghitung persediaan per akhir tanggal: jumlah dari ledger stok
+// (mutasi sebelum batas) dikali harga pokok sesuai metode. Ledger diputar ulang agar harga
+// beli terakhir & rata-rata bergerak sesuai kondisi pada tanggal tersebut, dengan rumus yang
+// sama seperti saat penerimaan barang dicatat.
+func GetNilaiPersediaan(tanggal, batas time.Time, metode string) (*LaporanPersediaan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"produk_id": 1, "jenis": 1, "jumlah": 1, "harga_beli": 1})
+	cur, err := stokCol().Find(ctx, bson.M{"created_at": bson.M{"$lt": batas}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	posisi := map[string]*posisiStok{}
+	for cur.Next(ctx) {
+		var m models.StokMutasi
+		if err := cur.Decode(&m); err != nil {
+			return nil, err
+		}
+		p, ok := posisi[m.ProdukID]
+		if !ok {
+			p = &posisiStok{}
+			posisi[m.ProdukID] = p
+		}
+		p.terapkan(m)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	produkList, err := GetAllProduk()
+	if err != nil {
+		return nil, err
+	}
+	produk := make(map[string]models.Produk, len(produkList))
+	for _, p := range produkList {
+		produk[p.ID] = p
+	}
+	kategoriList, err := GetAllKategori()
+	if err != nil {
+		return nil, err
+	}
+	namaKategori := make(map[string]string, len(kategoriList))
+	for _, k := range kategoriList {
+		namaKategori[k.ID] = k.NamaKategori
+	}
+
+	return susunPersediaan(tanggal, metode, posisi, produk, namaKategori), nil
+}
+
+// susunPersediaan menilai posisi stok per produk dengan harga pokok sesuai metode lalu
+// mengelompokkannya per kategori. Produk tanpa penerimaan ber-harga memakai harga pokok
+// produk saat ini; saldo negatif dihitung terpisah dan tidak dinilai.
+func susunPersediaan(tanggal time.Time, metode string, posisi map[string]*posisiStok, produk map[string]models.Produk, namaKategori map[string]string) *LaporanPersediaan {
+	lap := &LaporanPersediaan{Tanggal: tanggal.Format("2006-01-02"), MetodeHPP: metode}
+	perKategori := map[string]*KategoriPersediaan{}
+	for id, pos := range posisi {
+		if pos.saldo == 0 {
+			continue
+		}
+		if pos.saldo < 0 {
+			lap.SaldoNegatif++
+			continue
+		}
+		p, adaProduk := produk[id]
+		b := BarisPersediaan{ProdukID: id, NamaProduk: p.NamaProduk, Jumlah: pos.saldo, SumberHarga: SumberHargaMutasi}
+		if metode == models.MetodeHPPRataRata {
+			b.HargaPokok = pos.rata
+		} else {
+			b.HargaPokok = pos.terakhir
+		}
+		if b.HargaPokok == 0 {
+			lap.HargaDariProduk++
+			b.SumberHarga = SumberHargaProduk
+			if adaProduk {
+				b.HargaPokok = p.HargaPokok(metode)
+			}
+		}
+		if b.NamaProduk == "" {
+			b.NamaProduk = id
+		}
+		b.Nilai = b.HargaPokok * float64(b.Jumlah)
+
+		kategoriID := p.KategoriID
+		if strings.TrimSpace(kategoriID) == "" {
+			kategoriID = "-"
+		}
+		k, ok := perKategori[kategoriID]
+		if !ok {
+			nama := namaKategori[kategoriID]
+			if nama == "" {
+				nama = "Tanpa Kategori"
+			}
+			k = &KategoriPersediaan{KategoriID: kategoriID, NamaKategori: nama}
+			perKategori[kategoriID] = k
+		}
+		k.Jumlah += b.Jumlah
+		k.Nilai += b.Nilai
+		k.Produk = append(k.Produk, b)
+		lap.Jumlah += b.Jumlah
+		lap.Nilai += b.Nilai
+	}
+
+	lap.Kategori = make([]KategoriPersediaan, 0, len(perKategori))
+	for _, k := range perKategori {
+		sort.Slice(k.Produk, func(i, j int) bool {
+			if k.Produk[i].NamaProduk != k.Produk[j].NamaProduk {
+				return k.Produk[i].NamaProduk < k.Produk[j].NamaProduk
+			}
+			return k.Produk[i].ProdukID < k.Produk[j].ProdukID
+		})
+		lap.Kategori = append(lap.Kategori, *k)
+	}
+	sort.Slice(lap.Kategori, func(i, j int) bool {
+		return lap.Kategori[i].NamaKategori < lap.Kategori[j].NamaKategori
+	})
+	return lap
+}
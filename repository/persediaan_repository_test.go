@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"backend/models"
+	"math"
+	"testing"
+	"time"
+)
+
+// putarUji memutar ulang mutasi uji per produk seperti GetNilaiPersediaan
+func putarUji(mutasi []models.StokMutasi) map[string]*posisiStok {
+	posisi := map[string]*posisiStok{}
+	for _, m := range mutasi {
+		p, ok := posisi[m.ProdukID]
+		if !ok {
+			p = &posisiStok{}
+			posisi[m.ProdukID] = p
+		}
+		p.terapkan(m)
+	}
+	return posisi
+}
+
+func TestRataRataBergerak(t *testing.T) {
+	masuk := func(jumlah int, harga float64) models.StokMutasi {
+		return models.StokMutasi{ProdukID: "P", Jenis: "masuk", Jumlah: jumlah, HargaBeli: harga}
+	}
+	keluar := func(jumlah int) models.StokMutasi {
+		return models.StokMutasi{ProdukID: "P", Jenis: "keluar", Jumlah: jumlah}
+	}
+	tests := []struct {
+		nama     string
+		mutasi   []models.StokMutasi
+		saldo    int
+		terakhir float64
+		rata     float64
+	}{
+		{"satu penerimaan", []models.StokMutasi{masuk(10, 100)}, 10, 100, 100},
+		{"berbobot sisa saldo", []models.StokMutasi{masuk(10, 100), keluar(4), masuk(6, 130)}, 12, 130, 115},
+		{"saldo negatif berbobot nol", []models.StokMutasi{masuk(5, 40), keluar(9), masuk(10, 50)}, 6, 50, 50},
+		{"penerimaan tanpa harga tidak mengubah harga", []models.StokMutasi{masuk(10, 50), masuk(10, 0), masuk(20, 80)}, 40, 80, 65},
+		{"belum ada harga", []models.StokMutasi{masuk(3, 0), keluar(1)}, 2, 0, 0},
+	}
+	for _, tt := range tests {
+		p := putarUji(tt.mutasi)["P"]
+		if p.saldo != tt.saldo || p.terakhir != tt.terakhir || math.Abs(p.rata-tt.rata) > 1e-9 {
+			t.Errorf("%s: saldo/terakhir/rata = %d/%v/%v, want %d/%v/%v", tt.nama, p.saldo, p.terakhir, p.rata, tt.saldo, tt.terakhir, tt.rata)
+		}
+	}
+}
+
+func TestSusunPersediaan(t *testing.T) {
+	posisi := putarUji([]models.StokMutasi{
+		{ProdukID: "P1", Jenis: "masuk", Jumlah: 10, HargaBeli: 100},
+		{ProdukID: "P1", Jenis: "keluar", Jumlah: 4},
+		{ProdukID: "P1", Jenis: "masuk", Jumlah: 6, HargaBeli: 130},
+		{ProdukID: "P2", Jenis: "masuk", Jumlah: 3},  // tanpa harga: pakai harga produk
+		{ProdukID: "P3", Jenis: "keluar", Jumlah: 2}, // negatif: tidak dinilai
+		{ProdukID: "P4", Jenis: "masuk", Jumlah: 1, HargaBeli: 10},
+		{ProdukID: "P4", Jenis: "keluar", Jumlah: 1}, // habis: tidak tampil
+	})
+	produk := map[string]models.Produk{
+		"P1": {ID: "P1", NamaProduk: "Beras", KategoriID: "K1"},
+		"P2": {ID: "P2", NamaProduk: "Gula", KategoriID: "K1", HargaBeli: 20, HargaPokokRata: 25},
+	}
+	kategori := map[string]string{"K1": "Sembako"}
+	tanggal := time.Date(2026, 3, 31, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		metode         string
+		hargaP1, nilai float64
+		hargaP2        float64
+	}{
+		{models.MetodeHPPTerakhir, 130, 12*130 + 3*20, 20},
+		{models.MetodeHPPRataRata, 115, 12*115 + 3*25, 25},
+	}
+	for _, tt := range tests {
+		lap := susunPersediaan(tanggal, tt.metode, posisi, produk, kategori)
+		if lap.Jumlah != 15 || math.Abs(lap.Nilai-tt.nilai) > 1e-9 {
+			t.Errorf("%s: jumlah/nilai = %d/%v, want 15/%v", tt.metode, lap.Jumlah, lap.Nilai, tt.nilai)
+		}
+		if lap.SaldoNegatif != 1 || lap.HargaDariProduk != 1 {
+			t.Errorf("%s: saldo negatif/harga dari produk = %d/%d, want 1/1", tt.metode, lap.SaldoNegatif, lap.HargaDariProduk)
+		}
+		if len(lap.Kategori) != 1 || lap.Kategori[0].NamaKategori != "Sembako" || len(lap.Kategori[0].Produk) != 2 {
+			t.Fatalf("%s: kategori = %+v", tt.metode, lap.Kategori)
+		}
+		p1, p2 := lap.Kategori[0].Produk[0], lap.Kategori[0].Produk[1]
+		if p1.ProdukID != "P1" || math.Abs(p1.HargaPokok-tt.hargaP1) > 1e-9 || p1.SumberHarga != SumberHargaMutasi {
+			t.Errorf("%s: P1 = %+v", tt.metode, p1)
+		}
+		if p2.ProdukID != "P2" || p2.HargaPokok != tt.hargaP2 || p2.SumberHarga != SumberHargaProduk {
+			t.Errorf("%s: P2 = %+v", tt.metode, p2)
+		}
+	}
+}
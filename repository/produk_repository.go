@@ -118,3 +118,52 @@ func DeleteProduk(id string) (*mongo.DeleteResult, error) {
 
 	return produkCol().DeleteOne(ctx, bson.M{"_id": id})
 }
+
+// SinkronHargaPokokProduk menghitung ulang harga beli terakhir & rata-rata bergerak produk dari
+// ledger stok lalu menyimpannya dengan cek versi. Karena dihitung dari seluruh ledger (bukan
+// dari nilai produk yang dibaca sebelumnya), penerimaan yang tercatat bersamaan tidak saling
+// menimpa: penyimpanan yang kalah versi mengulang hitung dengan data terbaru.
+func SinkronHargaPokokProduk(id string) error {
+	for i := 0; i < 5; i++ {
+		p, err := GetProdukByID(id)
+		if err != nil {
+			return err
+		}
+		pos, err := posisiLedgerProduk(id)
+		if err != nil {
+			return err
+		}
+		if pos.terakhir == 0 {
+			// Belum ada penerimaan ber-harga; harga pokok tetap dari data produk
+			return nil
+		}
+		ok, err := simpanHargaPokokProduk(id, p.HPPVersi, pos.terakhir, pos.rata)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+	return errors.New("harga pokok produk terus berubah, coba lagi")
+}
+
+// simpanHargaPokokProduk menyimpan harga pokok hanya jika hpp_versi masih sama dengan yang dibaca
+func simpanHargaPokokProduk(id string, versi int, hargaBeli, rataRata float64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	filter := bson.M{"_id": id, "hpp_versi": versi}
+	if versi == 0 {
+		// Produk lama belum punya field hpp_versi
+		filter["hpp_versi"] = bson.M{"$in": bson.A{0, nil}}
+	}
+	res, err := produkCol().UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+		"harga_beli":       hargaBeli,
+		"harga_pokok_rata": rataRata,
+		"hpp_versi":        versi + 1,
+	}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
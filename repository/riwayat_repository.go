@@ -2,30 +2,164 @@ package repository
 
 import (
 	"backend/config"
-	"backend/models"
 	"context"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// Ambil pembayaran yang sudah selesai (riwayat)
-func GetRiwayatPembayaran(filter bson.M) ([]models.Pembayaran, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// RiwayatFilter adalah parameter query riwayat pembayaran.
+// KasirID membatasi ke pembayaran milik kasir; DriverID membatasi ke pembayaran
+// yang pengirimannya ditugaskan ke driver tersebut.
+type RiwayatFilter struct {
+	KasirID   string
+	DriverID  string
+	Status    []string
+	CreatedAt bson.M // hasil buildCreatedAtFilterFromQuery (boleh kosong)
+	Page      int
+	PageSize  int
+}
+
+// RiwayatPembayaranRow adalah baris riwayat pembayaran yang sudah digabung
+// dengan transaksi, pelanggan, kasir dan pengiriman terkait.
+type RiwayatPembayaranRow struct {
+	ID               string    `json:"id" bson:"_id"`
+	TransaksiID      string    `json:"transaksi_id" bson:"transaksi_id"`
+	Metode           string    `json:"metode" bson:"metode"`
+	Status           string    `json:"status" bson:"status"`
+	TotalBayar       float64   `json:"total_bayar" bson:"total_bayar"`
+	TotalHarga       float64   `json:"total_harga" bson:"total_harga"`
+	TotalProduk      int       `json:"total_produk" bson:"total_produk"`
+	Ongkir           float64   `json:"ongkir" bson:"ongkir"`
+	PelangganID      string    `json:"pelanggan_id" bson:"pelanggan_id"`
+	PelangganNama    string    `json:"pelanggan_nama" bson:"pelanggan_nama"`
+	KasirID          string    `json:"kasir_id" bson:"kasir_id"`
+	KasirNama        string    `json:"kasir_nama" bson:"kasir_nama"`
+	PengirimanID     string    `json:"pengiriman_id,omitempty" bson:"pengiriman_id,omitempty"`
+	DriverID         string    `json:"driver_id,omitempty" bson:"driver_id,omitempty"`
+	DriverNama       string    `json:"driver_nama,omitempty" bson:"driver_nama,omitempty"`
+	JenisPengiriman  string    `json:"jenis_pengiriman,omitempty" bson:"jenis_pengiriman,omitempty"`
+	StatusPengiriman string    `json:"status_pengiriman,omitempty" bson:"status_pengiriman,omitempty"`
+	CreatedAt        time.Time `json:"created_at" bson:"created_at"`
+}
+
+// GetRiwayatPembayaran menjalankan aggregation pembayaran -> transaksi -> pengiriman
+// dan mengembalikan satu halaman data beserta total baris.
+func GetRiwayatPembayaran(f RiwayatFilter) ([]RiwayatPembayaranRow, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 	defer cancel()
-	filter["status"] = "Selesai"
-	cursor, err := config.PembayaranCollection.Find(ctx, filter)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-	var list []models.Pembayaran
-	for cursor.Next(ctx) {
-		var p models.Pembayaran
-		if err := cursor.Decode(&p); err != nil {
-			return nil, err
+
+	match := bson.M{}
+	for k, v := range f.CreatedAt {
+		match[k] = v
+	}
+	if len(f.Status) > 0 {
+		match["status"] = bson.M{"$in": f.Status}
+	}
+	if f.KasirID != "" {
+		// IMPORTANT: kasir hanya melihat pembayaran miliknya sendiri
+		match["kasir_id"] = f.KasirID
+	}
+	if f.DriverID != "" {
+		// Transaksi driver diambil lebih dulu (index pengiriman.driver_id) agar $lookup
+		// hanya berjalan atas pembayaran yang relevan
+		ids, err := pengirimanCol().Distinct(ctx, "transaksi_id", bson.M{"driver_id": f.DriverID})
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(ids) == 0 {
+			return []RiwayatPembayaranRow{}, 0, nil
 		}
-		list = append(list, p)
+		match["transaksi_id"] = bson.M{"$in": ids}
+	}
+
+	// Pengiriman yang dipakai: milik driver (jika scope driver), selain itu record pertama per transaksi
+	pengirimanExpr := interface{}("$krm")
+	if f.DriverID != "" {
+		pengirimanExpr = bson.M{"$filter": bson.M{
+			"input": "$krm",
+			"as":    "k",
+			"cond":  bson.M{"$eq": bson.A{"$$k.driver_id", f.DriverID}},
+		}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$lookup", Value: bson.M{"from": "transaksi", "localField": "transaksi_id", "foreignField": "_id", "as": "trx"}}},
+		{{Key: "$lookup", Value: bson.M{"from": "pengiriman", "localField": "transaksi_id", "foreignField": "transaksi_id", "as": "krm"}}},
+		{{Key: "$addFields", Value: bson.M{
+			"trx": bson.M{"$arrayElemAt": bson.A{"$trx", 0}},
+			"krm": bson.M{"$arrayElemAt": bson.A{pengirimanExpr, 0}},
+		}}},
+	}
+	if f.DriverID != "" {
+		// IMPORTANT: driver hanya melihat pembayaran yang pengirimannya ditugaskan kepadanya
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"krm.driver_id": f.DriverID}}})
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$lookup", Value: bson.M{"from": "pelanggan", "localField": "trx.pelanggan_id", "foreignField": "_id", "as": "pel"}}},
+		bson.D{{Key: "$lookup", Value: bson.M{"from": "user", "localField": "kasir_id", "foreignField": "_id", "as": "kasir"}}},
+		bson.D{{Key: "$lookup", Value: bson.M{"from": "user", "localField": "krm.driver_id", "foreignField": "_id", "as": "driver"}}},
+		bson.D{{Key: "$project", Value: bson.M{
+			"transaksi_id":      1,
+			"metode":            1,
+			"status":            1,
+			"total_bayar":       1,
+			"created_at":        1,
+			"kasir_id":          1,
+			"total_harga":       bson.M{"$ifNull": bson.A{"$trx.total_harga", 0}},
+			"total_produk":      bson.M{"$ifNull": bson.A{"$trx.total_produk", 0}},
+			"ongkir":            bson.M{"$ifNull": bson.A{"$krm.ongkir", 0}},
+			"pelanggan_id":      bson.M{"$ifNull": bson.A{"$trx.pelanggan_id", ""}},
+			"pelanggan_nama":    bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$pel.nama", 0}}, bson.M{"$ifNull": bson.A{"$trx.pelanggan_id", ""}}}},
+			"kasir_nama":        bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$kasir.nama", 0}}, "$kasir_id"}},
+			"pengiriman_id":     "$krm._id",
+			"driver_id":         "$krm.driver_id",
+			"driver_nama":       bson.M{"$arrayElemAt": bson.A{"$driver.nama", 0}},
+			"jenis_pengiriman":  "$krm.jenis",
+			"status_pengiriman": "$krm.status",
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}}}},
+	)
+
+	dataStages := bson.A{}
+	if f.Page > 0 && f.PageSize > 0 {
+		dataStages = append(dataStages,
+			bson.M{"$skip": int64((f.Page - 1) * f.PageSize)},
+			bson.M{"$limit": int64(f.PageSize)},
+		)
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: bson.M{
+		"data":  dataStages,
+		"total": bson.A{bson.M{"$count": "n"}},
+	}}})
+
+	cur, err := config.PembayaranCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+
+	var out []struct {
+		Data  []RiwayatPembayaranRow `bson:"data"`
+		Total []struct {
+			N int64 `bson:"n"`
+		} `bson:"total"`
+	}
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, 0, err
+	}
+	if len(out) == 0 {
+		return []RiwayatPembayaranRow{}, 0, nil
+	}
+	var total int64
+	if len(out[0].Total) > 0 {
+		total = out[0].Total[0].N
+	}
+	rows := out[0].Data
+	if rows == nil {
+		rows = []RiwayatPembayaranRow{}
 	}
-	return list, nil
+	return rows, total, nil
 }
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"backend/config"
+	"backend/models"
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func rolePermissionCol() *mongo.Collection { return config.DB.Collection("role_permission") }
+
+// Permission role dibaca di setiap request (middleware), jadi di-cache sebentar
+var (
+	rolePermMu     sync.Mutex
+	rolePermCache  map[string]map[string]bool
+	rolePermDibaca time.Time
+)
+
+const rolePermCacheTTL = 30 * time.Second
+
+// EnsureRolePermissions menambahkan permission default ke setiap role.
+// Default yang sudah pernah dipasang dicatat di default_terpasang, jadi
+// permission yang sengaja dicabut admin tidak dipasang ulang, sedangkan
+// permission baru dari rilis berikutnya tetap sampai ke deployment lama.
+func EnsureRolePermissions() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for role, perms := range models.DefaultRolePermissions {
+		var rp models.RolePermission
+		err := rolePermissionCol().FindOne(ctx, bson.M{"_id": role}).Decode(&rp)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+		terpasang := map[string]bool{}
+		for _, p := range rp.DefaultTerpasang {
+			terpasang[p] = true
+		}
+		baru := []string{}
+		for _, p := range perms {
+			if !terpasang[p] {
+				baru = append(baru, p)
+			}
+		}
+		if len(baru) == 0 {
+			continue
+		}
+		_, err = rolePermissionCol().UpdateOne(ctx,
+			bson.M{"_id": role},
+			bson.M{
+				"$addToSet":    bson.M{"permissions": bson.M{"$each": baru}, "default_terpasang": bson.M{"$each": baru}},
+				"$setOnInsert": bson.M{"updated_at": time.Now()},
+			},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	invalidateRolePermCache()
+	return nil
+}
+
+func GetAllRolePermissions() ([]models.RolePermission, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cur, err := rolePermissionCol().Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	list := []models.RolePermission{}
+	if err := cur.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func GetRolePermission(role string) (*models.RolePermission, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var rp models.RolePermission
+	if err := rolePermissionCol().FindOne(ctx, bson.M{"_id": role}).Decode(&rp); err != nil {
+		return nil, err
+	}
+	return &rp, nil
+}
+
+func SaveRolePermission(rp *models.RolePermission) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// $set saja agar catatan default_terpasang tidak ikut terhapus
+	_, err := rolePermissionCol().UpdateOne(ctx,
+		bson.M{"_id": rp.Role},
+		bson.M{"$set": bson.M{"permissions": rp.Permissions, "updated_by": rp.UpdatedBy, "updated_at": rp.UpdatedAt}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+	invalidateRolePermCache()
+	return nil
+}
+
+func invalidateRolePermCache() {
+	rolePermMu.Lock()
+	rolePermCache = nil
+	rolePermMu.Unlock()
+}
+
+// RoleHasPermission memeriksa permission role memakai cache seluruh koleksi
+func RoleHasPermission(role, perm string) (bool, error) {
+	rolePermMu.Lock()
+	defer rolePermMu.Unlock()
+	if rolePermCache == nil || time.Since(rolePermDibaca) >= rolePermCacheTTL {
+		list, err := GetAllRolePermissions()
+		if err != nil {
+			return false, err
+		}
+		cache := make(map[string]map[string]bool, len(list))
+		for _, rp := range list {
+			set := make(map[string]bool, len(rp.Permissions))
+			for _, p := range rp.Permissions {
+				set[p] = true
+			}
+			cache[rp.Role] = set
+		}
+		rolePermCache = cache
+		rolePermDibaca = time.Now()
+	}
+	return rolePermCache[role][perm], nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"backend/config"
+	"backend/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func shiftDriverCol() *mongo.Collection { return config.DB.Collection("shift_driver") }
+
+func EnsureShiftDriverIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := shiftDriverCol().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "driver_id", Value: 1}, {Key: "mulai", Value: -1}}},
+		{Keys: bson.D{{Key: "mulai", Value: 1}, {Key: "selesai", Value: 1}}},
+	})
+	return err
+}
+
+func GetShiftDriverFiltered(filter bson.M) ([]models.ShiftDriver, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	opts := options.Find().SetSort(bson.D{{Key: "mulai", Value: -1}})
+	cur, err := shiftDriverCol().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var list []models.ShiftDriver
+	for cur.Next(ctx) {
+		var s models.ShiftDriver
+		if err := cur.Decode(&s); err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+func GetShiftDriverByID(id string) (*models.ShiftDriver, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var s models.ShiftDriver
+	if err := shiftDriverCol().FindOne(ctx, bson.M{"_id": id}).Decode(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Ambil semua shift driver yang beririsan dengan rentang [start, end)
+func GetShiftDriverInRange(driverID string, start, end time.Time) ([]models.ShiftDriver, error) {
+	filter := bson.M{
+		"mulai":   bson.M{"$lt": end},
+		"selesai": bson.M{"$gt": start},
+	}
+	if driverID != "" {
+		filter["driver_id"] = driverID
+	}
+	return GetShiftDriverFiltered(filter)
+}
+
+func CreateShiftDriver(s *models.ShiftDriver) (*mongo.InsertOneResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return shiftDriverCol().InsertOne(ctx, s)
+}
+
+func UpdateShiftDriver(id string, update bson.M) (*mongo.UpdateResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return shiftDriverCol().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+}
+
+func DeleteShiftDriver(id string) (*mongo.DeleteResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return shiftDriverCol().DeleteOne(ctx, bson.M{"_id": id})
+}
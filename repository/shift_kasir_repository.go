@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"backend/config"
+	"backend/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func shiftKasirCol() *mongo.Collection { return config.DB.Collection("shift_kasir") }
+
+// EnsureShiftKasirIndexes: satu kasir hanya boleh punya satu shift berstatus "buka"
+func EnsureShiftKasirIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := shiftKasirCol().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "kasir_id", Value: 1}},
+			Options: options.Index().SetName("uniq_shift_kasir_buka").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"status": "buka"}),
+		},
+		{Keys: bson.D{{Key: "kasir_id", Value: 1}, {Key: "dibuka_at", Value: -1}}},
+		{Keys: bson.D{{Key: "dibuka_at", Value: -1}}},
+	})
+	return err
+}
+
+func CreateShiftKasir(s *models.ShiftKasir) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := shiftKasirCol().InsertOne(ctx, s)
+	return err
+}
+
+func GetShiftKasirByID(id string) (*models.ShiftKasir, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var s models.ShiftKasir
+	if err := shiftKasirCol().FindOne(ctx, bson.M{"_id": id}).Decode(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetShiftKasirAktif mengambil shift kasir yang masih buka (mongo.ErrNoDocuments jika tidak ada)
+func GetShiftKasirAktif(kasirID string) (*models.ShiftKasir, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var s models.ShiftKasir
+	if err := shiftKasirCol().FindOne(ctx, bson.M{"kasir_id": kasirID, "status": "buka"}).Decode(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func ListShiftKasir(filter bson.M) ([]models.ShiftKasir, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cur, err := shiftKasirCol().Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "dibuka_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	list := []models.ShiftKasir{}
+	if err := cur.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// TutupShiftKasir menandai shift tutup beserta hasil hitung kasir, hanya jika shift masih buka
+// (false jika sudah ditutup). Setelah ini pembayaran baru tidak lagi masuk ke shift; rekap
+// disimpan terpisah lewat SimpanRekapShiftKasir.
+func TutupShiftKasir(id string, kasDihitung float64, setoran map[string]float64, catatan string, waktu time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	res, err := shiftKasirCol().UpdateOne(ctx,
+		bson.M{"_id": id, "status": "buka"},
+		bson.M{"$set": bson.M{
+			"status":        "tutup",
+			"ditutup_at":    waktu,
+			"kas_dihitung":  kasDihitung,
+			"setoran":       setoran,
+			"catatan_tutup": catatan,
+			"rekap_versi":   0,
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// SimpanRekapShiftKasir menyimpan rekap hanya jika rekap_versi masih sama dengan yang dibaca
+// (false jika rekap sudah diperbarui proses lain; hitung ulang dari data terbaru)
+func SimpanRekapShiftKasir(id string, versi int, rekap []models.ShiftRekapMetode, totalSelisih float64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	res, err := shiftKasirCol().UpdateOne(ctx,
+		bson.M{"_id": id, "status": "tutup", "rekap_versi": versi},
+		bson.M{"$set": bson.M{
+			"rekap":         rekap,
+			"total_selisih": totalSelisih,
+			"rekap_versi":   versi + 1,
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
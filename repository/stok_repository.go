@@ -69,13 +69,13 @@ func GetSaldoProduk(produkID string) (*models.StokSaldo, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	pipeline := mongo.Pipeline{
-		{{"$match", bson.D{{Key: "produk_id", Value: produkID}}}},
-		{{"$group", bson.D{
+		{{Key: "$match", Value: bson.D{{Key: "produk_id", Value: produkID}}}},
+		{{Key: "$group", Value: bson.D{
 			{Key: "_id", Value: "$produk_id"},
 			{Key: "masuk", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{bson.D{{Key: "$eq", Value: bson.A{"$jenis", "masuk"}}}, "$jumlah", 0}}}}}},
 			{Key: "keluar", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{bson.D{{Key: "$eq", Value: bson.A{"$jenis", "keluar"}}}, "$jumlah", 0}}}}}},
 		}}},
-		{{"$project", bson.D{
+		{{Key: "$project", Value: bson.D{
 			{Key: "produk_id", Value: "$_id"},
 			{Key: "masuk", Value: 1},
 			{Key: "keluar", Value: 1},
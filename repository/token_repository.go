@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"backend/config"
+	"backend/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func refreshTokenCol() *mongo.Collection  { return config.DB.Collection("refresh_token") }
+func revokedTokenCol() *mongo.Collection  { return config.DB.Collection("revoked_token") }
+func passwordResetCol() *mongo.Collection { return config.DB.Collection("password_reset") }
+func exportLinkCol() *mongo.Collection    { return config.DB.Collection("export_link") }
+
+// EnsureTokenIndexes membuat index pencarian & TTL agar token kadaluarsa terhapus otomatis
+func EnsureTokenIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := refreshTokenCol().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "family_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = revokedTokenCol().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = passwordResetCol().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = exportLinkCol().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+func CreateRefreshToken(t *models.RefreshToken) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := refreshTokenCol().InsertOne(ctx, t)
+	return err
+}
+
+func GetRefreshToken(hash string) (*models.RefreshToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var t models.RefreshToken
+	if err := refreshTokenCol().FindOne(ctx, bson.M{"_id": hash}).Decode(&t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// RotateRefreshToken menandai token lama dicabut & diganti. Hanya berhasil jika token
+// belum dicabut (mencegah dua request refresh paralel memakai token yang sama).
+func RotateRefreshToken(hash, replacedBy string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	now := time.Now()
+	res, err := refreshTokenCol().UpdateOne(ctx,
+		bson.M{"_id": hash, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": now, "replaced_by": replacedBy}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func revokeRefreshTokens(filter bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	filter["revoked_at"] = bson.M{"$exists": false}
+	_, err := refreshTokenCol().UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+	return err
+}
+
+func RevokeRefreshToken(hash string) error {
+	return revokeRefreshTokens(bson.M{"_id": hash})
+}
+
+func RevokeRefreshTokenFamily(familyID string) error {
+	return revokeRefreshTokens(bson.M{"family_id": familyID})
+}
+
+func RevokeRefreshTokensByUser(userID string) error {
+	return revokeRefreshTokens(bson.M{"user_id": userID})
+}
+
+// RevokeAccessToken memasukkan jti ke daftar cabut sampai token aslinya kadaluarsa
+func RevokeAccessToken(jti, userID string, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	opts := options.Update().SetUpsert(true)
+	_, err := revokedTokenCol().UpdateOne(ctx,
+		bson.M{"_id": jti},
+		bson.M{"$setOnInsert": bson.M{"user_id": userID, "expires_at": expiresAt}},
+		opts,
+	)
+	return err
+}
+
+func IsAccessTokenRevoked(jti string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	n, err := revokedTokenCol().CountDocuments(ctx, bson.M{"_id": jti}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// RevokeAllUserTokens mencabut semua sesi user: access token (via token_version) dan refresh token
+func RevokeAllUserTokens(userID string) error {
+	if err := IncrementTokenVersion(userID); err != nil {
+		return err
+	}
+	return RevokeRefreshTokensByUser(userID)
+}
+
+// CreatePasswordReset menyimpan token reset baru; token lama user yang belum dipakai dihapus
+// agar hanya link terakhir yang berlaku.
+func CreatePasswordReset(t *models.PasswordResetToken) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := passwordResetCol().DeleteMany(ctx, bson.M{"user_id": t.UserID, "used_at": bson.M{"$exists": false}}); err != nil {
+		return err
+	}
+	_, err := passwordResetCol().InsertOne(ctx, t)
+	return err
+}
+
+// ConsumePasswordReset menandai token terpakai secara atomik. Mengembalikan
+// mongo.ErrNoDocuments jika token tidak ada, sudah dipakai, atau kadaluarsa.
+func ConsumePasswordReset(hash string) (*models.PasswordResetToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	now := time.Now()
+	var t models.PasswordResetToken
+	err := passwordResetCol().FindOneAndUpdate(ctx,
+		bson.M{"_id": hash, "used_at": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"used_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&t)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func CreateExportLink(l *models.ExportLink) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := exportLinkCol().InsertOne(ctx, l)
+	return err
+}
+
+// ConsumeExportLink menandai link export terpakai secara atomik. Mengembalikan
+// mongo.ErrNoDocuments jika link tidak ada, sudah dipakai, atau kadaluarsa.
+func ConsumeExportLink(hash string) (*models.ExportLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	now := time.Now()
+	var l models.ExportLink
+	err := exportLinkCol().FindOneAndUpdate(ctx,
+		bson.M{"_id": hash, "used_at": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"used_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&l)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
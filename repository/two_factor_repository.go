@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"backend/config"
+	"backend/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func loginChallengeCol() *mongo.Collection { return config.DB.Collection("login_challenge") }
+
+// EnsureTwoFactorIndexes membuat TTL untuk interim token login 2FA
+func EnsureTwoFactorIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := loginChallengeCol().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+func CreateLoginChallenge(ch *models.LoginChallenge) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := loginChallengeCol().InsertOne(ctx, ch)
+	return err
+}
+
+// HitLoginChallenge menaikkan hitungan percobaan untuk challenge yang masih berlaku dan
+// belum melewati batas. mongo.ErrNoDocuments berarti challenge tidak valid lagi.
+func HitLoginChallenge(hash string, maks int) (*models.LoginChallenge, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var ch models.LoginChallenge
+	err := loginChallengeCol().FindOneAndUpdate(ctx,
+		bson.M{"_id": hash, "expires_at": bson.M{"$gt": time.Now()}, "percobaan": bson.M{"$lt": maks}},
+		bson.M{"$inc": bson.M{"percobaan": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&ch)
+	if err != nil {
+		return nil, err
+	}
+	return &ch, nil
+}
+
+func DeleteLoginChallenge(hash string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := loginChallengeCol().DeleteOne(ctx, bson.M{"_id": hash})
+	return err
+}
+
+// SetTOTPPending menyimpan secret yang menunggu konfirmasi kode pertama
+func SetTOTPPending(userID, secret string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := userCol().UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{"totp_pending": secret}})
+	return err
+}
+
+// AktifkanTOTP memindahkan secret pending menjadi aktif beserta hash kode pemulihan
+func AktifkanTOTP(userID, secret string, step int64, recoveryHashes []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := userCol().UpdateOne(ctx, bson.M{"_id": userID}, bson.M{
+		"$set": bson.M{
+			"totp_aktif":     true,
+			"totp_secret":    secret,
+			"totp_last_step": step,
+			"recovery_codes": recoveryHashes,
+		},
+		"$unset": bson.M{"totp_pending": ""},
+	})
+	return err
+}
+
+func NonaktifkanTOTP(userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := userCol().UpdateOne(ctx, bson.M{"_id": userID}, bson.M{
+		"$unset": bson.M{"totp_aktif": "", "totp_secret": "", "totp_pending": "", "totp_last_step": "", "recovery_codes": ""},
+	})
+	return err
+}
+
+// PakaiTOTPStep mencatat langkah waktu kode yang baru dipakai; gagal (false) jika
+// langkah tersebut atau yang lebih baru sudah pernah dipakai (replay).
+func PakaiTOTPStep(userID string, step int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	res, err := userCol().UpdateOne(ctx,
+		bson.M{"_id": userID, "$or": bson.A{
+			bson.M{"totp_last_step": bson.M{"$exists": false}},
+			bson.M{"totp_last_step": bson.M{"$lt": step}},
+		}},
+		bson.M{"$set": bson.M{"totp_last_step": step}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// PakaiRecoveryCode menghapus satu hash kode pemulihan; false jika kode tidak ada
+func PakaiRecoveryCode(userID, hash string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	res, err := userCol().UpdateOne(ctx,
+		bson.M{"_id": userID, "recovery_codes": hash},
+		bson.M{"$pull": bson.M{"recovery_codes": hash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func SetRecoveryCodes(userID string, hashes []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := userCol().UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{"recovery_codes": hashes}})
+	return err
+}
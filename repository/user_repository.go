@@ -205,3 +205,53 @@ func GetActiveKaryawan() ([]models.User, error) {
 	}
 	return users, nil
 }
+
+// Naikkan token_version agar semua access token lama user ditolak middleware
+func IncrementTokenVersion(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := userCol().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"token_version": 1}})
+	return err
+}
+
+// CountUsers menghitung seluruh user (termasuk admin)
+func CountUsers() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return userCol().CountDocuments(ctx, bson.M{})
+}
+
+// UpdateProfil mengubah field profil milik user sendiri (hanya field yang diisi)
+func UpdateProfil(id string, set bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	res, err := userCol().UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// UpdatePassword menyimpan hash password baru. wajibGanti=true dipakai saat reset oleh admin
+// agar user dipaksa mengganti password pada login berikutnya.
+func UpdatePassword(id, hashed string, wajibGanti bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	update := bson.M{"$set": bson.M{"password": hashed}}
+	if wajibGanti {
+		update["$set"].(bson.M)["wajib_ganti_password"] = true
+	} else {
+		update["$unset"] = bson.M{"wajib_ganti_password": ""}
+	}
+	res, err := userCol().UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
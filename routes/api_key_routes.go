@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/middleware"
+	"backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func ApiKeyRoutes(app *fiber.App) {
+	// Kelola API key integrasi (default: admin)
+	r := app.Group("/api-keys", middleware.RequirePermission(models.PermRoleKelola))
+	r.Get("/", controllers.GetAllApiKeys)
+	r.Post("/", controllers.CreateApiKey)
+	r.Delete("/:id", controllers.RevokeApiKey)
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/middleware"
+	"backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func AuditRoutes(app *fiber.App) {
+	// Audit log perubahan data (default: admin)
+	app.Get("/audit", middleware.RequirePermission(models.PermAuditRead), controllers.GetAuditLog)
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"backend/controllers"
+	"backend/middleware"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -9,8 +10,23 @@ import (
 func AuthRoutes(app *fiber.App) {
 	auth := app.Group("/auth")
 
-	auth.Post("/register", controllers.Register)
+	// Registrasi publik ditiadakan: akun baru dibuat admin lewat /users/karyawan,
+	// admin pertama dibuat lewat bootstrap (lihat main.go)
 	auth.Post("/login", controllers.Login)
+	auth.Post("/login/2fa", controllers.Login2FA)
+	auth.Post("/refresh", controllers.Refresh)
+	auth.Post("/logout", controllers.Logout)
+
+	// Lupa password (publik): token sekali pakai dikirim lewat email
+	auth.Post("/forgot", controllers.ForgotPassword)
+	auth.Post("/reset", controllers.ResetPassword)
+
+	// 2FA (TOTP) milik sendiri
+	auth.Get("/2fa", controllers.Get2FAStatus)
+	auth.Post("/2fa/setup", middleware.AuditAkunSendiri(), controllers.Setup2FA)
+	auth.Post("/2fa/aktifkan", middleware.AuditAkunSendiri(), controllers.Aktifkan2FA)
+	auth.Post("/2fa/nonaktifkan", middleware.AuditAkunSendiri(), controllers.Nonaktifkan2FA)
+	auth.Post("/2fa/recovery-codes", middleware.AuditAkunSendiri(), controllers.RegenerateRecoveryCodes)
 
 	// Endpoint untuk dropdown driver
 	auth.Get("/drivers", controllers.GetAllDrivers)
@@ -3,6 +3,7 @@ package routes
 import (
 	"backend/controllers"
 	"backend/middleware"
+	"backend/models"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -10,12 +11,12 @@ import (
 func KategoriRoutes(app *fiber.App) {
 	g := app.Group("/kategori")
 
-	// Semua role (dengan JWT) bisa melihat
-	g.Get("/", controllers.GetAllKategori)
-	g.Get("/:id", controllers.GetKategoriByID)
+	// Lihat kategori (default: semua role)
+	g.Get("/", middleware.RequirePermission(models.PermKategoriRead), controllers.GetAllKategori)
+	g.Get("/:id", middleware.RequirePermission(models.PermKategoriRead), controllers.GetKategoriByID)
 
-	// Hanya admin & gudang yang bisa create/update/delete
-	g.Post("/", middleware.RoleGuard("gudang"), controllers.CreateKategori)
-	g.Put("/:id", middleware.RoleGuard("gudang"), controllers.UpdateKategori)
-	g.Delete("/:id", middleware.RoleGuard("gudang"), controllers.DeleteKategori)
+	// Kelola kategori (default: gudang)
+	g.Post("/", middleware.RequirePermission(models.PermKategoriWrite), middleware.Audit("kategori"), controllers.CreateKategori)
+	g.Put("/:id", middleware.RequirePermission(models.PermKategoriWrite), middleware.Audit("kategori"), controllers.UpdateKategori)
+	g.Delete("/:id", middleware.RequirePermission(models.PermKategoriWrite), middleware.Audit("kategori"), controllers.DeleteKategori)
 }
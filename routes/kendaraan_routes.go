@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/middleware"
+	"backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func KendaraanRoutes(app *fiber.App) {
+	g := app.Group("/kendaraan")
+	// View armada (default: admin, kasir)
+	g.Get("/", middleware.RequirePermission(models.PermKendaraanRead), controllers.GetAllKendaraan)
+	g.Get("/:id", middleware.RequirePermission(models.PermKendaraanRead), controllers.GetKendaraanByID)
+	// Kelola armada (default: admin)
+	g.Post("/", middleware.RequirePermission(models.PermKendaraanWrite), controllers.CreateKendaraan)
+	g.Put("/:id", middleware.RequirePermission(models.PermKendaraanWrite), controllers.UpdateKendaraan)
+	g.Delete("/:id", middleware.RequirePermission(models.PermKendaraanWrite), controllers.DeleteKendaraan)
+
+	shift := app.Group("/shift-driver")
+	// View jadwal (default: admin, kasir, driver; driver hanya miliknya)
+	shift.Get("/", middleware.RequirePermission(models.PermShiftRead), controllers.GetAllShiftDriver)
+	// Kelola jadwal (default: admin)
+	shift.Post("/", middleware.RequirePermission(models.PermShiftWrite), controllers.CreateShiftDriver)
+	shift.Put("/:id", middleware.RequirePermission(models.PermShiftWrite), controllers.UpdateShiftDriver)
+	shift.Delete("/:id", middleware.RequirePermission(models.PermShiftWrite), controllers.DeleteShiftDriver)
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"backend/controllers"
 	"backend/middleware"
+	"backend/models"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -11,17 +12,91 @@ func LaporanRoutes(app *fiber.App) {
 
 	laporanController := controllers.NewLaporanController()
 
-	// Best sellers for dashboard (admin, gudang, kasir)
+	// Best sellers untuk dashboard (default: admin, gudang, kasir)
 	app.Get(
 		"/laporan/best-sellers",
-		middleware.RoleGuard("admin", "gudang", "kasir"),
+		middleware.RequirePermission(models.PermLaporanRead),
 		laporanController.BestSellers,
 	)
 
+	// Ringkasan penjualan per hari/minggu/bulan untuk dashboard (default: admin, gudang, kasir)
+	app.Get(
+		"/laporan/penjualan",
+		middleware.RequirePermission(models.PermLaporanRead),
+		laporanController.Penjualan,
+	)
+
+	// Kinerja kasir & driver (default: admin)
+	app.Get(
+		"/laporan/kinerja",
+		middleware.RequirePermission(models.PermLaporanKinerja),
+		laporanController.Kinerja,
+	)
+
+	// Laba kotor & margin per produk, kategori, kasir, hari (default: admin)
+	app.Get(
+		"/laporan/laba",
+		middleware.RequirePermission(models.PermLaporanLaba),
+		laporanController.Laba,
+	)
+
+	// Kartu stok: saldo awal, mutasi dengan saldo berjalan, saldo akhir (default: admin, gudang)
+	app.Get(
+		"/laporan/kartu-stok",
+		middleware.RequirePermission(models.PermLaporanStok),
+		laporanController.KartuStok,
+	)
+
+	// Nilai persediaan per tanggal, per kategori (default: admin)
+	app.Get(
+		"/laporan/persediaan",
+		middleware.RequirePermission(models.PermLaporanPersediaan),
+		laporanController.Persediaan,
+	)
+
+	// Job export di latar belakang untuk data besar (default: admin)
+	jobs := app.Group("/laporan/jobs", middleware.RequirePermission(models.PermLaporanExport))
+	jobs.Post("/", laporanController.BuatLaporanJob)
+	jobs.Get("/", laporanController.GetLaporanJobs)
+	jobs.Get("/:id", laporanController.GetLaporanJob)
+	jobs.Get("/:id/download", laporanController.DownloadLaporanJob)
+
+	// Link unduhan export sekali pakai (default: admin)
+	app.Post(
+		"/laporan/export/links",
+		middleware.RequirePermission(models.PermLaporanExport),
+		laporanController.BuatExportLink,
+	)
+
+	// Unduhan export: header Authorization, atau ?link= dari /laporan/export/links
 	app.Get(
 		"/laporan/export/excel",
-		middleware.JWTMiddlewareForExport,
-		middleware.RoleGuard("admin"),
+		middleware.RequirePermission(models.PermLaporanExport),
 		laporanController.ExportExcel,
 	)
+
+	app.Get(
+		"/laporan/export/kinerja",
+		middleware.RequirePermission(models.PermLaporanExport, models.PermLaporanKinerja),
+		laporanController.ExportKinerjaExcel,
+	)
+
+	app.Get(
+		"/laporan/export/laba",
+		middleware.RequirePermission(models.PermLaporanExport, models.PermLaporanLaba),
+		laporanController.ExportLabaExcel,
+	)
+
+	app.Get(
+		"/laporan/export/persediaan",
+		middleware.RequirePermission(models.PermLaporanExport, models.PermLaporanPersediaan),
+		laporanController.ExportPersediaanExcel,
+	)
+
+	// Export kartu stok cukup laporan:stok agar gudang bisa mengunduh tanpa laporan:export
+	app.Get(
+		"/laporan/export/kartu-stok",
+		middleware.RequirePermission(models.PermLaporanStok),
+		laporanController.ExportKartuStokExcel,
+	)
 }
@@ -3,6 +3,7 @@ package routes
 import (
 	"backend/controllers"
 	"backend/middleware"
+	"backend/models"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -10,12 +11,12 @@ import (
 func PelangganRoutes(app *fiber.App) {
 	pelanggan := app.Group("/pelanggan")
 
-	// GET bisa diakses admin, kasir, driver
-	pelanggan.Get("/", middleware.RoleGuard("admin", "kasir"), controllers.GetAllPelanggan)
-	pelanggan.Get("/:id", middleware.RoleGuard("admin", "kasir"), controllers.GetPelangganByID)
+	// Lihat pelanggan (default: admin, kasir)
+	pelanggan.Get("/", middleware.RequirePermission(models.PermPelangganRead), controllers.GetAllPelanggan)
+	pelanggan.Get("/:id", middleware.RequirePermission(models.PermPelangganRead), controllers.GetPelangganByID)
 
-	// POST/PUT/DELETE hanya admin, kasir
-	pelanggan.Post("/", middleware.RoleGuard("kasir"), controllers.CreatePelanggan)
-	pelanggan.Put("/:id", middleware.RoleGuard("kasir"), controllers.UpdatePelanggan)
-	pelanggan.Delete("/:id", middleware.RoleGuard("kasir"), controllers.DeletePelanggan)
+	// Kelola pelanggan (default: kasir)
+	pelanggan.Post("/", middleware.RequirePermission(models.PermPelangganWrite), middleware.Audit("pelanggan"), controllers.CreatePelanggan)
+	pelanggan.Put("/:id", middleware.RequirePermission(models.PermPelangganWrite), middleware.Audit("pelanggan"), controllers.UpdatePelanggan)
+	pelanggan.Delete("/:id", middleware.RequirePermission(models.PermPelangganWrite), middleware.Audit("pelanggan"), controllers.DeletePelanggan)
 }
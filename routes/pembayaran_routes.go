@@ -3,6 +3,7 @@ package routes
 import (
 	"backend/controllers"
 	"backend/middleware"
+	"backend/models"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -10,17 +11,17 @@ import (
 func PembayaranRoutes(app *fiber.App) {
 	pembayaran := app.Group("/pembayaran")
 
-	// GET semua pembayaran - admin, kasir
-	pembayaran.Get("/", middleware.RoleGuard("admin", "kasir"), controllers.GetAllPembayaran)
+	// GET semua pembayaran (default: admin, kasir)
+	pembayaran.Get("/", middleware.RequirePermission(models.PermPembayaranRead), controllers.GetAllPembayaran)
 
-	// GET by ID - admin, kasir
-	pembayaran.Get("/:id", middleware.RoleGuard("admin", "kasir"), controllers.GetPembayaranByID)
+	// GET by ID (default: admin, kasir)
+	pembayaran.Get("/:id", middleware.RequirePermission(models.PermPembayaranRead), controllers.GetPembayaranByID)
 
-	// POST - kasir saja (admin read-only)
-	pembayaran.Post("/", middleware.RoleGuard("kasir"), controllers.CreatePembayaran)
+	// POST (default: kasir; admin read-only)
+	pembayaran.Post("/", middleware.RequirePermission(models.PermPembayaranWrite), middleware.Audit("pembayaran"), controllers.CreatePembayaran)
 
-	// PUT selesaikan - kasir saja (admin read-only)
-	pembayaran.Put("/selesaikan/:id", middleware.RoleGuard("kasir"), controllers.SelesaikanPembayaran)
+	// PUT selesaikan (default: kasir; admin read-only)
+	pembayaran.Put("/selesaikan/:id", middleware.RequirePermission(models.PermPembayaranWrite), middleware.Audit("pembayaran"), controllers.SelesaikanPembayaran)
 
 	// Cetak surat jalan dihapus; dikelola oleh modul pengiriman
 }
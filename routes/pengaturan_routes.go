@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/middleware"
+	"backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func PengaturanRoutes(app *fiber.App) {
+	p := app.Group("/pengaturan", middleware.RequirePermission(models.PermPengaturanKelola))
+	p.Get("/keamanan", controllers.GetPengaturanKeamanan)
+	p.Put("/keamanan", controllers.UpdatePengaturanKeamanan)
+	p.Get("/laporan", controllers.GetPengaturanLaporan)
+	p.Put("/laporan", controllers.UpdatePengaturanLaporan)
+}
@@ -3,19 +3,22 @@ package routes
 import (
 	"backend/controllers"
 	"backend/middleware"
+	"backend/models"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 func PengirimanRoutes(app *fiber.App) {
 	g := app.Group("/pengiriman")
-	// List & detail: admin, kasir, driver (driver sees own)
-	g.Get("/", middleware.RoleGuard("admin", "kasir", "driver"), controllers.GetAllPengiriman)
-	g.Get("/:id", middleware.RoleGuard("admin", "kasir", "driver"), controllers.GetPengirimanByID)
-	// Create: admin, kasir
-	g.Post("/", middleware.RoleGuard("kasir"), controllers.CreatePengiriman)
-	// Update: admin, kasir, driver (driver only own)
-	g.Put("/:id", middleware.RoleGuard("kasir", "driver"), controllers.UpdatePengiriman)
-	// Delete: admin, kasir
-	g.Delete("/:id", middleware.RoleGuard("kasir"), controllers.DeletePengiriman)
+	// List & detail (default: admin, kasir, driver; driver hanya miliknya)
+	g.Get("/", middleware.RequirePermission(models.PermPengirimanRead), controllers.GetAllPengiriman)
+	g.Get("/:id", middleware.RequirePermission(models.PermPengirimanRead), controllers.GetPengirimanByID)
+	// Create (default: kasir)
+	g.Post("/", middleware.RequirePermission(models.PermPengirimanWrite), middleware.Audit("pengiriman"), controllers.CreatePengiriman)
+	// Update status (default: kasir, driver; driver hanya miliknya)
+	g.Put("/:id", middleware.RequirePermission(models.PermPengirimanStatus), middleware.Audit("pengiriman"), controllers.UpdatePengiriman)
+	// Buat ulang link tracking pelanggan: kasir pemilik transaksi
+	g.Post("/:id/tracking", middleware.RequirePermission(models.PermPengirimanWrite), middleware.Audit("pengiriman"), controllers.RegenerateTrackingPengiriman)
+	// Delete (default: kasir)
+	g.Delete("/:id", middleware.RequirePermission(models.PermPengirimanWrite), middleware.Audit("pengiriman"), controllers.DeletePengiriman)
 }
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/middleware"
+	"backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func PerjalananRoutes(app *fiber.App) {
+	g := app.Group("/perjalanan")
+	// Trip hari ini untuk driver login
+	g.Get("/hari-ini", middleware.RequirePermission(models.PermPerjalananJalan), controllers.GetPerjalananHariIni)
+	// List & detail (default: admin, kasir, driver; driver hanya miliknya)
+	g.Get("/", middleware.RequirePermission(models.PermPerjalananRead), controllers.GetAllPerjalanan)
+	g.Get("/:id", middleware.RequirePermission(models.PermPerjalananRead), controllers.GetPerjalananByID)
+	// Perencanaan trip (default: kasir)
+	g.Post("/", middleware.RequirePermission(models.PermPerjalananWrite), controllers.CreatePerjalanan)
+	g.Put("/:id/optimasi", middleware.RequirePermission(models.PermPerjalananWrite), controllers.OptimasiPerjalanan)
+	g.Delete("/:id", middleware.RequirePermission(models.PermPerjalananWrite), controllers.DeletePerjalanan)
+	// Eksekusi trip (default: driver pemilik)
+	g.Put("/:id/mulai", middleware.RequirePermission(models.PermPerjalananJalan), controllers.MulaiPerjalanan)
+	g.Put("/:id/selesai", middleware.RequirePermission(models.PermPerjalananJalan), controllers.SelesaikanPerjalanan)
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"backend/controllers"
 	"backend/middleware"
+	"backend/models"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -10,12 +11,12 @@ import (
 func ProdukRoutes(app *fiber.App) {
 	produk := app.Group("/produk")
 
-	// GET bisa diakses semua role (admin, kasir, gudang, driver)
-	produk.Get("/", middleware.RoleGuard("admin", "kasir", "gudang", "driver"), controllers.GetAllProduk)
-	produk.Get("/:id", middleware.RoleGuard("admin", "kasir", "gudang", "driver"), controllers.GetProdukByID)
+	// Lihat produk (default: semua role)
+	produk.Get("/", middleware.RequirePermission(models.PermProdukRead), controllers.GetAllProduk)
+	produk.Get("/:id", middleware.RequirePermission(models.PermProdukRead), controllers.GetProdukByID)
 
-	// POST/PUT/DELETE hanya admin, gudang
-	produk.Post("/", middleware.RoleGuard("gudang"), controllers.CreateProduk)
-	produk.Put("/:id", middleware.RoleGuard("gudang"), controllers.UpdateProduk)
-	produk.Delete("/:id", middleware.RoleGuard("gudang"), controllers.DeleteProduk)
+	// Kelola produk (default: gudang)
+	produk.Post("/", middleware.RequirePermission(models.PermProdukWrite), middleware.Audit("produk"), controllers.CreateProduk)
+	produk.Put("/:id", middleware.RequirePermission(models.PermProdukWrite), middleware.Audit("produk"), controllers.UpdateProduk)
+	produk.Delete("/:id", middleware.RequirePermission(models.PermProdukWrite), middleware.Audit("produk"), controllers.DeleteProduk)
 }
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/middleware"
+	"backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RoleRoutes(app *fiber.App) {
+	r := app.Group("/roles", middleware.RequirePermission(models.PermRoleKelola))
+	r.Get("/", controllers.GetAllRolePermissions)
+	r.Get("/permissions", controllers.GetKatalogPermission)
+	r.Get("/:role", controllers.GetRolePermission)
+	r.Put("/:role", controllers.UpdateRolePermission)
+}
@@ -12,8 +12,16 @@ func SetupRoutes(app *fiber.App) {
 	PelangganRoutes(app)
 	PembayaranRoutes(app)
 	PengirimanRoutes(app)
+	PerjalananRoutes(app)
+	KendaraanRoutes(app)
+	ShiftKasirRoutes(app)
+	TrackingRoutes(app)
 	LaporanRoutes(app)
 	AuthRoutes(app)
 	UserRoutes(app)
 	RiwayatRoutes(app)
+	PengaturanRoutes(app)
+	RoleRoutes(app)
+	ApiKeyRoutes(app)
+	AuditRoutes(app)
 }
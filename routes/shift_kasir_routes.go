@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/middleware"
+	"backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func ShiftKasirRoutes(app *fiber.App) {
+	g := app.Group("/shift-kasir")
+	// Buka/tutup laci kas sendiri (default: kasir)
+	g.Post("/buka", middleware.RequirePermission(models.PermShiftKasirKelola), controllers.BukaShiftKasir)
+	g.Get("/aktif", middleware.RequirePermission(models.PermShiftKasirKelola), controllers.GetShiftKasirAktif)
+	g.Post("/tutup", middleware.RequirePermission(models.PermShiftKasirKelola), controllers.TutupShiftKasir)
+	// Laporan rekonsiliasi (default: admin)
+	g.Get("/laporan", middleware.RequirePermission(models.PermShiftKasirLapor), controllers.LaporanShiftKasir)
+	// View shift (default: admin, kasir; kasir hanya miliknya)
+	g.Get("/", middleware.RequirePermission(models.PermShiftKasirRead), controllers.GetAllShiftKasir)
+	g.Get("/:id", middleware.RequirePermission(models.PermShiftKasirRead), controllers.GetShiftKasirByID)
+}
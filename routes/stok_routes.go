@@ -3,16 +3,17 @@ package routes
 import (
 	"backend/controllers"
 	"backend/middleware"
+	"backend/models"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 func StokRoutes(app *fiber.App) {
 	g := app.Group("/stok")
-	// View saldo & mutasi: semua role (admin, kasir, gudang, driver)
-	g.Get("/saldo/:produk_id", middleware.RoleGuard("admin", "kasir", "gudang"), controllers.GetSaldoProduk)
-	g.Get("/mutasi/:produk_id", middleware.RoleGuard("admin", "kasir", "gudang"), controllers.GetMutasiByProduk)
-	g.Get("/mutasi", middleware.RoleGuard("admin", "kasir", "gudang"), controllers.ListMutasi)
-	// Create mutasi: admin + gudang
-	g.Post("/", middleware.RoleGuard("gudang"), controllers.CreateMutasi)
+	// View saldo & mutasi (default: admin, kasir, gudang)
+	g.Get("/saldo/:produk_id", middleware.RequirePermission(models.PermStokRead), controllers.GetSaldoProduk)
+	g.Get("/mutasi/:produk_id", middleware.RequirePermission(models.PermStokRead), controllers.GetMutasiByProduk)
+	g.Get("/mutasi", middleware.RequirePermission(models.PermStokRead), controllers.ListMutasi)
+	// Create mutasi (default: gudang)
+	g.Post("/", middleware.RequirePermission(models.PermStokWrite), middleware.Audit("stok"), controllers.CreateMutasi)
 }
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"backend/controllers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TrackingRoutes adalah endpoint publik (tanpa JWT) untuk pelanggan melacak pengiriman
+func TrackingRoutes(app *fiber.App) {
+	app.Get("/track/:token", controllers.TrackPengiriman)
+}
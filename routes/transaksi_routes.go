@@ -3,17 +3,18 @@ package routes
 import (
 	"backend/controllers"
 	"backend/middleware"
+	"backend/models"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 func TransaksiRoutes(app *fiber.App) {
 	r := app.Group("/transaksi")
-	// Read-only monitoring: admin bisa view; kasir hanya miliknya
-	r.Get("/", middleware.RoleGuard("admin", "kasir"), controllers.ListTransaksi)
-	r.Get("/:id", middleware.RoleGuard("admin", "kasir"), controllers.GetTransaksiByID)
-	// Write: kasir saja
-	r.Post("/", middleware.RoleGuard("kasir"), controllers.CreateTransaksi)
-	r.Put("/:id", middleware.RoleGuard("kasir"), controllers.UpdateTransaksi)
-	r.Delete("/:id", middleware.RoleGuard("kasir"), controllers.DeleteTransaksi)
+	// Read-only monitoring (default: admin semua; kasir hanya miliknya)
+	r.Get("/", middleware.RequirePermission(models.PermTransaksiRead), controllers.ListTransaksi)
+	r.Get("/:id", middleware.RequirePermission(models.PermTransaksiRead), controllers.GetTransaksiByID)
+	// Write (default: kasir)
+	r.Post("/", middleware.RequirePermission(models.PermTransaksiWrite), middleware.Audit("transaksi"), controllers.CreateTransaksi)
+	r.Put("/:id", middleware.RequirePermission(models.PermTransaksiWrite), middleware.Audit("transaksi"), controllers.UpdateTransaksi)
+	r.Delete("/:id", middleware.RequirePermission(models.PermTransaksiWrite), middleware.Audit("transaksi"), controllers.DeleteTransaksi)
 }
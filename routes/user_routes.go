@@ -3,24 +3,38 @@ package routes
 import (
 	"backend/controllers"
 	"backend/middleware"
+	"backend/models"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 func UserRoutes(app *fiber.App) {
 	user := app.Group("/users")
-	// List driver untuk kebutuhan mapping/pemilihan (admin & kasir)
-	user.Get("/drivers", middleware.RoleGuard("admin", "kasir"), controllers.GetAllDrivers)
+	// Profil & password milik sendiri (semua role)
+	user.Get("/me", controllers.GetMe)
+	user.Put("/me", middleware.AuditAkunSendiri(), controllers.UpdateMe)
+	user.Post("/me/password", middleware.AuditAkunSendiri(), controllers.GantiPasswordSaya)
 
-	// CRUD karyawan (admin only)
-	user.Get("/karyawan", middleware.RoleGuard("admin"), controllers.GetAllKaryawan)
-	user.Get("/karyawan/active", middleware.RoleGuard("admin"), controllers.GetActiveKaryawan)
-	user.Get("/karyawan/:id", middleware.RoleGuard("admin"), controllers.GetKaryawanByID)
-	user.Post("/karyawan", middleware.RoleGuard("admin"), controllers.CreateKaryawan)
-	user.Put("/karyawan/:id", middleware.RoleGuard("admin"), controllers.UpdateKaryawan)
-	user.Delete("/karyawan/:id", middleware.RoleGuard("admin"), controllers.DeleteKaryawan)
-	user.Patch("/karyawan/:id/status", middleware.RoleGuard("admin"), controllers.UpdateKaryawanStatus)
+	// List driver untuk kebutuhan mapping/pemilihan (default: admin, kasir)
+	user.Get("/drivers", middleware.RequirePermission(models.PermDriverRead), controllers.GetAllDrivers)
+	// Saran driver yang tersedia (aktif, dalam shift, kapasitas belum penuh)
+	user.Get("/drivers/tersedia", middleware.RequirePermission(models.PermDriverRead), controllers.GetDriverTersedia)
+
+	// CRUD karyawan (default: admin)
+	user.Get("/karyawan", middleware.RequirePermission(models.PermKaryawanRead), controllers.GetAllKaryawan)
+	user.Get("/karyawan/active", middleware.RequirePermission(models.PermKaryawanRead), controllers.GetActiveKaryawan)
+	user.Get("/karyawan/:id", middleware.RequirePermission(models.PermKaryawanRead), controllers.GetKaryawanByID)
+	user.Post("/karyawan", middleware.RequirePermission(models.PermKaryawanWrite), middleware.Audit("user"), controllers.CreateKaryawan)
+	user.Put("/karyawan/:id", middleware.RequirePermission(models.PermKaryawanWrite), middleware.Audit("user"), controllers.UpdateKaryawan)
+	user.Delete("/karyawan/:id", middleware.RequirePermission(models.PermKaryawanWrite), middleware.Audit("user"), controllers.DeleteKaryawan)
+	user.Patch("/karyawan/:id/status", middleware.RequirePermission(models.PermKaryawanWrite), middleware.Audit("user"), controllers.UpdateKaryawanStatus)
+	user.Post("/karyawan/:id/unlock", middleware.RequirePermission(models.PermKaryawanWrite), middleware.Audit("user"), controllers.UnlockKaryawan)
+	user.Post("/karyawan/:id/reset-password", middleware.RequirePermission(models.PermKaryawanWrite), middleware.Audit("user"), controllers.ResetPasswordKaryawan)
+	user.Post("/karyawan/:id/reset-2fa", middleware.RequirePermission(models.PermKaryawanWrite), middleware.Audit("user"), controllers.Reset2FAKaryawan)
+
+	// Log percobaan login (default: admin)
+	user.Get("/login-attempts", middleware.RequirePermission(models.PermKaryawanRead), controllers.GetLoginAttempts)
 
 	// Register karyawan (bisa dipakai di halaman karyawan, bukan login)
-	user.Post("/register-karyawan", middleware.RoleGuard("admin"), controllers.RegisterKaryawan)
+	user.Post("/register-karyawan", middleware.RequirePermission(models.PermKaryawanWrite), middleware.Audit("user"), controllers.RegisterKaryawan)
 }
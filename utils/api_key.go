@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// Format API key: "mbg_<8 hex>.<secret>"; bagian sebelum titik adalah prefix yang
+// disimpan apa adanya untuk pencarian, seluruh key disimpan sebagai hash.
+const apiKeyAwalan = "mbg_"
+
+// GenerateApiKey membuat API key baru beserta prefix-nya
+func GenerateApiKey() (key, prefix string, err error) {
+	b := make([]byte, 4)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret, err := GenerateRandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	prefix = apiKeyAwalan + hex.EncodeToString(b)
+	return prefix + "." + secret, prefix, nil
+}
+
+// ApiKeyPrefix mengambil prefix dari API key; false jika format tidak sesuai
+func ApiKeyPrefix(key string) (string, bool) {
+	prefix, secret, ok := strings.Cut(key, ".")
+	if !ok || secret == "" || !strings.HasPrefix(prefix, apiKeyAwalan) {
+		return "", false
+	}
+	return prefix, true
+}
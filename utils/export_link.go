@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Secret link export: EXPORT_LINK_SECRET, fallback ke JWT_SECRET
+func exportLinkSecret() ([]byte, error) {
+	secret := os.Getenv("EXPORT_LINK_SECRET")
+	if secret == "" {
+		secret = os.Getenv("JWT_SECRET")
+	}
+	if secret == "" {
+		return nil, errors.New("EXPORT_LINK_SECRET/JWT_SECRET tidak ditemukan di environment")
+	}
+	return []byte(secret), nil
+}
+
+// KanonikQuery mengurutkan parameter query (tanpa nilai kosong) agar signature stabil
+func KanonikQuery(q url.Values) string {
+	bersih := url.Values{}
+	for k, vs := range q {
+		for _, v := range vs {
+			if v != "" {
+				bersih.Add(k, v)
+			}
+		}
+	}
+	return bersih.Encode()
+}
+
+func signExportLink(nonce string, exp int64, path, query string) (string, error) {
+	secret, err := exportLinkSecret()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("export:" + nonce + "|" + strconv.FormatInt(exp, 10) + "|" + path + "|" + query))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// BuatExportLinkToken membentuk token "<nonce>.<exp>.<signature>" yang terikat ke path & query
+func BuatExportLinkToken(nonce string, exp time.Time, path, query string) (string, error) {
+	sig, err := signExportLink(nonce, exp.Unix(), path, query)
+	if err != nil {
+		return "", err
+	}
+	return nonce + "." + strconv.FormatInt(exp.Unix(), 10) + "." + sig, nil
+}
+
+// VerifyExportLinkToken memeriksa signature & masa berlaku token lalu mengembalikan nonce-nya
+func VerifyExportLinkToken(token, path, query string, now time.Time) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return "", errors.New("link export tidak valid")
+	}
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", errors.New("link export tidak valid")
+	}
+	expected, err := signExportLink(parts[0], exp, path, query)
+	if err != nil {
+		return "", err
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+		return "", errors.New("link export tidak valid")
+	}
+	if now.Unix() >= exp {
+		return "", errors.New("link export sudah kadaluarsa")
+	}
+	return parts[0], nil
+}
@@ -13,17 +13,40 @@ type JWTClaims struct {
 	ID   string `json:"id"`
 	Role string `json:"role"`
 	Nama string `json:"nama"` // Nama user ditambahkan ke dalam token
+	Ver  int    `json:"ver"`  // token_version user saat token dibuat (untuk pencabutan massal)
 	jwt.RegisteredClaims
 }
 
-// Fungsi untuk menghasilkan JWT token
-func GenerateToken(id, role, nama string) (string, error) {
+// Masa berlaku access token (env ACCESS_TOKEN_TTL, contoh "15m"), default 15 menit
+func AccessTokenTTL() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("ACCESS_TOKEN_TTL")); err == nil && d > 0 {
+		return d
+	}
+	return 15 * time.Minute
+}
+
+// Masa berlaku refresh token (env REFRESH_TOKEN_TTL, contoh "168h"), default 7 hari
+func RefreshTokenTTL() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("REFRESH_TOKEN_TTL")); err == nil && d > 0 {
+		return d
+	}
+	return 7 * 24 * time.Hour
+}
+
+// Fungsi untuk menghasilkan JWT access token (jti acak agar bisa dicabut satu per satu)
+func GenerateToken(id, role, nama string, version int) (string, error) {
+	jti, err := GenerateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
 	claims := JWTClaims{
 		ID:   id,
 		Role: role,
 		Nama: nama,
+		Ver:  version,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(8 * time.Hour)), // expired dalam 8 jam
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL())),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mailer mengirim email teks sederhana. Implementasi dipilih lewat env MAIL_DRIVER.
+type Mailer interface {
+	Kirim(ke, subjek, isi string) error
+}
+
+// SMTPMailer mengirim lewat server SMTP (PLAIN auth jika username diisi)
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Kirim(ke, subjek, isi string) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	msg := strings.Join([]string{
+		"From: " + m.From,
+		"To: " + ke,
+		"Subject: " + subjek,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=\"utf-8\"",
+		"",
+		isi,
+	}, "\r\n")
+	return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{ke}, []byte(msg))
+}
+
+// LogMailer tidak mengirim email: isi ditulis ke file (Path) atau ke log server jika Path kosong.
+// Dipakai untuk pengembangan lokal dan pengujian.
+type LogMailer struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (m *LogMailer) Kirim(ke, subjek, isi string) error {
+	entry := fmt.Sprintf("=== %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC3339), ke, subjek, isi)
+	if m.Path == "" {
+		log.Printf("📧 [mail] %s", entry)
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, err := os.OpenFile(m.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(entry)
+	return err
+}
+
+var (
+	mailerOnce sync.Once
+	mailer     Mailer
+)
+
+// ErrMailerTidakDikonfigurasi: di production (APP_ENV=production) SMTP wajib; LogMailer
+// tidak dipakai agar token rahasia (mis. reset password) tidak tertulis ke log.
+var ErrMailerTidakDikonfigurasi = errors.New("MAIL_DRIVER=smtp dan SMTP_HOST wajib diset di production")
+
+// CekKonfigurasiMailer memeriksa konfigurasi mailer dari env tanpa membuat mailer
+func CekKonfigurasiMailer() error {
+	smtpAktif := strings.EqualFold(os.Getenv("MAIL_DRIVER"), "smtp") && strings.TrimSpace(os.Getenv("SMTP_HOST")) != ""
+	if !smtpAktif && strings.EqualFold(os.Getenv("APP_ENV"), "production") {
+		return ErrMailerTidakDikonfigurasi
+	}
+	return nil
+}
+
+// NewMailerFromEnv: MAIL_DRIVER=smtp memakai SMTP_HOST, SMTP_PORT (default 587), SMTP_USER,
+// SMTP_PASS, MAIL_FROM; selain itu LogMailer dengan MAIL_LOG_FILE (opsional).
+// Mengembalikan nil jika CekKonfigurasiMailer gagal (production tanpa SMTP).
+func NewMailerFromEnv() Mailer {
+	if CekKonfigurasiMailer() != nil {
+		return nil
+	}
+	if strings.EqualFold(os.Getenv("MAIL_DRIVER"), "smtp") {
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		from := os.Getenv("MAIL_FROM")
+		if from == "" {
+			from = os.Getenv("SMTP_USER")
+		}
+		return &SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASS"),
+			From:     from,
+		}
+	}
+	return &LogMailer{Path: os.Getenv("MAIL_LOG_FILE")}
+}
+
+// GetMailer mengembalikan mailer aktif (dibuat dari env saat pertama dipakai);
+// nil jika pengiriman email tidak tersedia
+func GetMailer() Mailer {
+	mailerOnce.Do(func() {
+		if mailer == nil {
+			mailer = NewMailerFromEnv()
+		}
+	})
+	return mailer
+}
+
+// SetMailer mengganti mailer aktif (mis. untuk pengujian)
+func SetMailer(m Mailer) {
+	mailerOnce.Do(func() {})
+	mailer = m
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"unicode"
+)
+
+// Panjang minimal password (env PASSWORD_MIN_LEN), default 8
+func PasswordMinLen() int {
+	if n, err := strconv.Atoi(os.Getenv("PASSWORD_MIN_LEN")); err == nil && n >= 8 {
+		return n
+	}
+	return 8
+}
+
+// ValidasiPassword menerapkan kebijakan kekuatan password: panjang minimal serta
+// mengandung huruf kecil, huruf besar dan angka.
+func ValidasiPassword(password string) error {
+	if len([]rune(password)) < PasswordMinLen() {
+		return errors.New("password minimal " + strconv.Itoa(PasswordMinLen()) + " karakter")
+	}
+	var lower, upper, digit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	if !lower || !upper || !digit {
+		return errors.New("password harus mengandung huruf kecil, huruf besar dan angka")
+	}
+	return nil
+}
+
+// PasswordSementara membuat password acak (reset oleh admin) sepanjang PasswordMinLen(),
+// minimal 12 karakter, yang selalu lolos ValidasiPassword.
+func PasswordSementara() (string, error) {
+	n := PasswordMinLen()
+	if n < 12 {
+		n = 12
+	}
+	acak, err := GenerateRandomToken(n)
+	if err != nil {
+		return "", err
+	}
+	// Karakter base64url acak + sufiks huruf besar, huruf kecil dan angka
+	return acak[:n-3] + "Aa1", nil
+}
@@ -0,0 +1,56 @@
+package utils
+
+import "math"
+
+// Titik adalah koordinat (lat/lng) yang dipakai untuk perhitungan rute
+type Titik struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// JarakKm menghitung jarak dua titik (haversine) dalam kilometer
+func JarakKm(a, b Titik) float64 {
+	const radiusBumiKm = 6371.0
+	toRad := func(d float64) float64 { return d * math.Pi / 180 }
+
+	dLat := toRad(b.Latitude - a.Latitude)
+	dLng := toRad(b.Longitude - a.Longitude)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(a.Latitude))*math.Cos(toRad(b.Latitude))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * radiusBumiKm * math.Asin(math.Sqrt(h))
+}
+
+// UrutkanNearestNeighbour mengembalikan urutan index titik berdasarkan heuristik
+// nearest-neighbour. Jika start nil, rute dimulai dari titik pertama.
+// Titik yang tidak punya koordinat (nil) ditaruh di akhir sesuai urutan awal.
+func UrutkanNearestNeighbour(start *Titik, titik []*Titik) []int {
+	order := make([]int, 0, len(titik))
+	sisa := make([]int, 0, len(titik))
+	tanpaKoordinat := make([]int, 0)
+	for i, t := range titik {
+		if t == nil {
+			tanpaKoordinat = append(tanpaKoordinat, i)
+			continue
+		}
+		sisa = append(sisa, i)
+	}
+
+	posisi := start
+	for len(sisa) > 0 {
+		pilih := 0
+		if posisi != nil {
+			terdekat := math.MaxFloat64
+			for k, idx := range sisa {
+				if d := JarakKm(*posisi, *titik[idx]); d < terdekat {
+					terdekat = d
+					pilih = k
+				}
+			}
+		}
+		idx := sisa[pilih]
+		order = append(order, idx)
+		posisi = titik[idx]
+		sisa = append(sisa[:pilih], sisa[pilih+1:]...)
+	}
+	return append(order, tanpaKoordinat...)
+}
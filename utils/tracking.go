@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strings"
+)
+
+// Secret tracking: TRACKING_SECRET, fallback ke JWT_SECRET
+func trackingSecret() ([]byte, error) {
+	secret := os.Getenv("TRACKING_SECRET")
+	if secret == "" {
+		secret = os.Getenv("JWT_SECRET")
+	}
+	if secret == "" {
+		return nil, errors.New("TRACKING_SECRET/JWT_SECRET tidak ditemukan di environment")
+	}
+	return []byte(secret), nil
+}
+
+func signTrackingNonce(nonce string) (string, error) {
+	secret, err := trackingSecret()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("tracking:" + nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16]), nil
+}
+
+// GenerateTrackingNonce membuat nonce acak yang disimpan di pengiriman
+func GenerateTrackingNonce() (string, error) {
+	return GenerateRandomToken(18)
+}
+
+// TrackingTokenFromNonce membentuk token publik "<nonce>.<signature>"
+func TrackingTokenFromNonce(nonce string) (string, error) {
+	sig, err := signTrackingNonce(nonce)
+	if err != nil {
+		return "", err
+	}
+	return nonce + "." + sig, nil
+}
+
+// VerifyTrackingToken memeriksa signature token dan mengembalikan nonce-nya
+func VerifyTrackingToken(token string) (string, error) {
+	nonce, sig, ok := strings.Cut(token, ".")
+	if !ok || nonce == "" || sig == "" {
+		return "", errors.New("token tracking tidak valid")
+	}
+	expected, err := signTrackingNonce(nonce)
+	if err != nil {
+		return "", err
+	}
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return "", errors.New("token tracking tidak valid")
+	}
+	return nonce, nil
+}